@@ -0,0 +1,179 @@
+// Package acc models the pages of Assetto Corsa Competizione's shared memory
+// as typed telemetry frames and validates frames received from publishers.
+package acc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/lap"
+	"github.com/blacksfk/are_hub/strategy"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// Name of the schema as stored in are_hub.Channel.
+	SCHEMA = "acc"
+
+	// Current version of the frame schema. Incremented whenever a change is
+	// made that is incompatible with existing publishers.
+	VERSION = 1
+)
+
+// A telemetry frame sent by publishers. Pages are optional as the physics page
+// is updated far more often than the graphics and static pages.
+type Frame struct {
+	Version  int       `json:"version" validate:"eq=1"`
+	Physics  *Physics  `json:"physics,omitempty"`
+	Graphics *Graphics `json:"graphics,omitempty"`
+	Static   *Static   `json:"static,omitempty"`
+}
+
+// Returned from Validate when a frame does not conform to the schema.
+// InvalidFrame implements error.
+type InvalidFrame struct {
+	// Description of each offending field.
+	Fields []string
+}
+
+func (e *InvalidFrame) Error() string {
+	return "Invalid frame: " + strings.Join(e.Fields, " ")
+}
+
+// Auxiliary function to determine whether or not an error is an InvalidFrame error.
+func IsInvalidFrame(e error) bool {
+	_, ok := e.(*InvalidFrame)
+
+	return ok
+}
+
+// Report field names as they are received (i.e. the JSON names).
+var validate = func() *validator.Validate {
+	v := validator.New()
+
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		return jsonName(f)
+	})
+
+	return v
+}()
+
+// Decode and validate a JSON encoded frame. Returns an InvalidFrame error listing
+// the offending fields if the frame is malformed.
+func Decode(bytes []byte) (*Frame, error) {
+	frame := &Frame{}
+	e := json.Unmarshal(bytes, frame)
+
+	if e != nil {
+		var te *json.UnmarshalTypeError
+
+		if errors.As(e, &te) {
+			// wrong type received for a field
+			field := fmt.Sprintf("Frame.%s: expected: %s, received: %s.", te.Field, te.Type, te.Value)
+
+			return nil, &InvalidFrame{[]string{field}}
+		}
+
+		return nil, &InvalidFrame{[]string{e.Error()}}
+	}
+
+	e = validate.Struct(frame)
+
+	if e != nil {
+		ve, ok := e.(validator.ValidationErrors)
+
+		if !ok {
+			return nil, e
+		}
+
+		// build a description of each field in the same format as the validate package
+		fields := make([]string, len(ve))
+
+		for i, fe := range ve {
+			fields[i] = fmt.Sprintf("%s: expected: %s, received: %v.", fe.Namespace(), fe.ActualTag(), fe.Value())
+		}
+
+		return nil, &InvalidFrame{fields}
+	}
+
+	return frame, nil
+}
+
+// Validate a JSON encoded frame. See Decode.
+func Validate(bytes []byte) error {
+	_, e := Decode(bytes)
+
+	return e
+}
+
+// Get the JSON name of a struct field.
+func jsonName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+
+	if name == "-" {
+		return ""
+	}
+
+	if len(name) == 0 {
+		return f.Name
+	}
+
+	return name
+}
+
+// Fields used to segment ACC telemetry into laps.
+var LapFields = lap.Fields{
+	CompletedLaps:  "graphics.completedLaps",
+	LastLapTime:    "graphics.iLastTime",
+	SectorIndex:    "graphics.currentSectorIndex",
+	LastSectorTime: "graphics.lastSectorTime",
+	Fuel:           "physics.fuel",
+	Valid:          "graphics.isValidLap",
+}
+
+// Fields used to estimate strategy from ACC telemetry.
+var StrategyFields = strategy.Fields{
+	Fuel:            "physics.fuel",
+	MaxFuel:         "static.maxFuel",
+	SessionTimeLeft: "graphics.sessionTimeLeft",
+	TyreWear:        "physics.tyreWear",
+}
+
+// Fields of ACC telemetry describing what a channel is publishing. The car
+// and track are only known from the static page.
+var MetadataFields = are_hub.MetadataFields{
+	Sim:      "Assetto Corsa Competizione",
+	CarModel: "static.carModel",
+	Track:    "static.track",
+}
+
+// Fields of ACC telemetry sent to spectators of public channels: timing,
+// position, and the driver's inputs. Fuel, tyres, and setup stay private to
+// the team.
+var SpectatorFields = []string{
+	"version",
+	"physics.speedKmh",
+	"physics.gear",
+	"physics.rpms",
+	"physics.gas",
+	"physics.brake",
+	"physics.steerAngle",
+	"graphics.status",
+	"graphics.session",
+	"graphics.currentTime",
+	"graphics.lastTime",
+	"graphics.bestTime",
+	"graphics.completedLaps",
+	"graphics.position",
+	"graphics.normalizedCarPosition",
+	"graphics.isInPit",
+	"graphics.isInPitLane",
+	"graphics.sessionTimeLeft",
+	"graphics.flag",
+	"static.carModel",
+	"static.track",
+}
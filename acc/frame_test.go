@@ -0,0 +1,63 @@
+package acc
+
+import (
+	"strings"
+	"testing"
+)
+
+// Are well-formed frames accepted?
+func TestDecodeValid(t *testing.T) {
+	body := `{
+		"version": 1,
+		"physics": {"gas": 0.8, "brake": 0, "fuel": 42.5, "speedKmh": 212.5, "wheelsPressure": [27.6, 27.8, 27.1, 27.3]},
+		"graphics": {"completedLaps": 22, "iLastTime": 104230, "isValidLap": 1},
+		"static": {"carModel": "audi_r8_lms_evo", "track": "spa"}
+	}`
+
+	frame, e := Decode([]byte(body))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if frame.Physics.Fuel != 42.5 || frame.Graphics.CompletedLaps != 22 || frame.Static.Track != "spa" {
+		t.Fatalf("Decoded incorrectly: %+v %+v %+v", frame.Physics, frame.Graphics, frame.Static)
+	}
+}
+
+// Are malformed frames rejected with every offending field listed?
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		body   string
+		fields []string
+	}{
+		{`{"physics": {}}`, []string{"Frame.version"}},
+		{`{"version": 2}`, []string{"Frame.version"}},
+		{
+			`{"version": 1, "physics": {"gas": 1.5, "wheelsPressure": [27.6, 27.8]}}`,
+			[]string{"Frame.physics.gas", "Frame.physics.wheelsPressure"},
+		},
+		{`{"version": 1, "graphics": {"completedLaps": "22"}}`, []string{"Frame.graphics.completedLaps"}},
+		{`{"version": 1,`, nil},
+	}
+
+	for _, test := range tests {
+		_, e := Decode([]byte(test.body))
+
+		if !IsInvalidFrame(e) {
+			t.Fatalf("Expected: InvalidFrame error for %s. Actual: %v.", test.body, e)
+		}
+
+		invalid := e.(*InvalidFrame)
+
+		if len(test.fields) > 0 && len(invalid.Fields) != len(test.fields) {
+			t.Fatalf("Expected: %d fields. Actual: %v.", len(test.fields), invalid.Fields)
+		}
+
+		for _, f := range test.fields {
+			if !strings.Contains(e.Error(), f) {
+				t.Fatalf("Expected: %s in %s.", f, e)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package acc
+
+// The graphics page of ACC's shared memory. Contains session, timing, and
+// strategy information. Times prefixed with "I" are in milliseconds.
+type Graphics struct {
+	PacketID                 int32   `json:"packetId"`
+	Status                   int32   `json:"status" validate:"min=0,max=3"`
+	Session                  int32   `json:"session" validate:"min=-1,max=7"`
+	CurrentTime              string  `json:"currentTime"`
+	LastTime                 string  `json:"lastTime"`
+	BestTime                 string  `json:"bestTime"`
+	Split                    string  `json:"split"`
+	CompletedLaps            int32   `json:"completedLaps" validate:"min=0"`
+	Position                 int32   `json:"position" validate:"min=0"`
+	ICurrentTime             int32   `json:"iCurrentTime" validate:"min=0"`
+	ILastTime                int32   `json:"iLastTime" validate:"min=0"`
+	IBestTime                int32   `json:"iBestTime" validate:"min=0"`
+	SessionTimeLeft          float32 `json:"sessionTimeLeft"`
+	DistanceTraveled         float32 `json:"distanceTraveled" validate:"min=0"`
+	IsInPit                  int32   `json:"isInPit" validate:"min=0,max=1"`
+	CurrentSectorIndex       int32   `json:"currentSectorIndex" validate:"min=0"`
+	LastSectorTime           int32   `json:"lastSectorTime" validate:"min=0"`
+	NumberOfLaps             int32   `json:"numberOfLaps" validate:"min=0"`
+	TyreCompound             string  `json:"tyreCompound"`
+	NormalizedCarPosition    float32 `json:"normalizedCarPosition" validate:"min=0,max=1"`
+	ActiveCars               int32   `json:"activeCars" validate:"min=0"`
+	PlayerCarID              int32   `json:"playerCarID"`
+	PenaltyTime              float32 `json:"penaltyTime"`
+	Flag                     int32   `json:"flag" validate:"min=0"`
+	Penalty                  int32   `json:"penalty" validate:"min=0"`
+	IdealLineOn              int32   `json:"idealLineOn" validate:"min=0,max=1"`
+	IsInPitLane              int32   `json:"isInPitLane" validate:"min=0,max=1"`
+	SurfaceGrip              float32 `json:"surfaceGrip"`
+	MandatoryPitDone         int32   `json:"mandatoryPitDone" validate:"min=0,max=1"`
+	WindSpeed                float32 `json:"windSpeed"`
+	WindDirection            float32 `json:"windDirection"`
+	IsSetupMenuVisible       int32   `json:"isSetupMenuVisible" validate:"min=0,max=1"`
+	MainDisplayIndex         int32   `json:"mainDisplayIndex"`
+	SecondaryDisplayIndex    int32   `json:"secondaryDisplayIndex"`
+	TC                       int32   `json:"tc" validate:"min=0"`
+	TCCut                    int32   `json:"tcCut" validate:"min=0"`
+	EngineMap                int32   `json:"engineMap" validate:"min=0"`
+	ABS                      int32   `json:"abs" validate:"min=0"`
+	FuelXLap                 float32 `json:"fuelXLap" validate:"min=0"`
+	RainLights               int32   `json:"rainLights" validate:"min=0,max=1"`
+	FlashingLights           int32   `json:"flashingLights" validate:"min=0,max=1"`
+	LightsStage              int32   `json:"lightsStage" validate:"min=0"`
+	ExhaustTemperature       float32 `json:"exhaustTemperature"`
+	WiperLV                  int32   `json:"wiperLV" validate:"min=0"`
+	DriverStintTotalTimeLeft int32   `json:"driverStintTotalTimeLeft"`
+	DriverStintTimeLeft      int32   `json:"driverStintTimeLeft"`
+	RainTyres                int32   `json:"rainTyres" validate:"min=0,max=1"`
+	SessionIndex             int32   `json:"sessionIndex" validate:"min=0"`
+	UsedFuel                 float32 `json:"usedFuel" validate:"min=0"`
+	DeltaLapTime             string  `json:"deltaLapTime"`
+	IDeltaLapTime            int32   `json:"iDeltaLapTime"`
+	EstimatedLapTime         string  `json:"estimatedLapTime"`
+	IEstimatedLapTime        int32   `json:"iEstimatedLapTime" validate:"min=0"`
+	IsDeltaPositive          int32   `json:"isDeltaPositive" validate:"min=0,max=1"`
+	ISplit                   int32   `json:"iSplit" validate:"min=0"`
+	IsValidLap               int32   `json:"isValidLap" validate:"min=0,max=1"`
+	FuelEstimatedLaps        float32 `json:"fuelEstimatedLaps" validate:"min=0"`
+	TrackStatus              string  `json:"trackStatus"`
+	MissingMandatoryPits     int32   `json:"missingMandatoryPits"`
+	Clock                    float32 `json:"clock"`
+	DirectionLightsLeft      int32   `json:"directionLightsLeft" validate:"min=0,max=1"`
+	DirectionLightsRight     int32   `json:"directionLightsRight" validate:"min=0,max=1"`
+	GlobalYellow             int32   `json:"globalYellow" validate:"min=0,max=1"`
+	GlobalWhite              int32   `json:"globalWhite" validate:"min=0,max=1"`
+	GlobalGreen              int32   `json:"globalGreen" validate:"min=0,max=1"`
+	GlobalChequered          int32   `json:"globalChequered" validate:"min=0,max=1"`
+	GlobalRed                int32   `json:"globalRed" validate:"min=0,max=1"`
+	MFDTyreSet               int32   `json:"mfdTyreSet" validate:"min=0"`
+	MFDFuelToAdd             float32 `json:"mfdFuelToAdd" validate:"min=0"`
+	MFDTyrePressureLF        float32 `json:"mfdTyrePressureLF" validate:"min=0"`
+	MFDTyrePressureRF        float32 `json:"mfdTyrePressureRF" validate:"min=0"`
+	MFDTyrePressureLR        float32 `json:"mfdTyrePressureLR" validate:"min=0"`
+	MFDTyrePressureRR        float32 `json:"mfdTyrePressureRR" validate:"min=0"`
+	TrackGripStatus          int32   `json:"trackGripStatus" validate:"min=0"`
+	RainIntensity            int32   `json:"rainIntensity" validate:"min=0"`
+	GapAhead                 int32   `json:"gapAhead"`
+	GapBehind                int32   `json:"gapBehind"`
+}
@@ -0,0 +1,49 @@
+package acc
+
+// The physics page of ACC's shared memory. Updated every physics step.
+// Arrays of wheel values are ordered: front left, front right, rear left, rear right.
+type Physics struct {
+	PacketID            int32     `json:"packetId"`
+	Gas                 float32   `json:"gas" validate:"min=0,max=1"`
+	Brake               float32   `json:"brake" validate:"min=0,max=1"`
+	Fuel                float32   `json:"fuel" validate:"min=0"`
+	Gear                int32     `json:"gear" validate:"min=0"`
+	RPMs                int32     `json:"rpms" validate:"min=0"`
+	SteerAngle          float32   `json:"steerAngle" validate:"min=-1,max=1"`
+	SpeedKmh            float32   `json:"speedKmh" validate:"min=0"`
+	Velocity            []float32 `json:"velocity" validate:"omitempty,len=3"`
+	AccG                []float32 `json:"accG" validate:"omitempty,len=3"`
+	WheelSlip           []float32 `json:"wheelSlip" validate:"omitempty,len=4"`
+	WheelsPressure      []float32 `json:"wheelsPressure" validate:"omitempty,len=4"`
+	WheelAngularSpeed   []float32 `json:"wheelAngularSpeed" validate:"omitempty,len=4"`
+	TyreWear            []float32 `json:"tyreWear" validate:"omitempty,len=4"`
+	TyreCoreTemperature []float32 `json:"tyreCoreTemperature" validate:"omitempty,len=4"`
+	SuspensionTravel    []float32 `json:"suspensionTravel" validate:"omitempty,len=4"`
+	TC                  float32   `json:"tc"`
+	Heading             float32   `json:"heading"`
+	Pitch               float32   `json:"pitch"`
+	Roll                float32   `json:"roll"`
+	CarDamage           []float32 `json:"carDamage" validate:"omitempty,len=5"`
+	PitLimiterOn        int32     `json:"pitLimiterOn" validate:"min=0,max=1"`
+	ABS                 float32   `json:"abs"`
+	AutoShifterOn       int32     `json:"autoShifterOn" validate:"min=0,max=1"`
+	TurboBoost          float32   `json:"turboBoost"`
+	AirTemp             float32   `json:"airTemp"`
+	RoadTemp            float32   `json:"roadTemp"`
+	LocalAngularVel     []float32 `json:"localAngularVel" validate:"omitempty,len=3"`
+	FinalFF             float32   `json:"finalFF"`
+	BrakeTemp           []float32 `json:"brakeTemp" validate:"omitempty,len=4"`
+	Clutch              float32   `json:"clutch" validate:"min=0,max=1"`
+	IsAIControlled      int32     `json:"isAIControlled" validate:"min=0,max=1"`
+	BrakeBias           float32   `json:"brakeBias" validate:"min=0,max=1"`
+	LocalVelocity       []float32 `json:"localVelocity" validate:"omitempty,len=3"`
+	WaterTemp           float32   `json:"waterTemp"`
+	BrakePressure       []float32 `json:"brakePressure" validate:"omitempty,len=4"`
+	FrontBrakeCompound  int32     `json:"frontBrakeCompound" validate:"min=0"`
+	RearBrakeCompound   int32     `json:"rearBrakeCompound" validate:"min=0"`
+	PadLife             []float32 `json:"padLife" validate:"omitempty,len=4"`
+	DiscLife            []float32 `json:"discLife" validate:"omitempty,len=4"`
+	IgnitionOn          int32     `json:"ignitionOn" validate:"min=0,max=1"`
+	StarterEngineOn     int32     `json:"starterEngineOn" validate:"min=0,max=1"`
+	IsEngineRunning     int32     `json:"isEngineRunning" validate:"min=0,max=1"`
+}
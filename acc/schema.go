@@ -0,0 +1,104 @@
+package acc
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSON Schema of Frame for client authors. Generated from the struct
+// definitions and their validation tags so that it cannot drift from Validate.
+var schema = func() []byte {
+	s := object(reflect.TypeOf(Frame{}))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["$id"] = fmt.Sprintf("are_hub/%s/v%d", SCHEMA, VERSION)
+	s["title"] = "ACC telemetry frame"
+	s["required"] = []string{"version"}
+
+	bytes, e := json.MarshalIndent(s, "", "\t")
+
+	if e != nil {
+		// programmer error
+		panic(e)
+	}
+
+	return bytes
+}()
+
+// Get the JSON Schema describing frames.
+func JSONSchema() []byte {
+	return schema
+}
+
+// Create a schema describing a struct type.
+func object(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{}, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := jsonName(f)
+
+		if len(name) == 0 {
+			continue
+		}
+
+		props[name] = property(f.Type, f.Tag.Get("validate"))
+	}
+
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+// Create a schema describing a field of type t with the validation rules in tag.
+func property(t reflect.Type, tag string) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var p map[string]interface{}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return object(t)
+	case reflect.Slice, reflect.Array:
+		p = map[string]interface{}{"type": "array", "items": property(t.Elem(), "")}
+	case reflect.String:
+		p = map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		p = map[string]interface{}{"type": "boolean"}
+	case reflect.Float32, reflect.Float64:
+		p = map[string]interface{}{"type": "number"}
+	default:
+		p = map[string]interface{}{"type": "integer"}
+	}
+
+	// translate the validation rules that have JSON Schema equivalents
+	for _, rule := range strings.Split(tag, ",") {
+		kv := strings.SplitN(rule, "=", 2)
+
+		if len(kv) != 2 {
+			continue
+		}
+
+		n, e := strconv.ParseFloat(kv[1], 64)
+
+		if e != nil {
+			continue
+		}
+
+		switch kv[0] {
+		case "min":
+			p["minimum"] = n
+		case "max":
+			p["maximum"] = n
+		case "eq":
+			p["const"] = n
+		case "len":
+			p["minItems"] = n
+			p["maxItems"] = n
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,44 @@
+package acc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Does the schema describe the pages along with their validation rules?
+func TestJSONSchema(t *testing.T) {
+	var schema struct {
+		ID         string `json:"$id"`
+		Properties map[string]struct {
+			Properties map[string]map[string]interface{} `json:"properties"`
+		} `json:"properties"`
+	}
+
+	e := json.Unmarshal(JSONSchema(), &schema)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if schema.ID != "are_hub/acc/v1" {
+		t.Fatalf("Expected: are_hub/acc/v1. Actual: %s.", schema.ID)
+	}
+
+	for _, page := range []string{"physics", "graphics", "static"} {
+		if _, ok := schema.Properties[page]; !ok {
+			t.Fatalf("Expected: %s page.", page)
+		}
+	}
+
+	gas := schema.Properties["physics"].Properties["gas"]
+
+	if gas["type"] != "number" || gas["minimum"] != 0.0 || gas["maximum"] != 1.0 {
+		t.Fatalf("Expected: number between 0 and 1. Actual: %v.", gas)
+	}
+
+	pressure := schema.Properties["physics"].Properties["wheelsPressure"]
+
+	if pressure["type"] != "array" || pressure["minItems"] != 4.0 || pressure["maxItems"] != 4.0 {
+		t.Fatalf("Expected: array of 4. Actual: %v.", pressure)
+	}
+}
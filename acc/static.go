@@ -0,0 +1,29 @@
+package acc
+
+// The static page of ACC's shared memory. Only changes when a session is
+// loaded.
+type Static struct {
+	SMVersion           string  `json:"smVersion"`
+	ACVersion           string  `json:"acVersion"`
+	NumberOfSessions    int32   `json:"numberOfSessions" validate:"min=0"`
+	NumCars             int32   `json:"numCars" validate:"min=0"`
+	CarModel            string  `json:"carModel"`
+	Track               string  `json:"track"`
+	PlayerName          string  `json:"playerName"`
+	PlayerSurname       string  `json:"playerSurname"`
+	PlayerNick          string  `json:"playerNick"`
+	SectorCount         int32   `json:"sectorCount" validate:"min=0"`
+	MaxRPM              int32   `json:"maxRpm" validate:"min=0"`
+	MaxFuel             float32 `json:"maxFuel" validate:"min=0"`
+	PenaltiesEnabled    int32   `json:"penaltiesEnabled" validate:"min=0,max=1"`
+	AidFuelRate         float32 `json:"aidFuelRate" validate:"min=0"`
+	AidTireRate         float32 `json:"aidTireRate" validate:"min=0"`
+	AidMechanicalDamage float32 `json:"aidMechanicalDamage" validate:"min=0"`
+	AidStability        float32 `json:"aidStability" validate:"min=0"`
+	AidAutoClutch       int32   `json:"aidAutoClutch" validate:"min=0,max=1"`
+	PitWindowStart      int32   `json:"pitWindowStart"`
+	PitWindowEnd        int32   `json:"pitWindowEnd"`
+	IsOnline            int32   `json:"isOnline" validate:"min=0,max=1"`
+	DryTyresName        string  `json:"dryTyresName"`
+	WetTyresName        string  `json:"wetTyresName"`
+}
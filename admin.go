@@ -0,0 +1,14 @@
+package are_hub
+
+// Actions taken through the admin API. Recorded in the audit log with
+// ACTOR_ADMIN.
+const (
+	// A subscriber was disconnected from a live channel.
+	ADMIN_KICK_SUBSCRIBER = "subscriber.kick"
+
+	// A live channel was closed, disconnecting every client.
+	ADMIN_CLOSE_CHANNEL = "channel.close"
+
+	// The publisher slot of a car was vacated.
+	ADMIN_RESET_PUBLISHER = "publisher.reset"
+)
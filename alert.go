@@ -0,0 +1,104 @@
+package are_hub
+
+import (
+	"context"
+	"fmt"
+)
+
+// A threshold rule evaluated against the frames broadcast on a channel.
+// Eg. "tyre pressure FL > 28.0 psi": Field: "physics.wheelsPressure.0",
+// Operator: ">", Threshold: 28.0, Unit: "psi".
+type AlertRule struct {
+	ChannelID string `json:"channelId"`
+	Name      string `json:"name"`
+
+	// Path of the value in frames (dot separated with array indices, eg.
+	// "physics.brakeTemp.1") or of a strategy estimate (eg. "strategy.lapsRemaining").
+	Field string `json:"field"`
+
+	// One of: ">", ">=", "<", "<=".
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"`
+	Unit      string  `json:"unit"`
+
+	// How long (in milliseconds) the condition must hold before the rule fires.
+	Duration int64 `json:"duration"`
+
+	// How far the value must move back past the threshold before the rule can
+	// fire again. Prevents a value hovering around the threshold from
+	// repeatedly firing the rule.
+	Hysteresis float64 `json:"hysteresis"`
+
+	Common `bson:",inline"`
+}
+
+// Create a new alert rule.
+func NewAlertRule(name, field, op string, threshold float64) *AlertRule {
+	return &AlertRule{Name: name, Field: field, Operator: op, Threshold: threshold}
+}
+
+// Get an alert rule from a context.
+func AlertRuleFromCtx(ctx context.Context) (*AlertRule, error) {
+	v := ctx.Value(keyAlertRule)
+	r, ok := v.(*AlertRule)
+
+	if !ok {
+		return nil, fmt.Errorf("Could not assert %v as *AlertRule\n", v)
+	}
+
+	return r, nil
+}
+
+// Insert an alert rule into context.
+func (r *AlertRule) ToCtx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyAlertRule, r)
+}
+
+type AlertRuleRepo interface {
+	// Get all alert rules of a channel by the channel's ID.
+	FindChannelID(context.Context, string) ([]AlertRule, error)
+
+	// Create a new alert rule.
+	Insert(context.Context, Archetype) error
+
+	// Find an alert rule by its ID.
+	FindID(context.Context, string) (*AlertRule, error)
+
+	// Find and update an alert rule by its ID.
+	UpdateID(context.Context, string, Archetype) error
+
+	// Find and delete an alert rule by its ID.
+	DeleteID(context.Context, string) (*AlertRule, error)
+}
+
+// Recorded when an alert rule fires.
+type Alert struct {
+	ChannelID string `json:"channelId"`
+	RuleID    string `json:"ruleId"`
+
+	// Car that fired the rule on team channels.
+	Car string `json:"car,omitempty"`
+
+	// The rule's name, field, operator, threshold, and unit at the time of firing.
+	Name      string  `json:"name"`
+	Field     string  `json:"field"`
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"`
+	Unit      string  `json:"unit"`
+
+	// The value that fired the rule.
+	Value float64 `json:"value"`
+
+	// Sequence number of the frame that fired the rule.
+	Seq uint64 `json:"seq"`
+
+	Common `bson:",inline"`
+}
+
+type AlertRepo interface {
+	// Get the alert history of a channel (by the channel's ID), newest first.
+	FindChannelID(context.Context, string) ([]Alert, error)
+
+	// Record a new alert.
+	Insert(context.Context, Archetype) error
+}
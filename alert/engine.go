@@ -0,0 +1,147 @@
+// Package alert evaluates threshold rules against the frames broadcast on a channel.
+package alert
+
+import (
+	"time"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Operators supported by rules.
+const (
+	GT  = ">"
+	GTE = ">="
+	LT  = "<"
+	LTE = "<="
+)
+
+// Find the numeric value at a path. Returns false if the value is missing.
+type Lookup func(path string) (float64, bool)
+
+// Evaluation state of a single rule.
+type state struct {
+	// the rule has fired and has not yet cleared
+	active bool
+
+	// when the condition was first met; zero if the condition is not met
+	since time.Time
+}
+
+// Evaluates alert rules against frames. An Engine is not safe for concurrent use.
+type Engine struct {
+	rules  []are_hub.AlertRule
+	states map[string]*state
+}
+
+// Create a new engine evaluating rules.
+func NewEngine(rules []are_hub.AlertRule) *Engine {
+	e := &Engine{states: make(map[string]*state)}
+	e.SetRules(rules)
+
+	return e
+}
+
+// Replace the rules being evaluated. The state of rules which are still present
+// (matched by ID) is kept so that active rules do not fire again.
+func (e *Engine) SetRules(rules []are_hub.AlertRule) {
+	states := make(map[string]*state, len(rules))
+
+	for _, r := range rules {
+		if s, ok := e.states[r.ID]; ok {
+			states[r.ID] = s
+		} else {
+			states[r.ID] = &state{}
+		}
+	}
+
+	e.rules = rules
+	e.states = states
+}
+
+// Get the number of rules being evaluated.
+func (e *Engine) Len() int {
+	return len(e.rules)
+}
+
+// Evaluate every rule against the values found by lookup at time now. Returns
+// an alert (without ChannelID and Seq) for each rule that fired.
+func (e *Engine) Evaluate(lookup Lookup, now time.Time) []*are_hub.Alert {
+	var alerts []*are_hub.Alert
+
+	for _, r := range e.rules {
+		v, ok := lookup(r.Field)
+
+		if !ok {
+			// partial frame; leave the state as is
+			continue
+		}
+
+		s := e.states[r.ID]
+
+		if s.active {
+			// the value must move back past the threshold by the hysteresis to clear
+			if cleared(r, v) {
+				s.active = false
+				s.since = time.Time{}
+			}
+
+			continue
+		}
+
+		if !met(r, v) {
+			s.since = time.Time{}
+
+			continue
+		}
+
+		if s.since.IsZero() {
+			s.since = now
+		}
+
+		if now.Sub(s.since) < time.Duration(r.Duration)*time.Millisecond {
+			// the condition has not held for long enough
+			continue
+		}
+
+		s.active = true
+		alerts = append(alerts, &are_hub.Alert{
+			RuleID:    r.ID,
+			Name:      r.Name,
+			Field:     r.Field,
+			Operator:  r.Operator,
+			Threshold: r.Threshold,
+			Unit:      r.Unit,
+			Value:     v,
+		})
+	}
+
+	return alerts
+}
+
+// Check whether v meets r's condition.
+func met(r are_hub.AlertRule, v float64) bool {
+	switch r.Operator {
+	case GT:
+		return v > r.Threshold
+	case GTE:
+		return v >= r.Threshold
+	case LT:
+		return v < r.Threshold
+	case LTE:
+		return v <= r.Threshold
+	}
+
+	return false
+}
+
+// Check whether v has moved back past r's threshold by r's hysteresis.
+func cleared(r are_hub.AlertRule, v float64) bool {
+	switch r.Operator {
+	case GT, GTE:
+		return v < r.Threshold-r.Hysteresis
+	case LT, LTE:
+		return v > r.Threshold+r.Hysteresis
+	}
+
+	return true
+}
@@ -0,0 +1,95 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Create a lookup returning v for every path.
+func value(v float64) Lookup {
+	return func(string) (float64, bool) {
+		return v, true
+	}
+}
+
+// Does a rule with a duration only fire once the condition has held for the duration?
+func TestEvaluateDuration(t *testing.T) {
+	rule := are_hub.AlertRule{Field: "physics.brakeTemp.0", Operator: GT, Threshold: 800, Duration: 3000}
+	rule.ID = "brakes"
+	e := NewEngine([]are_hub.AlertRule{rule})
+	now := time.Now()
+
+	steps := []struct {
+		offset time.Duration
+		value  float64
+		fire   bool
+	}{
+		{0, 810, false},
+		{time.Second, 820, false},
+		// condition broken; the duration starts again
+		{time.Second * 2, 790, false},
+		{time.Second * 3, 805, false},
+		{time.Second * 5, 812, false},
+		{time.Second * 6, 815, true},
+		// still active
+		{time.Second * 10, 830, false},
+	}
+
+	for i, s := range steps {
+		alerts := e.Evaluate(value(s.value), now.Add(s.offset))
+
+		if fired := len(alerts) == 1; fired != s.fire {
+			t.Fatalf("Step %d. Expected: fired %t. Actual: %d alerts.", i, s.fire, len(alerts))
+		}
+	}
+}
+
+// Does a less than rule clear above the threshold plus the hysteresis?
+func TestEvaluateHysteresisLT(t *testing.T) {
+	rule := are_hub.AlertRule{Field: "strategy.lapsRemaining", Operator: LT, Threshold: 2, Hysteresis: 1}
+	e := NewEngine([]are_hub.AlertRule{rule})
+	now := time.Now()
+
+	values := []float64{2.5, 1.9, 1.5, 2.5, 3.1, 1.8}
+	fire := []bool{false, true, false, false, false, true}
+
+	for i, v := range values {
+		alerts := e.Evaluate(value(v), now)
+
+		if fired := len(alerts) == 1; fired != fire[i] {
+			t.Fatalf("Value %.1f. Expected: fired %t. Actual: %d alerts.", v, fire[i], len(alerts))
+		}
+
+		if fire[i] && alerts[0].Value != v {
+			t.Fatalf("Expected: %.1f. Actual: %.1f.", v, alerts[0].Value)
+		}
+	}
+}
+
+// Is the state of active rules kept when the rules are replaced?
+func TestSetRules(t *testing.T) {
+	rule := are_hub.AlertRule{Field: "physics.fuel", Operator: LTE, Threshold: 5}
+	rule.ID = "fuel"
+	e := NewEngine([]are_hub.AlertRule{rule})
+	now := time.Now()
+
+	if alerts := e.Evaluate(value(4), now); len(alerts) != 1 {
+		t.Fatalf("Expected: 1 alert. Actual: %d.", len(alerts))
+	}
+
+	rule.Name = "Low fuel"
+	e.SetRules([]are_hub.AlertRule{rule})
+
+	if alerts := e.Evaluate(value(3), now); len(alerts) != 0 {
+		t.Fatalf("Expected: 0 alerts. Actual: %d.", len(alerts))
+	}
+
+	// missing values leave rules untouched
+	missing := func(string) (float64, bool) { return 0, false }
+
+	if alerts := e.Evaluate(missing, now); len(alerts) != 0 {
+		t.Fatalf("Expected: 0 alerts. Actual: %d.", len(alerts))
+	}
+}
@@ -17,3 +17,11 @@ type Archetype interface {
 	// time (preferably UTC+0).
 	Updated()
 }
+
+// Types supporting optimistic concurrency. Implemented by Common.
+type Versioned interface {
+	Archetype
+
+	// Should mutate the version field to the value of the parameter.
+	SetVersion(int64)
+}
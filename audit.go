@@ -0,0 +1,159 @@
+package are_hub
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// Actions recorded in the audit log other than the admin actions (see
+// ADMIN_*), which are recorded as well.
+const (
+	AUDIT_CHANNEL_CREATE   = "channel.create"
+	AUDIT_CHANNEL_UPDATE   = "channel.update"
+	AUDIT_CHANNEL_PASSWORD = "channel.password"
+	AUDIT_CHANNEL_DELETE   = "channel.delete"
+	AUDIT_CHANNEL_RESTORE  = "channel.restore"
+	AUDIT_INVITE_CREATE    = "invite.create"
+	AUDIT_INVITE_REVOKE    = "invite.revoke"
+	AUDIT_INVITE_REDEEM    = "invite.redeem"
+)
+
+// Actors recorded in the audit log.
+const (
+	// Authorised with the admin token.
+	ACTOR_ADMIN = "admin"
+
+	// Not authorised. Eg. editing a channel through the REST API.
+	ACTOR_ANONYMOUS = "anonymous"
+)
+
+// Replaces the values of secrets (eg. passwords) in audit events.
+const REDACTED = "[redacted]"
+
+// Fields left out of diffs: those maintained by repositories rather than
+// changed by actors, and channel passwords, which always encode as empty
+// strings.
+var unaudited = map[string]bool{
+	"id":        true,
+	"createdAt": true,
+	"updatedAt": true,
+	"deletedAt": true,
+	"version":   true,
+	"Password":  true,
+}
+
+// A change made to something (eg. a channel) recorded in the append-only
+// audit log.
+type AuditEvent struct {
+	Actor  string `json:"actor"`
+	Action string `json:"action"`
+
+	// ID of what was changed. Eg. the channel's ID.
+	TargetID string `json:"targetId"`
+
+	// What was acted upon within the target, if anything. Eg. the subscriber
+	// kicked or the car reset by an administrator.
+	Detail string `json:"detail,omitempty"`
+
+	// Values of the fields changed (by their JSON names) before and after the
+	// change. Secrets are redacted.
+	Changes map[string]Change `json:"changes,omitempty"`
+
+	// Request ID (from X-Request-ID or generated) and client IP of the request
+	// making the change.
+	RequestID string `json:"requestId"`
+	ClientIP  string `json:"clientIp"`
+
+	Common `bson:",inline"`
+}
+
+// The value of a field before and after a change. Nil if the field didn't
+// exist.
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Create a new audit event of actor taking action on the target matching
+// targetID.
+func NewAuditEvent(actor, action, targetID string) *AuditEvent {
+	return &AuditEvent{Actor: actor, Action: action, TargetID: targetID}
+}
+
+// Find the fields that differ between before and after (either may be nil) by
+// comparing their JSON encodings. Timestamps, IDs, versions, and passwords are
+// left out; add password changes with REDACTED values instead.
+func Diff(before, after interface{}) (map[string]Change, error) {
+	b, e := fields(before)
+
+	if e != nil {
+		return nil, e
+	}
+
+	a, e := fields(after)
+
+	if e != nil {
+		return nil, e
+	}
+
+	changes := make(map[string]Change)
+
+	for k, v := range b {
+		if unaudited[k] {
+			continue
+		}
+
+		if !reflect.DeepEqual(v, a[k]) {
+			changes[k] = Change{v, a[k]}
+		}
+	}
+
+	for k, v := range a {
+		if _, ok := b[k]; !ok && !unaudited[k] {
+			changes[k] = Change{nil, v}
+		}
+	}
+
+	return changes, nil
+}
+
+// Get the fields of the JSON encoding of v. Returns nil if v is nil.
+func fields(v interface{}) (map[string]interface{}, error) {
+	if rv := reflect.ValueOf(v); !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return nil, nil
+	}
+
+	bytes, e := json.Marshal(v)
+
+	if e != nil {
+		return nil, e
+	}
+
+	m := make(map[string]interface{})
+
+	return m, json.Unmarshal(bytes, &m)
+}
+
+// Filters of the audit log. Zero values match every event.
+type AuditQuery struct {
+	Actor    string
+	Action   string
+	TargetID string
+
+	// When the event occurred. Both ends are inclusive.
+	After  time.Time
+	Before time.Time
+
+	// Maximum number of events to return. Every event is returned if zero.
+	Limit int64
+}
+
+type AuditRepo interface {
+	// Get the events matching a query, newest first.
+	Query(context.Context, AuditQuery) ([]AuditEvent, error)
+
+	// Record an event.
+	Insert(context.Context, Archetype) error
+}
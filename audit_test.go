@@ -0,0 +1,44 @@
+package are_hub
+
+import (
+	"testing"
+	"time"
+)
+
+// Are only the changed fields included?
+// Are passwords and repository maintained fields left out?
+func TestDiff(t *testing.T) {
+	before := &Channel{Name: "Team WRT", Password: "abc123", Limits: Limits{MaxSubscribers: 10}}
+	after := &Channel{Name: "Audi Sport Team WRT", Password: "lol123", Limits: Limits{MaxSubscribers: 10}}
+	after.Version = 3
+	after.UpdatedAt = time.Now()
+
+	changes, e := Diff(before, after)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("Expected: 1 change. Actual: %+v.", changes)
+	}
+
+	if c := changes["name"]; c.Before != "Team WRT" || c.After != "Audi Sport Team WRT" {
+		t.Errorf("Expected: Team WRT -> Audi Sport Team WRT. Actual: %+v.", c)
+	}
+
+	// every field changes from nothing when created
+	changes, e = Diff(nil, after)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if c, ok := changes["maxSubscribers"]; !ok || c.Before != nil || c.After != float64(10) {
+		t.Errorf("Expected: nil -> 10. Actual: %+v.", c)
+	}
+
+	if _, ok := changes["Password"]; ok {
+		t.Error("Expected the password to be left out")
+	}
+}
@@ -0,0 +1,66 @@
+package are_hub
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// A message broadcast on a channel by the node its publisher is connected to,
+// relayed to the subscribers connected to other nodes.
+type Broadcast struct {
+	ChannelID string `json:"channelId"`
+
+	// Node the message was broadcast by.
+	Node string `json:"node"`
+
+	// Websocket status of the message. Eg. 4200 for telemetry frames.
+	Status int `json:"status"`
+
+	// Sequence number of the frame (or the last frame for other messages).
+	Seq uint64 `json:"seq"`
+
+	// Car the message relates to on team channels. Empty for messages relating
+	// to the entire channel.
+	Car string `json:"car"`
+
+	// JSON encoded data of the message.
+	Data json.RawMessage `json:"data"`
+}
+
+// Relays messages between the nodes serving the same channels and coordinates
+// the publisher slots of channels across them. Implementations must be safe
+// for concurrent use.
+type Backplane interface {
+	// Send b to the nodes subscribed to its channel other than the node that
+	// broadcast it. Must not block. Messages published by a node are received
+	// in the order they were published.
+	Publish(b Broadcast)
+
+	// Receive the messages published on the channel matching id by nodes other
+	// than node until the returned function is called. deliver is not called
+	// concurrently and may be called after unsubscribing. Must not block.
+	Subscribe(id, node string, deliver func(Broadcast)) func()
+
+	// Get the next sequence number of the channel matching id.
+	Sequence(ctx context.Context, id string) (uint64, error)
+
+	// Claim the publisher slot of car on the channel matching id for driver
+	// for ttl. The slot is granted if it is vacant, its claim has expired, it
+	// is held by driver, or it was handed over to driver. Returns the driver
+	// previously holding the slot and whether the slot was granted.
+	Claim(ctx context.Context, id, car, driver string, ttl time.Duration) (string, bool, error)
+
+	// Hand the publisher slot of car on the channel matching id over to driver.
+	// The driver takes over with their next claim.
+	Handoff(ctx context.Context, id, car, driver string) error
+
+	// Vacate the publisher slot of car on the channel matching id, cancelling
+	// any pending handover.
+	Release(ctx context.Context, id, car string) error
+
+	// Discard the sequence number and publisher slots of the channel matching
+	// id once it has been closed. State shared with other nodes that may still
+	// serve the channel may be kept.
+	Forget(ctx context.Context, id string) error
+}
@@ -0,0 +1,215 @@
+// Package backplane relays messages broadcast on channels between the nodes
+// serving them and coordinates the publisher slots of channels across nodes.
+package backplane
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Number of messages queued for each subscription before messages are
+// discarded.
+const QUEUE_LEN = 256
+
+// In-process backplane relaying messages between nodes in the same process.
+// Implements are_hub.Backplane.
+type Local struct {
+	mtx sync.Mutex
+
+	// Subscriptions by channel ID.
+	subs map[string]map[*localSub]struct{}
+
+	// Last sequence number by channel ID.
+	seqs map[string]uint64
+
+	// Publisher slots by channel ID and car.
+	slots map[slotKey]*slot
+}
+
+// Identifies the publisher slot of a car on a channel.
+type slotKey struct {
+	id, car string
+}
+
+// Driver holding a publisher slot and the driver it was handed over to.
+type slot struct {
+	driver  string
+	expires time.Time
+	pending string
+}
+
+// A node subscribed to a channel.
+type localSub struct {
+	node    string
+	deliver func(are_hub.Broadcast)
+	queue   chan are_hub.Broadcast
+
+	// Closed when unsubscribed.
+	done chan struct{}
+	once sync.Once
+}
+
+// Create a new in-process backplane.
+func NewLocal() *Local {
+	return &Local{
+		subs:  make(map[string]map[*localSub]struct{}),
+		seqs:  make(map[string]uint64),
+		slots: make(map[slotKey]*slot),
+	}
+}
+
+// Queue b for every subscription to its channel by other nodes. Messages are
+// discarded if a subscription's queue is full.
+func (l *Local) Publish(b are_hub.Broadcast) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	for sub := range l.subs[b.ChannelID] {
+		if sub.node == b.Node {
+			continue
+		}
+
+		select {
+		case sub.queue <- b:
+		default:
+			log.Printf("Backplane queue of node %s full; discarding message on %s\n", sub.node, b.ChannelID)
+		}
+	}
+}
+
+// Deliver the messages published on the channel matching id by nodes other
+// than node from a goroutine until the returned function is called.
+func (l *Local) Subscribe(id, node string, deliver func(are_hub.Broadcast)) func() {
+	sub := &localSub{
+		node:    node,
+		deliver: deliver,
+		queue:   make(chan are_hub.Broadcast, QUEUE_LEN),
+		done:    make(chan struct{}),
+	}
+
+	l.mtx.Lock()
+
+	if l.subs[id] == nil {
+		l.subs[id] = make(map[*localSub]struct{})
+	}
+
+	l.subs[id][sub] = struct{}{}
+	l.mtx.Unlock()
+
+	go sub.run()
+
+	return func() {
+		l.mtx.Lock()
+		delete(l.subs[id], sub)
+
+		if len(l.subs[id]) == 0 {
+			delete(l.subs, id)
+		}
+
+		l.mtx.Unlock()
+		sub.once.Do(func() { close(sub.done) })
+	}
+}
+
+// Deliver queued messages until unsubscribed.
+func (sub *localSub) run() {
+	for {
+		select {
+		case b := <-sub.queue:
+			sub.deliver(b)
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// Get the next sequence number of the channel matching id.
+func (l *Local) Sequence(_ context.Context, id string) (uint64, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.seqs[id]++
+
+	return l.seqs[id], nil
+}
+
+// Claim the publisher slot of car on the channel matching id for driver.
+func (l *Local) Claim(_ context.Context, id, car, driver string, ttl time.Duration) (string, bool, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	s := l.slot(id, car)
+	prev := s.driver
+
+	if time.Now().After(s.expires) {
+		// claims expire once the publisher stops publishing
+		prev = ""
+	}
+
+	if len(prev) > 0 && prev != driver && s.pending != driver {
+		return prev, false, nil
+	}
+
+	if s.pending == driver {
+		s.pending = ""
+	}
+
+	s.driver = driver
+	s.expires = time.Now().Add(ttl)
+
+	return prev, true, nil
+}
+
+// Hand the publisher slot of car on the channel matching id over to driver.
+func (l *Local) Handoff(_ context.Context, id, car, driver string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.slot(id, car).pending = driver
+
+	return nil
+}
+
+// Vacate the publisher slot of car on the channel matching id.
+func (l *Local) Release(_ context.Context, id, car string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	delete(l.slots, slotKey{id, car})
+
+	return nil
+}
+
+// Discard the sequence number and publisher slots of the channel matching id.
+// The sequence restarts from 1 if the channel is served again.
+func (l *Local) Forget(_ context.Context, id string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	delete(l.seqs, id)
+
+	for k := range l.slots {
+		if k.id == id {
+			delete(l.slots, k)
+		}
+	}
+
+	return nil
+}
+
+// Get the publisher slot of car on the channel matching id. mtx must be held.
+func (l *Local) slot(id, car string) *slot {
+	k := slotKey{id, car}
+	s, ok := l.slots[k]
+
+	if !ok {
+		s = &slot{}
+		l.slots[k] = s
+	}
+
+	return s
+}
@@ -0,0 +1,208 @@
+package backplane
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// How long to wait for messages to be delivered.
+const testWait = time.Second * 2
+
+// Are messages delivered in order to other nodes but not the node that
+// published them? Are messages no longer delivered once unsubscribed?
+func testRelay(t *testing.T, bp are_hub.Backplane) {
+	received := make(chan are_hub.Broadcast, QUEUE_LEN)
+	own := make(chan are_hub.Broadcast, QUEUE_LEN)
+
+	unsubscribe := bp.Subscribe("1", "b", func(b are_hub.Broadcast) { received <- b })
+	defer bp.Subscribe("1", "a", func(b are_hub.Broadcast) { own <- b })()
+
+	// subscriptions may take effect asynchronously
+	waitSubscribed(t, bp, received)
+
+	for i := 1; i <= 3; i++ {
+		bp.Publish(are_hub.Broadcast{ChannelID: "1", Node: "a", Status: 4200, Seq: uint64(i), Data: []byte(`{}`)})
+	}
+
+	for i := 1; i <= 3; i++ {
+		select {
+		case b := <-received:
+			if b.Seq != uint64(i) || b.Node != "a" || string(b.Data) != `{}` {
+				t.Errorf("Expected: message %d from a. Actual: %+v.", i, b)
+			}
+		case <-time.After(testWait):
+			t.Fatalf("Expected message %d", i)
+		}
+	}
+
+	// give the publishing node's subscription a chance to receive them
+	time.Sleep(time.Millisecond * 50)
+
+	for len(own) > 0 {
+		if b := <-own; b.Node == "a" {
+			t.Errorf("Expected: no messages delivered to the publishing node. Actual: %+v.", b)
+		}
+	}
+
+	unsubscribe()
+	bp.Publish(are_hub.Broadcast{ChannelID: "1", Node: "a", Seq: 4})
+
+	select {
+	case b := <-received:
+		t.Errorf("Expected: no messages once unsubscribed. Actual: %+v.", b)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+// Publish probes from another node until one is delivered to received.
+func waitSubscribed(t *testing.T, bp are_hub.Backplane, received chan are_hub.Broadcast) {
+	deadline := time.Now().Add(testWait)
+
+	for time.Now().Before(deadline) {
+		bp.Publish(are_hub.Broadcast{ChannelID: "1", Node: "probe"})
+
+		select {
+		case <-received:
+			// discard the remaining probes
+			time.Sleep(time.Millisecond * 20)
+
+			for len(received) > 0 {
+				<-received
+			}
+
+			return
+		case <-time.After(time.Millisecond * 20):
+		}
+	}
+
+	t.Fatal("Expected the subscription to take effect")
+}
+
+// Are sequence numbers counted per channel?
+func testSequence(t *testing.T, bp are_hub.Backplane) {
+	ctx := context.Background()
+
+	for i, id := range []string{"1", "1", "2", "1"} {
+		seq, e := bp.Sequence(ctx, id)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		expected := map[int]uint64{0: 1, 1: 2, 2: 1, 3: 3}[i]
+
+		if seq != expected {
+			t.Errorf("Expected: %d on %s. Actual: %d.", expected, id, seq)
+		}
+	}
+}
+
+// Are publisher slots only granted to the driver holding them, the driver they
+// were handed over to, or any driver once vacant or expired?
+func testClaim(t *testing.T, bp are_hub.Backplane) {
+	ctx := context.Background()
+	ttl := time.Millisecond * 200
+
+	claim := func(driver string, granted bool, prev string) {
+		t.Helper()
+
+		p, ok, e := bp.Claim(ctx, "1", "31", driver, ttl)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if ok != granted || p != prev {
+			t.Errorf("Expected: %s granted %v after %q. Actual: %v after %q.", driver, granted, prev, ok, p)
+		}
+	}
+
+	claim("Vanthoor", true, "")
+	claim("Vanthoor", true, "Vanthoor")
+	claim("Weerts", false, "Vanthoor")
+
+	// other cars are claimed separately
+	if _, ok, e := bp.Claim(ctx, "1", "32", "Weerts", ttl); e != nil || !ok {
+		t.Errorf("Expected: car 32 granted. Actual: %v, %v.", ok, e)
+	}
+
+	if e := bp.Handoff(ctx, "1", "31", "Weerts"); e != nil {
+		t.Fatal(e)
+	}
+
+	claim("Weerts", true, "Vanthoor")
+	claim("Vanthoor", false, "Weerts")
+
+	if e := bp.Release(ctx, "1", "31"); e != nil {
+		t.Fatal(e)
+	}
+
+	claim("Vanthoor", true, "")
+	time.Sleep(ttl * 2)
+	claim("Weerts", true, "")
+}
+
+func TestLocal(t *testing.T) {
+	for name, test := range map[string]func(*testing.T, are_hub.Backplane){
+		"relay":    testRelay,
+		"sequence": testSequence,
+		"claim":    testClaim,
+	} {
+		t.Run(name, func(t *testing.T) {
+			test(t, NewLocal())
+		})
+	}
+}
+
+// Are messages discarded rather than blocking the publisher once a node's
+// queue is full?
+func TestLocalFull(t *testing.T) {
+	l := NewLocal()
+	block := make(chan struct{})
+	defer close(block)
+
+	defer l.Subscribe("1", "b", func(are_hub.Broadcast) { <-block })()
+
+	done := make(chan struct{})
+
+	go func() {
+		for i := 0; i < QUEUE_LEN*2; i++ {
+			l.Publish(are_hub.Broadcast{ChannelID: "1", Node: "a", Data: []byte(fmt.Sprint(i))})
+		}
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(testWait):
+		t.Fatal("Expected publishing not to block")
+	}
+}
+
+// Are the sequence numbers and publisher slots of forgotten channels discarded?
+func TestLocalForget(t *testing.T) {
+	ctx := context.Background()
+	l := NewLocal()
+
+	for _, id := range []string{"1", "2"} {
+		l.Sequence(ctx, id)
+		l.Claim(ctx, id, "31", "Vanthoor", time.Minute)
+	}
+
+	if e := l.Forget(ctx, "1"); e != nil {
+		t.Fatal(e)
+	}
+
+	if _, ok := l.seqs["1"]; ok || len(l.seqs) != 1 {
+		t.Errorf("Expected: only channel 2's sequence. Actual: %v.", l.seqs)
+	}
+
+	if _, ok := l.slots[slotKey{"1", "31"}]; ok || len(l.slots) != 1 {
+		t.Errorf("Expected: only channel 2's slot. Actual: %v.", l.slots)
+	}
+}
@@ -0,0 +1,619 @@
+package backplane
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+)
+
+const (
+	// How long to wait for the Redis server to reply.
+	TIMEOUT = time.Second * 5
+
+	// Delay before reconnecting to the Redis server unless changed for testing.
+	BACKOFF = time.Second
+
+	// Prefix of the keys and pub/sub channels used.
+	PREFIX = "are_hub:"
+
+	// How long a handover waits for the driver taking over the slot.
+	PENDING_TTL = time.Hour
+
+	// Maximum number of connections sending commands with replies unless
+	// configured.
+	POOL_SIZE = 8
+)
+
+// Delay before reconnecting to the Redis server.
+var backoff = BACKOFF
+
+// Claims the publisher slot for ARGV[1] for ARGV[2] milliseconds unless it is
+// held by another driver and was not handed over to ARGV[1]. KEYS[1] holds the
+// driver holding the slot and KEYS[2] the driver it was handed over to. Returns
+// whether the slot was granted and the driver previously holding it.
+const claimScript = `local prev = redis.call('GET', KEYS[1]) or ''
+local pending = redis.call('GET', KEYS[2]) or ''
+if prev ~= '' and prev ~= ARGV[1] and pending ~= ARGV[1] then
+	return {0, prev}
+end
+if pending == ARGV[1] then
+	redis.call('DEL', KEYS[2])
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return {1, prev}`
+
+// Redis connection parameters.
+type RedisParams struct {
+	// Address (host + port). Eg. "localhost:6379".
+	Address string
+
+	// Password to authenticate with. Not sent if empty.
+	Password string
+
+	// Maximum number of connections sending commands with replies at once.
+	// POOL_SIZE if zero.
+	PoolSize int
+}
+
+// Backplane relaying messages between nodes through Redis pub/sub. Publisher
+// slots are claimed with keys expiring once their publisher stops publishing
+// and sequence numbers are counted with INCR. Commands are sent on a pool of
+// connections so that channels don't wait for each other. Implements
+// are_hub.Backplane.
+type Redis struct {
+	params RedisParams
+
+	// Idle connections sending commands with replies. Guarded by mtx.
+	mtx  sync.Mutex
+	idle []*respConn
+
+	// Holds a token for each connection sending a command so that no more
+	// than PoolSize are open.
+	conns chan struct{}
+
+	// Messages waiting to be published.
+	queue chan are_hub.Broadcast
+
+	// Subscriptions by channel ID and the connection receiving their messages.
+	// Guarded by subMtx.
+	subMtx sync.Mutex
+	subs   map[string]map[*redisSub]struct{}
+	sconn  *respConn
+
+	// Signalled when a channel is first subscribed to or last unsubscribed
+	// from so that the listening connection's subscriptions are updated
+	// without writing to it while subMtx is held. See sync.
+	changed chan struct{}
+
+	// Closed when the backplane is closed.
+	done chan struct{}
+	once sync.Once
+
+	// The publishing and listening goroutines.
+	wg sync.WaitGroup
+}
+
+// A node subscribed to a channel.
+type redisSub struct {
+	node    string
+	deliver func(are_hub.Broadcast)
+}
+
+// Create a new Redis backplane connecting to the server in p. Connections are
+// established in the background and re-established when they fail.
+func NewRedis(p RedisParams) *Redis {
+	if p.PoolSize <= 0 {
+		p.PoolSize = POOL_SIZE
+	}
+
+	r := &Redis{
+		params:  p,
+		conns:   make(chan struct{}, p.PoolSize),
+		queue:   make(chan are_hub.Broadcast, QUEUE_LEN),
+		subs:    make(map[string]map[*redisSub]struct{}),
+		changed: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	r.wg.Add(2)
+	go r.publish()
+	go r.listen()
+
+	return r
+}
+
+// Queue b to be published. Messages are discarded if the queue is full.
+func (r *Redis) Publish(b are_hub.Broadcast) {
+	select {
+	case r.queue <- b:
+	default:
+		log.Printf("Backplane queue full; discarding message on %s\n", b.ChannelID)
+	}
+}
+
+// Deliver the messages published on the channel matching id by nodes other
+// than node until the returned function is called. Messages are delivered
+// from a single goroutine shared by every subscription.
+func (r *Redis) Subscribe(id, node string, deliver func(are_hub.Broadcast)) func() {
+	sub := &redisSub{node, deliver}
+
+	r.subMtx.Lock()
+	defer r.subMtx.Unlock()
+
+	if r.subs[id] == nil {
+		r.subs[id] = make(map[*redisSub]struct{})
+		r.notify()
+	}
+
+	r.subs[id][sub] = struct{}{}
+
+	return func() {
+		r.subMtx.Lock()
+		defer r.subMtx.Unlock()
+
+		delete(r.subs[id], sub)
+
+		if len(r.subs[id]) == 0 {
+			delete(r.subs, id)
+			r.notify()
+		}
+	}
+}
+
+// Get the next sequence number of the channel matching id.
+func (r *Redis) Sequence(ctx context.Context, id string) (uint64, error) {
+	reply, e := r.do(ctx, "INCR", PREFIX+"seq:"+id)
+
+	if e != nil {
+		return 0, e
+	}
+
+	n, ok := reply.(int64)
+
+	if !ok {
+		return 0, fmt.Errorf("Unexpected INCR reply: %v", reply)
+	}
+
+	return uint64(n), nil
+}
+
+// Claim the publisher slot of car on the channel matching id for driver.
+func (r *Redis) Claim(ctx context.Context, id, car, driver string, ttl time.Duration) (string, bool, error) {
+	ms := strconv.FormatInt(ttl.Milliseconds(), 10)
+	reply, e := r.do(ctx, "EVAL", claimScript, "2", driverKey(id, car), pendingKey(id, car), driver, ms)
+
+	if e != nil {
+		return "", false, e
+	}
+
+	values, ok := reply.([]interface{})
+
+	if !ok || len(values) != 2 {
+		return "", false, fmt.Errorf("Unexpected claim reply: %v", reply)
+	}
+
+	granted, _ := values[0].(int64)
+	prev, _ := values[1].(string)
+
+	return prev, granted == 1, nil
+}
+
+// Hand the publisher slot of car on the channel matching id over to driver.
+// The handover is cancelled if the driver hasn't claimed the slot within
+// PENDING_TTL.
+func (r *Redis) Handoff(ctx context.Context, id, car, driver string) error {
+	ms := strconv.FormatInt(PENDING_TTL.Milliseconds(), 10)
+	_, e := r.do(ctx, "SET", pendingKey(id, car), driver, "PX", ms)
+
+	return e
+}
+
+// Vacate the publisher slot of car on the channel matching id.
+func (r *Redis) Release(ctx context.Context, id, car string) error {
+	_, e := r.do(ctx, "DEL", driverKey(id, car), pendingKey(id, car))
+
+	return e
+}
+
+// Keep the state of the channel matching id as other nodes may still serve it.
+// Slot keys expire once their publisher stops publishing.
+func (r *Redis) Forget(_ context.Context, id string) error {
+	return nil
+}
+
+// Close the connections to the Redis server and wait for the background
+// goroutines to stop. Queued messages are discarded.
+func (r *Redis) Close() error {
+	r.once.Do(func() { close(r.done) })
+
+	r.subMtx.Lock()
+
+	if r.sconn != nil {
+		// unblock the listening goroutine
+		r.sconn.Close()
+	}
+
+	r.subMtx.Unlock()
+	r.wg.Wait()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, conn := range r.idle {
+		conn.Close()
+	}
+
+	r.idle = nil
+
+	return nil
+}
+
+// Send a command and read its reply on an idle connection, connecting if none
+// are idle. Waits for a connection to become idle if PoolSize are in use.
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	select {
+	case r.conns <- struct{}{}:
+		defer func() { <-r.conns }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	conn, e := r.get()
+
+	if e != nil {
+		return nil, e
+	}
+
+	reply, e := conn.do(args...)
+
+	if _, ok := e.(respError); e != nil && !ok {
+		// the connection is broken; another is dialed by the next command
+		conn.Close()
+
+		return reply, e
+	}
+
+	r.put(conn)
+
+	return reply, e
+}
+
+// Take an idle connection or dial a new one.
+func (r *Redis) get() (*respConn, error) {
+	r.mtx.Lock()
+
+	if n := len(r.idle); n > 0 {
+		conn := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		r.mtx.Unlock()
+
+		return conn, nil
+	}
+
+	r.mtx.Unlock()
+
+	return dialResp(r.params.Address, r.params.Password)
+}
+
+// Return conn to the idle connections, closing it if the backplane is closed.
+func (r *Redis) put(conn *respConn) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	select {
+	case <-r.done:
+		conn.Close()
+	default:
+		r.idle = append(r.idle, conn)
+	}
+}
+
+// Tell the goroutine updating the listening connection's subscriptions that
+// they have changed. Never blocks.
+func (r *Redis) notify() {
+	select {
+	case r.changed <- struct{}{}:
+	default:
+		// already pending
+	}
+}
+
+// Subscribe conn to the channels subscribed to and unsubscribe it from the
+// channels no longer subscribed to whenever they change, until stop is closed
+// or writing fails. Replies are read by the listening goroutine.
+func (r *Redis) sync(conn *respConn, stop chan struct{}) {
+	subscribed := make(map[string]bool)
+
+	for {
+		var sub, unsub []string
+
+		r.subMtx.Lock()
+
+		for id := range r.subs {
+			if !subscribed[id] {
+				subscribed[id] = true
+				sub = append(sub, channelKey(id))
+			}
+		}
+
+		for id := range subscribed {
+			if _, ok := r.subs[id]; !ok {
+				delete(subscribed, id)
+				unsub = append(unsub, channelKey(id))
+			}
+		}
+
+		r.subMtx.Unlock()
+
+		e := command(conn, "SUBSCRIBE", sub)
+
+		if e == nil {
+			e = command(conn, "UNSUBSCRIBE", unsub)
+		}
+
+		if e != nil {
+			// reconnect and subscribe again
+			conn.Close()
+
+			return
+		}
+
+		select {
+		case <-r.changed:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Send name with keys on conn unless keys is empty.
+func command(conn *respConn, name string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	conn.conn.SetWriteDeadline(time.Now().Add(TIMEOUT))
+	defer conn.conn.SetWriteDeadline(time.Time{})
+
+	e := conn.write(append([]string{name}, keys...)...)
+
+	if e != nil {
+		return e
+	}
+
+	return conn.w.Flush()
+}
+
+// Write queued messages in batches until closed.
+func (r *Redis) publish() {
+	var conn *respConn
+
+	defer r.wg.Done()
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		var batch []are_hub.Broadcast
+
+		select {
+		case b := <-r.queue:
+			batch = append(batch, b)
+		case <-r.done:
+			return
+		}
+
+		// write everything queued at once
+	drain:
+		for len(batch) < QUEUE_LEN {
+			select {
+			case b := <-r.queue:
+				batch = append(batch, b)
+			default:
+				break drain
+			}
+		}
+
+		if conn == nil {
+			var e error
+			conn, e = dialResp(r.params.Address, r.params.Password)
+
+			if e != nil {
+				log.Printf("Backplane failed to connect; discarding %d messages: %v\n", len(batch), e)
+
+				if !r.wait(backoff) {
+					return
+				}
+
+				continue
+			}
+		}
+
+		e := send(conn, batch)
+
+		if e != nil {
+			log.Printf("Backplane failed to publish %d messages: %v\n", len(batch), e)
+			conn.Close()
+			conn = nil
+		}
+	}
+}
+
+// Publish batch on conn and read the replies.
+func send(conn *respConn, batch []are_hub.Broadcast) error {
+	conn.conn.SetDeadline(time.Now().Add(TIMEOUT))
+	defer conn.conn.SetDeadline(time.Time{})
+
+	for _, b := range batch {
+		bytes, e := json.Marshal(b)
+
+		if e != nil {
+			return e
+		}
+
+		e = conn.write("PUBLISH", channelKey(b.ChannelID), string(bytes))
+
+		if e != nil {
+			return e
+		}
+	}
+
+	e := conn.w.Flush()
+
+	if e != nil {
+		return e
+	}
+
+	for range batch {
+		_, e = conn.read()
+
+		if e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// Receive published messages, reconnecting and subscribing again when the
+// connection fails, until closed.
+func (r *Redis) listen() {
+	defer r.wg.Done()
+
+	for {
+		conn, e := dialResp(r.params.Address, r.params.Password)
+
+		if e == nil {
+			r.subMtx.Lock()
+
+			select {
+			case <-r.done:
+				// closed while connecting
+				r.subMtx.Unlock()
+				conn.Close()
+
+				return
+			default:
+			}
+
+			r.sconn = conn
+			r.subMtx.Unlock()
+
+			stop := make(chan struct{})
+			synced := make(chan struct{})
+
+			go func() {
+				defer close(synced)
+				r.sync(conn, stop)
+			}()
+
+			e = r.receive(conn)
+
+			// unblock a pending write before waiting for the subscribing
+			// goroutine
+			close(stop)
+			conn.Close()
+			<-synced
+
+			r.subMtx.Lock()
+			r.sconn = nil
+			r.subMtx.Unlock()
+		}
+
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+
+		log.Printf("Backplane subscription failed: %v\n", e)
+
+		if !r.wait(backoff) {
+			return
+		}
+	}
+}
+
+// Deliver the messages received on conn until reading fails.
+func (r *Redis) receive(conn *respConn) error {
+	for {
+		reply, e := conn.read()
+
+		if e != nil {
+			return e
+		}
+
+		values, ok := reply.([]interface{})
+
+		if !ok || len(values) != 3 || values[0] != "message" {
+			// (un)subscribe confirmation
+			continue
+		}
+
+		payload, _ := values[2].(string)
+		b := are_hub.Broadcast{}
+		e = json.Unmarshal([]byte(payload), &b)
+
+		if e != nil {
+			log.Printf("Backplane received a malformed message: %v\n", e)
+
+			continue
+		}
+
+		r.dispatch(b)
+	}
+}
+
+// Deliver b to the subscriptions of other nodes to its channel.
+func (r *Redis) dispatch(b are_hub.Broadcast) {
+	var subs []*redisSub
+
+	r.subMtx.Lock()
+
+	for sub := range r.subs[b.ChannelID] {
+		if sub.node != b.Node {
+			subs = append(subs, sub)
+		}
+	}
+
+	r.subMtx.Unlock()
+
+	for _, sub := range subs {
+		sub.deliver(b)
+	}
+}
+
+// Wait for d. Returns false if closed in the meantime.
+func (r *Redis) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-r.done:
+		return false
+	}
+}
+
+// Pub/sub channel relaying the messages of the channel matching id.
+func channelKey(id string) string {
+	return PREFIX + "channel:" + id
+}
+
+// Key holding the driver of the publisher slot of car.
+func driverKey(id, car string) string {
+	return PREFIX + "slot:" + id + ":" + car
+}
+
+// Key holding the driver the publisher slot of car was handed over to.
+func pendingKey(id, car string) string {
+	return PREFIX + "pending:" + id + ":" + car
+}
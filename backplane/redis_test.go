@@ -0,0 +1,402 @@
+package backplane
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Stand-in for a Redis server implementing the commands used by the Redis
+// backplane. The claim script is run natively rather than interpreted.
+type standin struct {
+	lis      net.Listener
+	password string
+
+	mtx     sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+
+	// Subscribed connections by pub/sub channel.
+	channels map[string]map[*standinConn]struct{}
+	conns    map[*standinConn]struct{}
+}
+
+// A connection to the stand-in. Writes are guarded by mtx as messages are
+// pushed to subscribers from other connections.
+type standinConn struct {
+	net.Conn
+	mtx sync.Mutex
+	w   *bufio.Writer
+}
+
+// Start a stand-in listening on a random local port.
+func newStandin(t *testing.T, password string) *standin {
+	lis, e := net.Listen("tcp", "127.0.0.1:0")
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	s := &standin{
+		lis:      lis,
+		password: password,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+		channels: make(map[string]map[*standinConn]struct{}),
+		conns:    make(map[*standinConn]struct{}),
+	}
+
+	go s.accept()
+	t.Cleanup(s.close)
+
+	return s
+}
+
+func (s *standin) address() string {
+	return s.lis.Addr().String()
+}
+
+func (s *standin) accept() {
+	for {
+		conn, e := s.lis.Accept()
+
+		if e != nil {
+			return
+		}
+
+		c := &standinConn{Conn: conn, w: bufio.NewWriter(conn)}
+
+		s.mtx.Lock()
+		s.conns[c] = struct{}{}
+		s.mtx.Unlock()
+
+		go s.serve(c)
+	}
+}
+
+// Stop listening and drop every connection.
+func (s *standin) close() {
+	s.lis.Close()
+	s.drop()
+}
+
+// Drop every connection as if the server restarted without losing data.
+func (s *standin) drop() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+func (s *standin) serve(c *standinConn) {
+	defer func() {
+		s.mtx.Lock()
+		delete(s.conns, c)
+
+		for _, subs := range s.channels {
+			delete(subs, c)
+		}
+
+		s.mtx.Unlock()
+		c.Close()
+	}()
+
+	r := &respConn{conn: c, r: bufio.NewReader(c)}
+	authed := len(s.password) == 0
+
+	for {
+		reply, e := r.read()
+
+		if e != nil {
+			return
+		}
+
+		values, _ := reply.([]interface{})
+		args := make([]string, len(values))
+
+		for i, v := range values {
+			args[i], _ = v.(string)
+		}
+
+		if len(args) == 0 {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+
+		if cmd == "AUTH" {
+			authed = len(args) == 2 && args[1] == s.password
+
+			if !authed {
+				c.reply("-WRONGPASS invalid password\r\n")
+
+				continue
+			}
+
+			c.reply("+OK\r\n")
+
+			continue
+		}
+
+		if !authed {
+			c.reply("-NOAUTH Authentication required.\r\n")
+
+			continue
+		}
+
+		c.reply(s.exec(c, cmd, args[1:]))
+	}
+}
+
+// Execute a command and return its encoded reply.
+func (s *standin) exec(c *standinConn, cmd string, args []string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	switch cmd {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		return bulk(s.get(args[0]))
+	case "SET":
+		s.set(args[0], args[1], args[2:])
+
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+
+		for _, k := range args {
+			if _, ok := s.get(k); ok {
+				n++
+			}
+
+			delete(s.values, k)
+		}
+
+		return fmt.Sprintf(":%d\r\n", n)
+	case "INCR":
+		v, _ := s.get(args[0])
+		n, _ := strconv.ParseInt(v, 10, 64)
+		s.values[args[0]] = strconv.FormatInt(n+1, 10)
+
+		return fmt.Sprintf(":%d\r\n", n+1)
+	case "EVAL":
+		if args[0] != claimScript {
+			return "-ERR unknown script\r\n"
+		}
+
+		return s.claim(args[2], args[3], args[4], args[5])
+	case "PUBLISH":
+		n := 0
+		msg := fmt.Sprintf("*3\r\n%s%s%s", bulk("message", true), bulk(args[0], true), bulk(args[1], true))
+
+		for sub := range s.channels[args[0]] {
+			sub.reply(msg)
+			n++
+		}
+
+		return fmt.Sprintf(":%d\r\n", n)
+	case "SUBSCRIBE", "UNSUBSCRIBE":
+		b := strings.Builder{}
+
+		for _, ch := range args {
+			if cmd == "SUBSCRIBE" {
+				if s.channels[ch] == nil {
+					s.channels[ch] = make(map[*standinConn]struct{})
+				}
+
+				s.channels[ch][c] = struct{}{}
+			} else {
+				delete(s.channels[ch], c)
+			}
+
+			fmt.Fprintf(&b, "*3\r\n%s%s:1\r\n", bulk(strings.ToLower(cmd), true), bulk(ch, true))
+		}
+
+		return b.String()
+	}
+
+	return "-ERR unknown command '" + cmd + "'\r\n"
+}
+
+// The claim script. mtx must be held.
+func (s *standin) claim(driverKey, pendingKey, driver, ms string) string {
+	prev, _ := s.get(driverKey)
+	pending, _ := s.get(pendingKey)
+
+	if len(prev) > 0 && prev != driver && pending != driver {
+		return "*2\r\n:0\r\n" + bulk(prev, true)
+	}
+
+	if pending == driver {
+		delete(s.values, pendingKey)
+	}
+
+	s.set(driverKey, driver, []string{"PX", ms})
+
+	return "*2\r\n:1\r\n" + bulk(prev, true)
+}
+
+// Get the value of an unexpired key. mtx must be held.
+func (s *standin) get(k string) (string, bool) {
+	if exp, ok := s.expires[k]; ok && time.Now().After(exp) {
+		delete(s.values, k)
+		delete(s.expires, k)
+	}
+
+	v, ok := s.values[k]
+
+	return v, ok
+}
+
+// Set a key with the options PX or EX. mtx must be held.
+func (s *standin) set(k, v string, opts []string) {
+	s.values[k] = v
+	delete(s.expires, k)
+
+	for i := 0; i+1 < len(opts); i += 2 {
+		n, _ := strconv.ParseInt(opts[i+1], 10, 64)
+
+		switch strings.ToUpper(opts[i]) {
+		case "PX":
+			s.expires[k] = time.Now().Add(time.Duration(n) * time.Millisecond)
+		case "EX":
+			s.expires[k] = time.Now().Add(time.Duration(n) * time.Second)
+		}
+	}
+}
+
+func (c *standinConn) reply(s string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.w.WriteString(s)
+	c.w.Flush()
+}
+
+// Encode v as a bulk string or a nil reply if !ok.
+func bulk(v string, ok bool) string {
+	if !ok {
+		return "$-1\r\n"
+	}
+
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+}
+
+func TestRedis(t *testing.T) {
+	for name, test := range map[string]func(*testing.T, are_hub.Backplane){
+		"relay":    testRelay,
+		"sequence": testSequence,
+		"claim":    testClaim,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newStandin(t, "hunter2")
+			r := NewRedis(RedisParams{Address: s.address(), Password: "hunter2"})
+			defer r.Close()
+
+			test(t, r)
+		})
+	}
+}
+
+// Are messages relayed between separate connections to the same server?
+// Are subscriptions restored after the connection to the server is lost?
+func TestRedisReconnect(t *testing.T) {
+	defer func(d time.Duration) { backoff = d }(backoff)
+	backoff = time.Millisecond * 10
+
+	s := newStandin(t, "")
+	a := NewRedis(RedisParams{Address: s.address()})
+	b := NewRedis(RedisParams{Address: s.address()})
+
+	defer a.Close()
+	defer b.Close()
+
+	received := make(chan are_hub.Broadcast, QUEUE_LEN)
+	defer b.Subscribe("1", "b", func(m are_hub.Broadcast) { received <- m })()
+
+	waitSubscribed(t, a, received)
+	s.drop()
+	waitSubscribed(t, a, received)
+
+	a.Publish(are_hub.Broadcast{ChannelID: "1", Node: "a", Seq: 7})
+
+	select {
+	case m := <-received:
+		if m.Seq != 7 {
+			t.Errorf("Expected: seq 7. Actual: %+v.", m)
+		}
+	case <-time.After(testWait):
+		t.Fatal("Expected the message after reconnecting")
+	}
+}
+
+// Are commands rejected with the wrong password?
+func TestRedisAuth(t *testing.T) {
+	s := newStandin(t, "hunter2")
+	r := NewRedis(RedisParams{Address: s.address(), Password: "nope"})
+	defer r.Close()
+
+	_, e := r.Sequence(context.Background(), "1")
+
+	if _, ok := e.(respError); !ok {
+		t.Errorf("Expected: error reply. Actual: %v.", e)
+	}
+}
+
+// Are concurrent commands sent on no more than PoolSize connections?
+func TestRedisPool(t *testing.T) {
+	s := newStandin(t, "")
+	r := NewRedis(RedisParams{Address: s.address(), PoolSize: 2})
+	defer r.Close()
+
+	var wg sync.WaitGroup
+	seqs := make(chan uint64, 50)
+
+	for i := 0; i < cap(seqs); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			seq, e := r.Sequence(context.Background(), "1")
+
+			if e != nil {
+				t.Error(e)
+			}
+
+			seqs <- seq
+		}()
+	}
+
+	wg.Wait()
+	close(seqs)
+
+	seen := make(map[uint64]bool)
+
+	for seq := range seqs {
+		if seen[seq] {
+			t.Errorf("Expected unique sequence numbers. Actual: %d twice.", seq)
+		}
+
+		seen[seq] = true
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	// the pool and the listening connection
+	if n := len(s.conns); n > 3 {
+		t.Errorf("Expected: at most 3 connections. Actual: %d.", n)
+	}
+}
@@ -0,0 +1,159 @@
+package backplane
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// An error reply from the Redis server.
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+// Connection speaking the Redis serialisation protocol (RESP2).
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// Connect to the Redis server at address, authenticating with password if it
+// is not empty.
+func dialResp(address, password string) (*respConn, error) {
+	conn, e := net.DialTimeout("tcp", address, TIMEOUT)
+
+	if e != nil {
+		return nil, e
+	}
+
+	c := &respConn{conn, bufio.NewReader(conn), bufio.NewWriter(conn)}
+
+	if len(password) > 0 {
+		_, e = c.do("AUTH", password)
+
+		if e != nil {
+			conn.Close()
+
+			return nil, e
+		}
+	}
+
+	return c, nil
+}
+
+// Send a command and read its reply within TIMEOUT.
+func (c *respConn) do(args ...string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(TIMEOUT))
+	defer c.conn.SetDeadline(time.Time{})
+
+	e := c.write(args...)
+
+	if e == nil {
+		e = c.w.Flush()
+	}
+
+	if e != nil {
+		return nil, e
+	}
+
+	return c.read()
+}
+
+// Buffer a command as an array of bulk strings. Call Flush on c.w to send it.
+func (c *respConn) write(args ...string) error {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	// bufio.Writer remembers the first error
+	_, e := c.w.Write(nil)
+
+	return e
+}
+
+// Read a reply. Simple and bulk strings are returned as string, integers as
+// int64, arrays as []interface{}, and nil replies as nil. Error replies are
+// returned as respError.
+func (c *respConn) read() (interface{}, error) {
+	line, e := c.line()
+
+	if e != nil {
+		return nil, e
+	}
+
+	if len(line) == 0 {
+		return nil, errors.New("Empty RESP reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, respError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, e := strconv.Atoi(line[1:])
+
+		if e != nil || n < 0 {
+			return nil, e
+		}
+
+		buf := make([]byte, n+2)
+		_, e = io.ReadFull(c.r, buf)
+
+		if e != nil {
+			return nil, e
+		}
+
+		return string(buf[:n]), nil
+	case '*':
+		n, e := strconv.Atoi(line[1:])
+
+		if e != nil || n < 0 {
+			return nil, e
+		}
+
+		values := make([]interface{}, n)
+
+		for i := range values {
+			values[i], e = c.read()
+
+			if e != nil {
+				return nil, e
+			}
+		}
+
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("Unknown RESP reply: %q", line)
+}
+
+// Read a line without its CRLF terminator.
+func (c *respConn) line() (string, error) {
+	line, e := c.r.ReadString('\n')
+
+	if e != nil {
+		return "", e
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("Malformed RESP line: %q", line)
+	}
+
+	return line[:len(line)-2], nil
+}
+
+func (c *respConn) Close() error {
+	return c.conn.Close()
+}
@@ -3,6 +3,7 @@ package are_hub
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // password implements json.Marshaler.
@@ -19,7 +20,140 @@ func (p password) MarshalJSON() ([]byte, error) {
 type Channel struct {
 	Name     string `json:"name"`
 	Password password
-	Common   `bson:",inline"`
+
+	// Name of the schema telemetry frames are validated against before being
+	// broadcast. Eg. "acc". Frames are not validated if empty.
+	Schema string `json:"schema"`
+
+	// Numbers of the cars of team channels. Each car is published separately
+	// and subscribers may follow a single car. Channels without cars have a
+	// single publisher.
+	Cars []string `json:"cars"`
+
+	// Subscriber capacity and buffering. Zero values fall back to the server's
+	// defaults.
+	Limits `bson:",inline"`
+
+	// Who may spectate the channel without its password (one of the
+	// VISIBILITY_ constants). Private if empty.
+	Visibility string `json:"visibility"`
+
+	// What the channel is publishing. The empty fields are filled from the
+	// telemetry of schemas defining metadata fields.
+	Metadata `bson:",inline"`
+
+	Common `bson:",inline"`
+}
+
+// Describes what a channel is publishing so that channels can be told apart.
+type Metadata struct {
+	// Title of the sim. Eg. "Assetto Corsa Competizione".
+	Sim string `json:"sim"`
+
+	// Model of the car (or cars of team channels) as named by the sim. Eg.
+	// "amr_v8_vantage_gt3".
+	CarModel string `json:"carModel"`
+
+	// Track as named by the sim. Eg. "spa".
+	Track string `json:"track"`
+
+	// Name of the event. Eg. "Spa 24 Hours".
+	Event string `json:"event"`
+
+	// Name of the team. Eg. "Garage 59".
+	Team string `json:"team"`
+
+	// Free-form tags. Eg. ["endurance", "pro-am"].
+	Tags []string `json:"tags"`
+}
+
+// Paths of the fields of telemetry frames describing what a channel is
+// publishing. Empty paths are not filled.
+type MetadataFields struct {
+	// Title of the sim the schema models. Filled in as is rather than looked
+	// up in frames.
+	Sim string
+
+	CarModel string
+	Track    string
+}
+
+// Channel visibilities. See Channel.Visibility.
+const (
+	// Only clients with the password (or a session) may subscribe.
+	VISIBILITY_PRIVATE = "private"
+
+	// Anyone with the channel's ID may spectate.
+	VISIBILITY_UNLISTED = "unlisted"
+
+	// Anyone may spectate and the channel is listed in the directory.
+	VISIBILITY_PUBLIC = "public"
+)
+
+// Check whether v is one of the channel visibilities.
+func IsVisibility(v string) bool {
+	return v == VISIBILITY_PRIVATE || v == VISIBILITY_UNLISTED || v == VISIBILITY_PUBLIC
+}
+
+// Subscriber capacity and buffering of telemetry channels.
+type Limits struct {
+	// Maximum number of subscribers connected at once.
+	MaxSubscribers int `json:"maxSubscribers"`
+
+	// Number of messages queued for each subscriber before it is dropped for
+	// being too slow. Also the number of recent frames kept for resuming
+	// subscribers.
+	BufferLength int `json:"bufferLength"`
+
+	// How messages are handled once a subscriber's buffer is full (one of the
+	// POLICY_ constants). Subscribers may choose their own.
+	SlowPolicy string `json:"slowPolicy"`
+}
+
+// Slow subscriber policies. See Limits.SlowPolicy.
+const (
+	// Disconnect the subscriber.
+	POLICY_DISCONNECT = "disconnect"
+
+	// Discard the oldest message queued to make room for the new message.
+	POLICY_DROP_OLDEST = "drop-oldest"
+
+	// Discard the new message.
+	POLICY_DROP_NEWEST = "drop-newest"
+
+	// Discard every message queued, keeping only the new message.
+	POLICY_CONFLATE = "conflate"
+
+	// Block the publisher until there is room or a deadline passes, after
+	// which the subscriber is disconnected.
+	POLICY_BLOCK = "block"
+)
+
+// Check whether policy is one of the slow subscriber policies.
+func IsSlowPolicy(policy string) bool {
+	switch policy {
+	case POLICY_DISCONNECT, POLICY_DROP_OLDEST, POLICY_DROP_NEWEST, POLICY_CONFLATE, POLICY_BLOCK:
+		return true
+	}
+
+	return false
+}
+
+// Get l with zero values replaced by those of defaults.
+func (l Limits) Or(defaults Limits) Limits {
+	if l.MaxSubscribers == 0 {
+		l.MaxSubscribers = defaults.MaxSubscribers
+	}
+
+	if l.BufferLength == 0 {
+		l.BufferLength = defaults.BufferLength
+	}
+
+	if len(l.SlowPolicy) == 0 {
+		l.SlowPolicy = defaults.SlowPolicy
+	}
+
+	return l
 }
 
 // Create a new channel.
@@ -54,22 +188,210 @@ func (c *Channel) SetPasswordStr(pw string) {
 	c.Password = password(pw)
 }
 
+// Check whether the channel may be spectated without its password.
+func (c *Channel) Spectatable() bool {
+	return c.Visibility == VISIBILITY_UNLISTED || c.Visibility == VISIBILITY_PUBLIC
+}
+
 type ChannelRepo interface {
 	// Get all channels
 	All(context.Context) ([]Channel, error)
 
+	// Get a page of the channels matching a query.
+	Query(context.Context, ChannelQuery) (*ChannelPage, error)
+
 	// Create a new channel
 	Insert(context.Context, Archetype) error
 
 	// Find a channel by its ID.
 	FindID(context.Context, string) (*Channel, error)
 
-	// Find and update a channel by its ID.
-	UpdateID(context.Context, string, Archetype) error
+	// Find and update a channel by its ID if its version is still the one
+	// given, incrementing the version. Returns a VersionConflict error if the
+	// channel was updated since.
+	UpdateID(context.Context, string, int64, Versioned) error
+
+	// Find and update the fields of a channel set in a patch by its ID if its
+	// version is still the one given, incrementing the version. Returns the
+	// updated channel or a VersionConflict error if the channel was updated
+	// since.
+	PatchID(context.Context, string, int64, ChannelPatch) (*Channel, error)
+
+	// Find and set the empty metadata fields of a channel by its ID to those
+	// of the given metadata without incrementing the version. Fields already
+	// set are left as they are. Returns the updated channel or a
+	// NoObjectsFound error if none of the fields were empty.
+	FillID(context.Context, string, Metadata) (*Channel, error)
+
+	// Mark a channel as updated by its ID without incrementing the version so
+	// that every server watching channels is told it changed (eg. its alert
+	// rules). Returns a NoObjectsFound error if the channel doesn't exist.
+	TouchID(context.Context, string) error
 
-	// Find and delete a channel by its ID.
+	// Find and move a channel to the trash by its ID.
 	DeleteID(context.Context, string) (*Channel, error)
 
+	// Get the channels in the trash, most recently deleted first.
+	Trash(context.Context) ([]Channel, error)
+
+	// Find and restore a channel from the trash by its ID.
+	RestoreID(context.Context, string) (*Channel, error)
+
+	// Permanently delete the channels moved to the trash before the given time
+	// along with their messages, laps, alert rules, alerts, webhooks, and
+	// deliveries. Returns the number of channels deleted.
+	Purge(context.Context, time.Time) (int64, error)
+
 	// Get a count of channels.
 	Count(context.Context) (int64, error)
 }
+
+// Fields of a channel to update. Nil fields are left as they are.
+type ChannelPatch struct {
+	Name           *string
+	Schema         *string
+	Cars           *[]string
+	MaxSubscribers *int
+	BufferLength   *int
+	SlowPolicy     *string
+	Visibility     *string
+	Sim            *string
+	CarModel       *string
+	Track          *string
+	Event          *string
+	Team           *string
+	Tags           *[]string
+
+	// Hash of the new password. Only set when changing the password.
+	Password *string
+}
+
+// Get a channel patch from a context.
+func ChannelPatchFromCtx(ctx context.Context) (*ChannelPatch, error) {
+	v := ctx.Value(keyChannelPatch)
+	p, ok := v.(*ChannelPatch)
+
+	if !ok {
+		return nil, fmt.Errorf("Could not assert %v as *ChannelPatch\n", v)
+	}
+
+	return p, nil
+}
+
+// Insert a channel patch into context.
+func (p *ChannelPatch) ToCtx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyChannelPatch, p)
+}
+
+// A channel's current and new password in plaintext.
+type PasswordChange struct {
+	Current string
+	New     string
+}
+
+// Get a password change from a context.
+func PasswordChangeFromCtx(ctx context.Context) (*PasswordChange, error) {
+	v := ctx.Value(keyPasswordChange)
+	pc, ok := v.(*PasswordChange)
+
+	if !ok {
+		return nil, fmt.Errorf("Could not assert %v as *PasswordChange\n", v)
+	}
+
+	return pc, nil
+}
+
+// Insert a password change into context.
+func (pc *PasswordChange) ToCtx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyPasswordChange, pc)
+}
+
+// Fields channels can be sorted by. See ChannelQuery.Sort.
+const (
+	SORT_NAME    = "name"
+	SORT_CREATED = "createdAt"
+	SORT_UPDATED = "updatedAt"
+)
+
+// Check whether field is one of the fields channels can be sorted by.
+func IsChannelSort(field string) bool {
+	switch field {
+	case SORT_NAME, SORT_CREATED, SORT_UPDATED:
+		return true
+	}
+
+	return false
+}
+
+// Filters, sort order, and page of a query over channels. Zero values match
+// every channel.
+type ChannelQuery struct {
+	// Case-insensitive substring of the channel's name.
+	Name string
+
+	// One of the VISIBILITY_ constants. Channels of every visibility match if
+	// empty.
+	Visibility string
+
+	// Case-insensitive substrings of the channel's metadata.
+	Sim      string
+	CarModel string
+	Track    string
+	Event    string
+	Team     string
+
+	// Tags the channel must have, all of them if more than one.
+	Tags []string
+
+	// When the channel was created and last updated. Both ends are inclusive.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+
+	// Field to sort by (one of the SORT_ constants) and whether in descending
+	// order. Channels are sorted in order of creation if empty.
+	Sort       string
+	Descending bool
+
+	// Number of matching channels to skip and the maximum number to return.
+	// Every channel is returned if Limit is zero.
+	Offset int64
+	Limit  int64
+}
+
+// A page of the channels matching a query.
+type ChannelPage struct {
+	Channels []Channel
+
+	// Number of channels matching the query across every page.
+	Total int64
+}
+
+// Types of changes made to channels.
+const (
+	// A channel was created or updated.
+	CHANGE_UPDATED = "updated"
+
+	// A channel was deleted.
+	CHANGE_DELETED = "deleted"
+)
+
+// A change made to a channel by any instance of the server.
+type ChannelChange struct {
+	// One of the CHANGE_* constants.
+	Type string
+
+	ID string
+
+	// The channel after the change. Nil if it was deleted.
+	Channel *Channel
+}
+
+// Implemented by channel repositories able to report changes made to channels
+// by other instances of the server. Optional.
+type ChannelWatcher interface {
+	// Call fn with every change made to channels until ctx is cancelled or
+	// watching fails. Changes made while not watching are not reported.
+	Watch(ctx context.Context, fn func(ChannelChange)) error
+}
@@ -5,6 +5,9 @@ import (
 	"log"
 	"os"
 
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/backplane"
+	"github.com/blacksfk/are_hub/http"
 	"github.com/blacksfk/are_hub/mongodb"
 )
 
@@ -18,6 +21,39 @@ type config struct {
 
 	// allow requests originating from this domain. Eg. "example.com", "*".
 	AllowOrigin string
+
+	// address for the gRPC server to listen on. Eg. ":6061". The gRPC server is
+	// not started if empty.
+	GRPCAddress string
+
+	// bearer token authorising requests to the admin API. The admin API is
+	// disabled if empty.
+	AdminToken string
+
+	// subscriber capacity, buffering, and slow subscriber policy of channels that
+	// don't define their own. Eg. {"maxSubscribers": 10, "bufferLength": 16,
+	// "slowPolicy": "disconnect"}. Zero values fall back to http.MAX_SUBS,
+	// http.BUF_LEN, and are_hub.POLICY_DISCONNECT.
+	ChannelDefaults are_hub.Limits
+
+	// the largest subscriber capacity and buffering channels may be given.
+	// Zero values fall back to ChannelDefaults. The slow subscriber policy is
+	// ignored.
+	ChannelMaximums are_hub.Limits
+
+	// spectator capacity and downsampling of unlisted and public channels. Eg.
+	// {"maxSpectators": 500, "frameInterval": 1000}. Zero values fall back to
+	// http.MAX_SPECTATORS and http.SPECTATOR_INTERVAL.
+	Spectators http.SpectatorLimits
+
+	// days channels stay in the trash before they are permanently deleted along
+	// with their messages, laps, alert rules, alerts, webhooks, and deliveries.
+	// Zero falls back to TRASH_RETENTION_DAYS.
+	TrashRetentionDays int
+
+	// redis connection parameters of the backplane relaying channels between
+	// nodes. Channels are only served by this node if nil.
+	Redis *backplane.RedisParams
 }
 
 // Unmarshal file as JSON into a config struct. This function is only intended to be
@@ -29,7 +65,7 @@ func load(file string) *config {
 
 	if e != nil {
 		// reading failed so die
-		log.Fatal("Error loading %s:", e)
+		log.Fatalf("Error loading %s: %v", file, e)
 	}
 
 	// unmarshal the bytes
@@ -38,7 +74,33 @@ func load(file string) *config {
 
 	if e != nil {
 		// unmarshalling failed so die
-		log.Fatalf("Error processing %s as JSON:", e)
+		log.Fatalf("Error processing %s as JSON: %v", file, e)
+	}
+
+	conf.ChannelDefaults = conf.ChannelDefaults.Or(are_hub.Limits{
+		MaxSubscribers: http.MAX_SUBS,
+		BufferLength:   http.BUF_LEN,
+		SlowPolicy:     are_hub.POLICY_DISCONNECT,
+	})
+	conf.ChannelMaximums = conf.ChannelMaximums.Or(conf.ChannelDefaults)
+
+	if conf.ChannelDefaults.MaxSubscribers > conf.ChannelMaximums.MaxSubscribers ||
+		conf.ChannelDefaults.BufferLength > conf.ChannelMaximums.BufferLength {
+		// the defaults would be rejected by the channel validator so die
+		log.Fatalf("Error processing %s: ChannelDefaults exceed ChannelMaximums", file)
+	}
+
+	conf.Spectators = conf.Spectators.Or(http.SpectatorLimits{
+		MaxSpectators: http.MAX_SPECTATORS,
+		FrameInterval: http.SPECTATOR_INTERVAL,
+	})
+
+	if conf.TrashRetentionDays <= 0 {
+		conf.TrashRetentionDays = TRASH_RETENTION_DAYS
+	}
+
+	if !are_hub.IsSlowPolicy(conf.ChannelDefaults.SlowPolicy) {
+		log.Fatalf("Error processing %s: unknown slow subscriber policy %s", file, conf.ChannelDefaults.SlowPolicy)
 	}
 
 	return conf
@@ -3,9 +3,13 @@ package main
 import (
 	"flag"
 	"log"
+	"net"
 	"net/http"
 
+	"github.com/blacksfk/are_hub/rpc"
+	"github.com/blacksfk/are_hub/rpc/pb"
 	uf "github.com/blacksfk/microframework"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -47,12 +51,33 @@ func main() {
 	})
 
 	// define routes
-	routes(s, services)
+	routes(s, services, conf)
+
+	// serve gRPC alongside HTTP
+	if len(conf.GRPCAddress) > 0 {
+		go serveGRPC(conf.GRPCAddress, services)
+	}
 
 	// anchors aweigh!
 	log.Fatal(s.Start())
 }
 
+// Listen for gRPC connections on address. Dies if the listener cannot be created
+// or the server stops.
+func serveGRPC(address string, services *services) {
+	lis, e := net.Listen("tcp", address)
+
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	gs := grpc.NewServer()
+	pb.RegisterTelemetryServer(gs, rpc.NewTelemetry(services.telemetry))
+
+	log.Printf("Starting gRPC server on %s\n", address)
+	log.Fatal(gs.Serve(lis))
+}
+
 func logStdout(e error) {
 	log.Println(e)
 }
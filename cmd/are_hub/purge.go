@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+)
+
+const (
+	// Days channels stay in the trash unless configured otherwise.
+	TRASH_RETENTION_DAYS = 30
+
+	// How often the trash is purged.
+	PURGE_INTERVAL = time.Hour
+)
+
+// Permanently delete the channels that have been in the trash for longer
+// than retention every PURGE_INTERVAL. Never returns.
+func purge(channels are_hub.ChannelRepo, retention time.Duration) {
+	for {
+		n, e := channels.Purge(context.Background(), time.Now().UTC().Add(-retention))
+
+		if e != nil {
+			log.Printf("Purging the trash failed: %v\n", e)
+		} else if n > 0 {
+			log.Printf("Purged %d channels from the trash\n", n)
+		}
+
+		time.Sleep(PURGE_INTERVAL)
+	}
+}
@@ -2,29 +2,106 @@ package main
 
 import (
 	"github.com/blacksfk/are_hub/http"
+	"github.com/blacksfk/are_hub/http/middleware/auth"
 	"github.com/blacksfk/are_hub/http/middleware/validate"
+	"github.com/blacksfk/are_hub/metrics"
 	uf "github.com/blacksfk/microframework"
-	"nhooyr.io/websocket"
 )
 
 // HTTP route definitions.
-func routes(s *uf.Server, services *services) {
+func routes(s *uf.Server, services *services, conf *config) {
+	ts := services.telemetry
+
 	// channel routes
-	c := http.NewChannel(services.channels)
-	v := validate.NewChannel()
+	c := http.NewChannel(services.channels, services.sessions, services.events, services.telemetry, services.audits)
+	v := validate.NewChannel(conf.ChannelMaximums)
 
 	s.NewGroup("/channel").Get(c.Index).Post(c.Store, v.Store)
-	s.NewGroup("/channel/:id").Get(c.Show).Put(c.Update, v.Store).Delete(c.Delete)
+	s.NewGroup("/channel/:id").Get(c.Show).Put(c.Update, v.Store).Patch(c.Patch, v.Update).Delete(c.Delete)
+	s.Put("/channel/:id/password", c.Password, v.Password)
+	s.Post("/channel/:id/restore", c.Restore)
+	s.Get("/trash/channel", c.Trash)
+	s.Get("/directory", c.Directory)
 
-	// websocket upgrade routes
-	opts := &websocket.AcceptOptions{
-		InsecureSkipVerify: true,
-		CompressionMode:    websocket.CompressionDisabled,
-	}
+	// invitation routes
+	iv := http.NewInvite(services.invites, services.sessions, services.audits)
+	vi := validate.NewInvite()
+
+	// managing invitations requires the password rather than a session
+	s.NewGroup("/channel/:id/invites", ts.Owner).Get(iv.Index).Post(iv.Store, vi.Store)
+	s.Delete("/channel/:id/invites/:invite", iv.Delete, ts.Owner)
+	s.Post("/invites/redeem", iv.Redeem)
+
+	// the routes below expose more than spectators receive so require the
+	// channel password like the raw telemetry. Subscriber sessions may only
+	// read
+
+	// chat message routes
+	m := http.NewMessage(services.messages)
+
+	s.Get("/channel/:id/messages", m.Index, ts.Reader)
+
+	// lap routes
+	l := http.NewLap(services.laps)
+
+	s.Get("/channel/:id/laps", l.Index, ts.Reader)
+	s.Get("/channel/:id/laps/:lap", l.Show, ts.Reader)
+
+	// alert rule routes
+	ar := http.NewAlertRule(services.rules, services.telemetry)
+	va := validate.NewAlertRule()
+
+	s.NewGroup("/channel/:id/rules").Get(ar.Index, ts.Reader).Post(ar.Store, ts.Writer, va.Store)
+	s.NewGroup("/channel/:id/rules/:rule").Get(ar.Show, ts.Reader).Put(ar.Update, ts.Writer, va.Store).Delete(ar.Delete, ts.Writer)
+
+	// alert history routes
+	a := http.NewAlert(services.alerts)
+
+	s.Get("/channel/:id/alerts", a.Index, ts.Reader)
 
-	ts := http.NewTelemetryServer(opts, services.channels)
+	// webhook routes (changing them requires the password rather than a session)
+	wh := http.NewWebhook(services.webhooks, services.deliveries)
+	vw := validate.NewWebhook()
 
+	s.NewGroup("/channel/:id/webhooks").Get(wh.Index, ts.Reader).Post(wh.Store, ts.Owner, vw.Store)
+	s.NewGroup("/channel/:id/webhooks/:webhook").Get(wh.Show, ts.Reader).Put(wh.Update, ts.Owner, vw.Store).Delete(wh.Delete, ts.Owner)
+	s.Get("/channel/:id/webhooks/:webhook/deliveries", wh.Deliveries, ts.Reader)
+
+	// prometheus metrics
+	mt := http.NewMetrics(metrics.Registry)
+
+	s.Get("/metrics", mt.Index)
+
+	// admin routes (authorised with the admin token)
+	ad := http.NewAdmin(services.telemetry, services.audits)
+	aa := auth.NewAdmin(conf.AdminToken)
+
+	s.NewGroup("/admin/channels", aa.Authorise).Get(ad.Index)
+	s.NewGroup("/admin/channels/:id", aa.Authorise).Get(ad.Show).Delete(ad.Close)
+	s.NewGroup("/admin/channels/:id/subscribers/:sub", aa.Authorise).Delete(ad.Kick)
+	s.NewGroup("/admin/channels/:id/publisher", aa.Authorise).Delete(ad.Reset)
+
+	// webhooks receiving the events of every channel (authorised with the admin token)
+	s.NewGroup("/webhooks", aa.Authorise).Get(wh.Index).Post(wh.Store, vw.Store)
+	s.NewGroup("/webhooks/:webhook", aa.Authorise).Get(wh.Show).Put(wh.Update, vw.Store).Delete(wh.Delete)
+	s.Get("/webhooks/:webhook/deliveries", wh.Deliveries, aa.Authorise)
+
+	// audit log of channel changes and admin actions (authorised with the admin token)
+	au := http.NewAudit(services.audits)
+
+	s.NewGroup("/audit", aa.Authorise).Get(au.Index)
+
+	// telemetry frame schema routes
+	sc := http.NewSchema()
+
+	s.Get("/schema/:name", sc.Show)
+
+	// websocket upgrade routes
 	s.Get("/subscribe/:id", ts.Subscribe)
+	s.Get("/subscribe/:id/events", ts.Events)
+	s.Get("/spectate/:id", ts.Spectate)
+	s.Get("/channel/:id/strategy", ts.Strategy, ts.Reader)
 	// s.Get("/publish/:id", ts.Publish)
 	s.Post("/publish/:id", ts.Publish)
+	s.Post("/publish/:id/swap", ts.Swap)
 }
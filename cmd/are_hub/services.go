@@ -3,15 +3,41 @@ package main
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/backplane"
+	"github.com/blacksfk/are_hub/http"
+	"github.com/blacksfk/are_hub/metrics"
 	"github.com/blacksfk/are_hub/mongodb"
+	"github.com/blacksfk/are_hub/webhook"
+	"nhooyr.io/websocket"
 )
 
 // Wraps various database tables and services that
 // require initilisation.
 type services struct {
 	channels are_hub.ChannelRepo
+	messages are_hub.MessageRepo
+	laps     are_hub.LapRepo
+	rules    are_hub.AlertRuleRepo
+	alerts   are_hub.AlertRepo
+
+	webhooks   are_hub.WebhookRepo
+	deliveries are_hub.DeliveryRepo
+
+	// invitations to channels and the sessions redeemed from them
+	invites  are_hub.InviteRepo
+	sessions are_hub.SessionRepo
+
+	// audit log of channel changes and admin actions
+	audits are_hub.AuditRepo
+
+	// delivers channel events to webhooks
+	events *webhook.Dispatcher
+
+	// shared by the websocket, HTTP, and gRPC telemetry routes
+	telemetry *http.TelemetryServer
 }
 
 // Initialise various services and create mongodb collections based on conf. This function
@@ -25,7 +51,64 @@ func initServices(conf *config) *services {
 		log.Fatal(e)
 	}
 
-	return &services{
-		mongodb.NewChannelCollection(client, conf.MongoDB.Name),
+	e = mongodb.CreateIndexes(context.Background(), client, conf.MongoDB.Name)
+
+	if e != nil {
+		// most likely documents violating a unique index
+		log.Fatal(e)
+	}
+
+	s := &services{
+		channels: mongodb.NewChannelCollection(client, conf.MongoDB.Name),
+		messages: mongodb.NewMessageCollection(client, conf.MongoDB.Name),
+		laps:     mongodb.NewLapCollection(client, conf.MongoDB.Name),
+		rules:    mongodb.NewAlertRuleCollection(client, conf.MongoDB.Name),
+		alerts:   mongodb.NewAlertCollection(client, conf.MongoDB.Name),
+
+		webhooks:   mongodb.NewWebhookCollection(client, conf.MongoDB.Name),
+		deliveries: mongodb.NewDeliveryCollection(client, conf.MongoDB.Name),
+
+		invites:  mongodb.NewInviteCollection(client, conf.MongoDB.Name),
+		sessions: mongodb.NewSessionCollection(client, conf.MongoDB.Name),
+
+		audits: mongodb.NewAuditCollection(client, conf.MongoDB.Name),
 	}
+
+	s.events = webhook.NewDispatcher(s.webhooks, s.deliveries)
+
+	// websocket upgrade options
+	opts := &websocket.AcceptOptions{
+		InsecureSkipVerify: true,
+		CompressionMode:    websocket.CompressionDisabled,
+	}
+
+	s.telemetry = http.NewTelemetryServer(opts, http.TelemetryRepos{
+		Channels: s.channels,
+		Messages: s.messages,
+		Laps:     s.laps,
+		Rules:    s.rules,
+		Alerts:   s.alerts,
+		Sessions: s.sessions,
+	}, s.events)
+
+	s.telemetry.SetDefaults(conf.ChannelDefaults)
+	s.telemetry.SetSpectatorLimits(conf.Spectators)
+
+	if conf.Redis != nil {
+		// relay channels between nodes
+		s.telemetry.SetBackplane(backplane.NewRedis(*conf.Redis))
+	}
+
+	if w, ok := s.channels.(are_hub.ChannelWatcher); ok {
+		// pick up channels changed or deleted through other instances
+		go s.telemetry.Watch(context.Background(), w)
+	}
+
+	// permanently delete channels once they have been in the trash for too long
+	go purge(s.channels, time.Duration(conf.TrashRetentionDays)*time.Hour*24)
+
+	// report the live channels alongside the other metrics
+	metrics.Registry.MustRegister(s.telemetry)
+
+	return s
 }
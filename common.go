@@ -8,6 +8,14 @@ type Common struct {
 	ID        string    `json:"id" bson:"_id,omitempty"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Incremented by repositories supporting optimistic concurrency on every
+	// update. Zero until first updated.
+	Version int64 `json:"version" bson:",omitempty"`
+
+	// When the document was moved to the trash. Nil unless deleted.
+	// Repositories exclude deleted documents unless stated otherwise.
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:",omitempty"`
 }
 
 // Set ID.
@@ -30,3 +38,8 @@ func (c *Common) Created() {
 func (c *Common) Updated() {
 	c.UpdatedAt = time.Now().UTC()
 }
+
+// Set Version.
+func (c *Common) SetVersion(v int64) {
+	c.Version = v
+}
@@ -7,7 +7,7 @@ import (
 
 func TestSetID(t *testing.T) {
 	expected := "abc123"
-	c := &common{}
+	c := &Common{}
 
 	c.SetID(expected)
 
@@ -17,7 +17,7 @@ func TestSetID(t *testing.T) {
 }
 
 func TestUnSetID(t *testing.T) {
-	c := &common{ID: "abc123"}
+	c := &Common{ID: "abc123"}
 
 	c.UnsetID()
 
@@ -27,7 +27,7 @@ func TestUnSetID(t *testing.T) {
 }
 
 func TestCreated(t *testing.T) {
-	c := &common{}
+	c := &Common{}
 	zero := time.Time{}
 
 	c.Created()
@@ -39,7 +39,7 @@ func TestCreated(t *testing.T) {
 }
 
 func TestUpdated(t *testing.T) {
-	c := &common{}
+	c := &Common{}
 	zero := time.Time{}
 
 	c.Updated()
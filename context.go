@@ -10,6 +10,11 @@ type ctxKey uint
 // and: https://golang.org/doc/effective_go.html#constants
 const (
 	keyChannel ctxKey = iota
+	keyAlertRule
+	keyWebhook
+	keyChannelPatch
+	keyPasswordChange
+	keyInvite
 )
 
 // Types implementing this interface can be stored in a context.
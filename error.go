@@ -24,3 +24,50 @@ func IsNoObjectsFound(e error) bool {
 
 	return ok
 }
+
+// Returned from repositories when an object would have the same value of a
+// unique field as an existing object. Duplicate implements error.
+type Duplicate struct {
+	repo, field string
+}
+
+// Create a new Duplicate error specifying the repository (eg. "channels") and
+// the unique field (eg. "name").
+func NewDuplicate(r, f string) *Duplicate {
+	return &Duplicate{r, f}
+}
+
+func (e *Duplicate) Error() string {
+	return fmt.Sprintf("%s: An object with the same %s already exists", e.repo, e.field)
+}
+
+// Auxiliary function to determine whether or not an error is a Duplicate error.
+func IsDuplicate(e error) bool {
+	_, ok := e.(*Duplicate)
+
+	return ok
+}
+
+// Returned from repositories when an object was updated since the version an
+// update is based on. VersionConflict implements error.
+type VersionConflict struct {
+	repo, id string
+	version  int64
+}
+
+// Create a new VersionConflict error specifying the repository (eg.
+// "channels"), the object's ID, and the version the update is based on.
+func NewVersionConflict(r, id string, v int64) *VersionConflict {
+	return &VersionConflict{r, id, v}
+}
+
+func (e *VersionConflict) Error() string {
+	return fmt.Sprintf("%s: %s was updated since version %d", e.repo, e.id, e.version)
+}
+
+// Auxiliary function to determine whether or not an error is a VersionConflict error.
+func IsVersionConflict(e error) bool {
+	_, ok := e.(*VersionConflict)
+
+	return ok
+}
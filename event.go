@@ -0,0 +1,51 @@
+package are_hub
+
+import "time"
+
+// Types of events emitted.
+const (
+	// A channel was created.
+	EVENT_CHANNEL_CREATED = "channel.created"
+
+	// A channel was moved to the trash.
+	EVENT_CHANNEL_DELETED = "channel.deleted"
+
+	// A channel was restored from the trash.
+	EVENT_CHANNEL_RESTORED = "channel.restored"
+
+	// The publisher of a channel started a new session.
+	EVENT_SESSION_STARTED = "session.started"
+
+	// The publisher of a channel stopped broadcasting frames.
+	EVENT_PUBLISHER_OFFLINE = "publisher.offline"
+
+	// An alert rule fired on a channel.
+	EVENT_ALERT_FIRED = "alert.fired"
+
+	// A new driver took over a car of a team channel.
+	EVENT_DRIVER_SWAPPED = "driver.swapped"
+)
+
+// Something that occurred on a channel.
+type Event struct {
+	// Unique identifier. Set when the event is emitted.
+	ID string `json:"id"`
+
+	// One of the EVENT_* constants.
+	Type string `json:"event"`
+
+	ChannelID string      `json:"channelId"`
+	Time      time.Time   `json:"time"`
+	Data      interface{} `json:"data"`
+}
+
+// Create a new event of type t that occurred on a channel now.
+func NewEvent(t, channelID string, data interface{}) Event {
+	return Event{Type: t, ChannelID: channelID, Time: time.Now().UTC(), Data: data}
+}
+
+// Receives the events that occur on channels. Implementations must not block
+// and must be safe for concurrent use.
+type Emitter interface {
+	Emit(Event)
+}
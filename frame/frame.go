@@ -0,0 +1,162 @@
+// Package frame provides access to the fields of generic JSON encoded telemetry
+// frames by path so that the hub can interpret frames of any schema.
+package frame
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// A decoded telemetry frame.
+type Frame map[string]interface{}
+
+// Decode a JSON encoded frame.
+func Decode(bytes []byte) (Frame, error) {
+	var f Frame
+
+	return f, json.Unmarshal(bytes, &f)
+}
+
+// Find the value at path (dot separated, eg. "graphics.completedLaps"). Elements
+// of arrays are found by their index, eg. "physics.wheelsPressure.0".
+func (f Frame) Lookup(path string) (interface{}, bool) {
+	if len(path) == 0 || f == nil {
+		return nil, false
+	}
+
+	var v interface{} = map[string]interface{}(f)
+
+	for _, key := range strings.Split(path, ".") {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+
+			v, ok = t[key]
+
+			if !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, e := strconv.Atoi(key)
+
+			if e != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+
+			v = t[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// Find the number at path.
+func (f Frame) Number(path string) (float64, bool) {
+	v, ok := f.Lookup(path)
+
+	if !ok {
+		return 0, false
+	}
+
+	n, ok := v.(float64)
+
+	return n, ok
+}
+
+// Find the array of numbers at path.
+func (f Frame) Numbers(path string) ([]float64, bool) {
+	v, ok := f.Lookup(path)
+
+	if !ok {
+		return nil, false
+	}
+
+	slice, ok := v.([]interface{})
+
+	if !ok {
+		return nil, false
+	}
+
+	numbers := make([]float64, len(slice))
+
+	for i, v := range slice {
+		n, ok := v.(float64)
+
+		if !ok {
+			return nil, false
+		}
+
+		numbers[i] = n
+	}
+
+	return numbers, true
+}
+
+// Find the string at path.
+func (f Frame) String(path string) (string, bool) {
+	v, ok := f.Lookup(path)
+
+	if !ok {
+		return "", false
+	}
+
+	str, ok := v.(string)
+
+	return str, ok
+}
+
+// Find the value at path and interpret it as a boolean. Numbers are true if
+// they are non-zero.
+func (f Frame) Bool(path string) (bool, bool) {
+	v, ok := f.Lookup(path)
+
+	if !ok {
+		return false, false
+	}
+
+	switch t := v.(type) {
+	case bool:
+		return t, true
+	case float64:
+		return t != 0, true
+	}
+
+	return v != nil, true
+}
+
+// Get a copy of the frame with only the values at paths. Paths not found are
+// left out. Eg. picking "physics.speedKmh" from a frame with a physics page
+// returns {"physics": {"speedKmh": ...}}. Arrays are picked whole rather than
+// by element.
+func (f Frame) Pick(paths []string) Frame {
+	picked := Frame{}
+
+	for _, path := range paths {
+		v, ok := f.Lookup(path)
+
+		if !ok {
+			continue
+		}
+
+		keys := strings.Split(path, ".")
+		m := map[string]interface{}(picked)
+
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := m[key].(map[string]interface{})
+
+			if !ok {
+				next = make(map[string]interface{})
+				m[key] = next
+			}
+
+			m = next
+		}
+
+		m[keys[len(keys)-1]] = v
+	}
+
+	return picked
+}
@@ -0,0 +1,32 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Number of random bytes in a token.
+const TOKEN_LEN = 32
+
+// Generate a random hex encoded token starting with prefix.
+func NewToken(prefix string) (string, error) {
+	bytes := make([]byte, TOKEN_LEN)
+
+	// fill with secure random bytes
+	_, e := rand.Read(bytes)
+
+	if e != nil {
+		return "", e
+	}
+
+	return prefix + hex.EncodeToString(bytes), nil
+}
+
+// Get the SHA-256 hash of a token to store and look it up by. Tokens are long
+// and random so, unlike passwords, don't need a slow, salted hash.
+func Token(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+// Middleware requiring the channel password (or a session token of any role)
+// in the "Channel-Password" header. Protects the routes reading more than
+// spectators receive (eg. laps and strategy) the same as the raw telemetry.
+// Routes without a channel ID are not affected.
+func (ts *TelemetryServer) Reader(r *http.Request) error {
+	return ts.guard(r, are_hub.ROLE_SUBSCRIBER)
+}
+
+// Middleware requiring the channel password (or a publisher session token) in
+// the "Channel-Password" header. Protects the routes changing how a channel is
+// processed (eg. alert rules) from subscribers. Routes without a channel ID are
+// not affected.
+func (ts *TelemetryServer) Writer(r *http.Request) error {
+	return ts.guard(r, are_hub.ROLE_PUBLISHER)
+}
+
+// Middleware requiring the channel password in the "Channel-Password" header.
+// Protects the routes granting access to the channel (eg. invitations) or
+// sending its events elsewhere (eg. webhooks) from every session.
+func (ts *TelemetryServer) Owner(r *http.Request) error {
+	return owner(ts.repo, r)
+}
+
+// Check the "Channel-Password" header of r is the password of the channel in
+// the URL. Session tokens are not accepted.
+func owner(channels are_hub.ChannelRepo, r *http.Request) error {
+	plaintext := r.Header.Get("Channel-Password")
+
+	if len(plaintext) == 0 {
+		return uf.Unauthorized("Channel password required.")
+	}
+
+	channel, e := channels.FindID(r.Context(), uf.GetParam(r, "id"))
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound(e.Error())
+		}
+
+		return e
+	}
+
+	match, e := verify(channel.PasswordStr(), plaintext)
+
+	if e != nil {
+		return e
+	}
+
+	if !match {
+		return uf.Forbidden("Incorrect channel password.")
+	}
+
+	return nil
+}
+
+// Check the "Channel-Password" header of r is the password of the channel in
+// the URL or the token of a session of the channel permitting role.
+func (ts *TelemetryServer) guard(r *http.Request, role string) error {
+	id := uf.GetParam(r, "id")
+
+	if len(id) == 0 {
+		return nil
+	}
+
+	channel, e := ts.repo.FindID(r.Context(), id)
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound(e.Error())
+		}
+
+		return e
+	}
+
+	plaintext := r.Header.Get("Channel-Password")
+
+	if len(plaintext) == 0 {
+		return uf.Unauthorized("Channel password required.")
+	}
+
+	match, e := ts.admit(r.Context(), id, channel.PasswordStr(), plaintext, role)
+
+	if e != nil {
+		return e
+	}
+
+	if !match {
+		return uf.Forbidden("Incorrect channel password.")
+	}
+
+	return nil
+}
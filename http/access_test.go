@@ -0,0 +1,93 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/hash"
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Are the engineer routes password protected, with subscriber sessions only
+// allowed to read?
+func TestReaderWriter(t *testing.T) {
+	hub := newTestHub(t)
+	hub.sessions[hash.Token("ses_sub")] = are_hub.Session{ChannelID: testChannelID, Role: are_hub.ROLE_SUBSCRIBER}
+	hub.sessions[hash.Token("ses_pub")] = are_hub.Session{ChannelID: testChannelID, Role: are_hub.ROLE_PUBLISHER}
+
+	tests := []struct {
+		visibility, password string
+		reader, writer       int
+	}{
+		{are_hub.VISIBILITY_PRIVATE, "", http.StatusUnauthorized, http.StatusUnauthorized},
+		{are_hub.VISIBILITY_PRIVATE, testChannelPassword, 0, 0},
+		{are_hub.VISIBILITY_UNLISTED, "", http.StatusUnauthorized, http.StatusUnauthorized},
+		{are_hub.VISIBILITY_PUBLIC, "wrong", http.StatusForbidden, http.StatusForbidden},
+		{are_hub.VISIBILITY_PUBLIC, testChannelPassword, 0, 0},
+		{are_hub.VISIBILITY_PUBLIC, "ses_sub", 0, http.StatusForbidden},
+		{are_hub.VISIBILITY_PUBLIC, "ses_pub", 0, 0},
+	}
+
+	for _, test := range tests {
+		hub.visibility = test.visibility
+
+		guards := []struct {
+			name string
+			mw   uf.Middleware
+			code int
+		}{
+			{"reader", hub.ts.Reader, test.reader},
+			{"writer", hub.ts.Writer, test.writer},
+		}
+
+		for _, guard := range guards {
+			req, e := http.NewRequest(http.MethodGet, "/channel/"+testChannelID+"/rules", nil)
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			req.Header.Set("Channel-Password", test.password)
+			uf.EmbedParams(req, httprouter.Param{Key: "id", Value: testChannelID})
+			e = guard.mw(req)
+
+			if he, ok := e.(uf.HttpError); guard.code == 0 && e != nil || guard.code != 0 && (!ok || he.Code != guard.code) {
+				t.Errorf("%s %s %q: Expected: %d. Actual: %v.", guard.name, test.visibility, test.password, guard.code, e)
+			}
+		}
+	}
+}
+
+// Are changes to webhooks only allowed with the channel password?
+func TestOwner(t *testing.T) {
+	hub := newTestHub(t)
+	hub.sessions[hash.Token("ses_pub")] = are_hub.Session{ChannelID: testChannelID, Role: are_hub.ROLE_PUBLISHER}
+
+	tests := []struct {
+		password string
+		code     int
+	}{
+		{"", http.StatusUnauthorized},
+		{"wrong", http.StatusForbidden},
+		{"ses_pub", http.StatusForbidden},
+		{testChannelPassword, 0},
+	}
+
+	for _, test := range tests {
+		req, e := http.NewRequest(http.MethodPost, "/channel/"+testChannelID+"/webhooks", nil)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		req.Header.Set("Channel-Password", test.password)
+		uf.EmbedParams(req, httprouter.Param{Key: "id", Value: testChannelID})
+		e = hub.ts.Owner(req)
+
+		if he, ok := e.(uf.HttpError); test.code == 0 && e != nil || test.code != 0 && (!ok || he.Code != test.code) {
+			t.Errorf("%q: Expected: %d. Actual: %v.", test.password, test.code, e)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package http
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+// Controller inspecting and managing the live channels of a TelemetryServer.
+// Every action taken is recorded in the audit log.
+type Admin struct {
+	ts     *TelemetryServer
+	audits are_hub.AuditRepo
+}
+
+// Create a new admin controller.
+func NewAdmin(ts *TelemetryServer, audits are_hub.AuditRepo) Admin {
+	return Admin{ts, audits}
+}
+
+// A live channel as reported by the admin API.
+type channelStatus struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// Where the channel is in its lifecycle: opening, live, closing, or closed.
+	State string `json:"state"`
+
+	// Sequence number of the last frame broadcast.
+	Seq uint64 `json:"seq"`
+
+	// Subscribers dropped for being too slow.
+	Dropped uint64 `json:"dropped"`
+
+	// Subscriber capacity and buffering with the server's defaults applied.
+	Limits are_hub.Limits `json:"limits"`
+
+	// Number of spectators connected without the password.
+	Spectators int `json:"spectators"`
+
+	Publishers  []publisherStatus  `json:"publishers"`
+	Subscribers []subscriberStatus `json:"subscribers"`
+}
+
+// The publisher slot of a car (or the only slot of channels without cars).
+type publisherStatus struct {
+	Car     string `json:"car"`
+	Driver  string `json:"driver"`
+	Pending string `json:"pending"`
+	Offline bool   `json:"offline"`
+
+	// Frames broadcast in the slot.
+	Frames uint64 `json:"frames"`
+
+	// When the last frame was published. Zero if none have been.
+	LastFrame time.Time `json:"lastFrame"`
+}
+
+// A subscriber connected to a live channel.
+type subscriberStatus struct {
+	ID          string    `json:"id"`
+	Car         string    `json:"car"`
+	RemoteAddr  string    `json:"remoteAddr"`
+	ConnectedAt time.Time `json:"connectedAt"`
+
+	// Messages queued for the subscriber and the size of its buffer.
+	Buffered   int `json:"buffered"`
+	BufferSize int `json:"bufferSize"`
+
+	// Messages delivered to the subscriber and discarded by its slow
+	// subscriber policy.
+	Sent   uint64 `json:"sent"`
+	Missed uint64 `json:"missed"`
+
+	// Slow subscriber policy chosen by the subscriber. Empty if it uses the
+	// channel's.
+	Policy string `json:"policy"`
+}
+
+// List the live channels by ID.
+func (a Admin) Index(w http.ResponseWriter, r *http.Request) error {
+	channels := a.ts.channels.all()
+	statuses := make([]channelStatus, 0, len(channels))
+
+	for _, tc := range channels {
+		statuses = append(statuses, tc.status())
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+
+	return uf.SendJSON(w, statuses)
+}
+
+// Get a live channel by its ID.
+func (a Admin) Show(w http.ResponseWriter, r *http.Request) error {
+	tc, e := a.find(r)
+
+	if e != nil {
+		return e
+	}
+
+	return uf.SendJSON(w, tc.status())
+}
+
+// Close a live channel, disconnecting every client and discarding its state.
+// The channel goes live again with the next client to connect.
+func (a Admin) Close(w http.ResponseWriter, r *http.Request) error {
+	id := uf.GetParam(r, "id")
+
+	if !a.ts.closeChannel(id, "Channel closed by an administrator") {
+		return uf.NotFound("Channel " + id + " is not live.")
+	}
+
+	return a.record(w, r, are_hub.ADMIN_CLOSE_CHANNEL, id, "")
+}
+
+// Disconnect a subscriber from a live channel by the subscriber's ID.
+func (a Admin) Kick(w http.ResponseWriter, r *http.Request) error {
+	tc, e := a.find(r)
+
+	if e != nil {
+		return e
+	}
+
+	sub := uf.GetParam(r, "sub")
+
+	if !tc.kick(sub) {
+		return uf.NotFound("No subscriber matching: " + sub)
+	}
+
+	return a.record(w, r, are_hub.ADMIN_KICK_SUBSCRIBER, tc.ID, sub)
+}
+
+// Vacate the publisher slot of a live channel so that any driver may claim it.
+// Team channels require the car in the "car" query parameter.
+func (a Admin) Reset(w http.ResponseWriter, r *http.Request) error {
+	tc, e := a.find(r)
+
+	if e != nil {
+		return e
+	}
+
+	car := r.URL.Query().Get("car")
+	e = tc.reset(r.Context(), car)
+
+	if e != nil {
+		return e
+	}
+
+	return a.record(w, r, are_hub.ADMIN_RESET_PUBLISHER, tc.ID, car)
+}
+
+// Get the live channel matching the "id" URL parameter.
+func (a Admin) find(r *http.Request) (*telemetryChannel, error) {
+	id := uf.GetParam(r, "id")
+
+	tc, ok := a.ts.channels.get(id)
+
+	if !ok {
+		return nil, uf.NotFound("Channel " + id + " is not live.")
+	}
+
+	return tc, nil
+}
+
+// Record action on the channel matching id in the audit log and send the
+// event. target is the subscriber kicked or car reset.
+func (a Admin) record(w http.ResponseWriter, r *http.Request, action, id, target string) error {
+	event := are_hub.NewAuditEvent(are_hub.ACTOR_ADMIN, action, id)
+	event.Detail = target
+	audit(a.audits, w, r, event)
+
+	return uf.SendJSON(w, event)
+}
+
+// Remove the channel matching id from the live channels and close it,
+// disconnecting its subscribers with reason. Its state kept by the backplane is
+// discarded. Returns false if the channel is not live.
+func (ts *TelemetryServer) closeChannel(id, reason string) bool {
+	tc, ok := ts.channels.remove(id)
+
+	if ok {
+		tc.close(reason)
+
+		e := tc.bp.Forget(context.Background(), id)
+
+		if e != nil {
+			log.Printf("Failed to discard the backplane state of %s: %v\n", id, e)
+		}
+	}
+
+	return ok
+}
+
+// Report the channel's publishers and subscribers.
+func (c *telemetryChannel) status() channelStatus {
+	cs := channelStatus{ID: c.ID, Name: c.name(), State: stateNames[c.lifecycle()]}
+	cars := c.Cars
+
+	if len(cars) == 0 {
+		cars = []string{""}
+	}
+
+	c.pubMtx.Lock()
+
+	for _, car := range cars {
+		s := c.streams[car]
+		cs.Publishers = append(cs.Publishers, publisherStatus{
+			Car:       car,
+			Driver:    s.driver,
+			Pending:   s.pending,
+			Offline:   s.offline,
+			LastFrame: s.last,
+		})
+	}
+
+	c.pubMtx.Unlock()
+	c.subMtx.Lock()
+
+	cs.Seq = c.seq
+	cs.Dropped = c.dropped
+	cs.Limits = c.limits
+	cs.Spectators = len(c.specs)
+
+	for i, car := range cars {
+		cs.Publishers[i].Frames = c.streams[car].frames
+	}
+
+	cs.Subscribers = make([]subscriberStatus, 0, len(c.subs))
+
+	for _, sub := range c.subs {
+		cs.Subscribers = append(cs.Subscribers, subscriberStatus{
+			ID:          sub.id,
+			Car:         sub.car,
+			RemoteAddr:  sub.remote,
+			ConnectedAt: sub.connected,
+			Buffered:    len(sub.buffer),
+			BufferSize:  cap(sub.buffer),
+			Sent:        sub.sent.Load(),
+			Missed:      sub.lost,
+			Policy:      sub.policy,
+		})
+	}
+
+	c.subMtx.Unlock()
+
+	// oldest first
+	sort.Slice(cs.Subscribers, func(i, j int) bool {
+		return cs.Subscribers[i].ConnectedAt.Before(cs.Subscribers[j].ConnectedAt)
+	})
+
+	return cs
+}
+
+// Disconnect the subscriber matching id. Returns false if there is none.
+func (c *telemetryChannel) kick(id string) bool {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	sub, ok := c.subs[id]
+
+	if !ok {
+		return false
+	}
+
+	c.remove(id)
+	go sub.drop(WS_ERROR_DISCONNECTED, "Disconnected by an administrator")
+
+	return true
+}
+
+// Vacate the publisher slot of car, cancelling any pending handover, on every
+// node serving the channel.
+func (c *telemetryChannel) reset(ctx context.Context, car string) error {
+	s, e := c.stream(car)
+
+	if e != nil {
+		return e
+	}
+
+	e = c.bp.Release(ctx, c.ID, car)
+
+	if e != nil {
+		return e
+	}
+
+	c.pubMtx.Lock()
+	defer c.pubMtx.Unlock()
+
+	s.driver = ""
+	s.pending = ""
+	s.offline = false
+
+	if s.idle != nil {
+		s.idle.Stop()
+		s.idle = nil
+	}
+
+	return nil
+}
+
+// Disconnect every subscriber and spectator with reason and stop the publishers' idle
+// timers. New publishers and subscribers are rejected. Returns false if the
+// channel is not live.
+func (c *telemetryChannel) close(reason string) bool {
+	if !c.transition(stateLive, stateClosing) {
+		return false
+	}
+
+	c.subMtx.Lock()
+
+	for id, sub := range c.subs {
+		c.remove(id)
+		go sub.drop(WS_ERROR_DISCONNECTED, reason)
+	}
+
+	for id, spec := range c.specs {
+		delete(c.specs, id)
+		go spec.drop(WS_ERROR_DISCONNECTED, reason)
+	}
+
+	c.ignore()
+	c.subMtx.Unlock()
+	c.pubMtx.Lock()
+
+	for _, s := range c.streams {
+		if s.idle != nil {
+			s.idle.Stop()
+			s.idle = nil
+		}
+	}
+
+	c.pubMtx.Unlock()
+
+	return c.transition(stateClosing, stateClosed)
+}
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+	"nhooyr.io/websocket"
+)
+
+// Create an admin controller recording actions in events.
+func newTestAdmin(ts *TelemetryServer, events *[]are_hub.AuditEvent) Admin {
+	return NewAdmin(ts, recordAudits(events))
+}
+
+// Call h with params and decode the response into ptr.
+func callAdmin(t *testing.T, method string, h uf.Handler, ptr interface{}, params ...httprouter.Param) {
+	r, e := http.NewRequest(method, "/admin", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	uf.EmbedParams(r, params...)
+	w := httptest.NewRecorder()
+	e = h(w, r)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	checkCT(res, t)
+
+	e = json.NewDecoder(res.Body).Decode(ptr)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+}
+
+// Does it list the live channels with their publishers and subscribers?
+func TestAdminIndex(t *testing.T) {
+	hub := newTestHub(t)
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+
+	hub.publishAs(t, testChannelID, testChannelPassword, Slot{Driver: "Vanthoor"}, `{"speedKmh":212.5}`)
+	readResponse(t, conn)
+
+	var actions []are_hub.AuditEvent
+	var statuses []channelStatus
+
+	callAdmin(t, http.MethodGet, newTestAdmin(hub.ts, &actions).Index, &statuses)
+
+	if len(statuses) != 1 {
+		t.Fatalf("Expected: 1 live channel. Actual: %d.", len(statuses))
+	}
+
+	cs := statuses[0]
+
+	if cs.ID != testChannelID || cs.Seq != 1 {
+		t.Errorf("Expected: channel %s at seq 1. Actual: %+v.", testChannelID, cs)
+	}
+
+	if len(cs.Publishers) != 1 || cs.Publishers[0].Driver != "Vanthoor" || cs.Publishers[0].Frames != 1 {
+		t.Errorf("Expected: 1 frame published by Vanthoor. Actual: %+v.", cs.Publishers)
+	}
+
+	if cs.Publishers[0].LastFrame.IsZero() {
+		t.Error("Expected the time of the last frame")
+	}
+
+	if len(cs.Subscribers) != 1 {
+		t.Fatalf("Expected: 1 subscriber. Actual: %+v.", cs.Subscribers)
+	}
+
+	sub := cs.Subscribers[0]
+
+	if len(sub.ID) != ID_LEN*2 || len(sub.RemoteAddr) == 0 || sub.ConnectedAt.IsZero() {
+		t.Errorf("Expected: subscriber ID, address, and connect time. Actual: %+v.", sub)
+	}
+
+	if sub.BufferSize != BUF_LEN || sub.Sent != 1 {
+		t.Errorf("Expected: 1 message sent with a buffer of %d. Actual: %+v.", BUF_LEN, sub)
+	}
+}
+
+// Is the subscriber disconnected with WS_ERROR_DISCONNECTED and the action recorded?
+func TestAdminKick(t *testing.T) {
+	hub := newTestHub(t)
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+
+	var actions []are_hub.AuditEvent
+	controller := newTestAdmin(hub.ts, &actions)
+	cs := hub.channel(t).status()
+	id := httprouter.Param{Key: "id", Value: testChannelID}
+
+	test404(t, http.MethodDelete, "/admin", nil, controller.Kick, id, httprouter.Param{Key: "sub", Value: "nope"})
+
+	action := are_hub.AuditEvent{}
+	sub := cs.Subscribers[0].ID
+
+	callAdmin(t, http.MethodDelete, controller.Kick, &action, id, httprouter.Param{Key: "sub", Value: sub})
+
+	if action.Action != are_hub.ADMIN_KICK_SUBSCRIBER || action.Detail != sub {
+		t.Errorf("Expected: kick of %s. Actual: %+v.", sub, action)
+	}
+
+	if len(actions) != 1 {
+		t.Fatalf("Expected: 1 action recorded. Actual: %d.", len(actions))
+	}
+
+	_, _, e := conn.Read(context.Background())
+
+	if websocket.CloseStatus(e) != WS_ERROR_DISCONNECTED {
+		t.Fatalf("Expected: %d. Actual: %v.", WS_ERROR_DISCONNECTED, e)
+	}
+}
+
+// Does it vacate the publisher slot so that another driver may publish?
+func TestAdminReset(t *testing.T) {
+	hub := newTestHub(t)
+	hub.cars = []string{"31"}
+
+	res := hub.publishAs(t, testChannelID, testChannelPassword, Slot{"31", "Vanthoor"}, `{}`)
+	res.Body.Close()
+
+	res = hub.publishAs(t, testChannelID, testChannelPassword, Slot{"31", "Weerts"}, `{}`)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusConflict, res.StatusCode)
+	}
+
+	var actions []are_hub.AuditEvent
+	controller := newTestAdmin(hub.ts, &actions)
+	action := are_hub.AuditEvent{}
+
+	r, e := http.NewRequest(http.MethodDelete, "/admin?car=31", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	uf.EmbedParams(r, httprouter.Param{Key: "id", Value: testChannelID})
+	w := httptest.NewRecorder()
+	e = controller.Reset(w, r)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	e = json.NewDecoder(w.Result().Body).Decode(&action)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if action.Action != are_hub.ADMIN_RESET_PUBLISHER || action.Detail != "31" {
+		t.Errorf("Expected: reset of car 31. Actual: %+v.", action)
+	}
+
+	res = hub.publishAs(t, testChannelID, testChannelPassword, Slot{"31", "Weerts"}, `{}`)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+}
+
+// Are subscribers disconnected and the channel removed from the live channels?
+func TestAdminClose(t *testing.T) {
+	hub := newTestHub(t)
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+	tc := hub.channel(t)
+
+	var actions []are_hub.AuditEvent
+	controller := newTestAdmin(hub.ts, &actions)
+	action := are_hub.AuditEvent{}
+	id := httprouter.Param{Key: "id", Value: testChannelID}
+
+	callAdmin(t, http.MethodDelete, controller.Close, &action, id)
+
+	if action.Action != are_hub.ADMIN_CLOSE_CHANNEL || action.TargetID != testChannelID {
+		t.Errorf("Expected: close of %s. Actual: %+v.", testChannelID, action)
+	}
+
+	_, _, e := conn.Read(context.Background())
+
+	if websocket.CloseStatus(e) != WS_ERROR_DISCONNECTED {
+		t.Fatalf("Expected: %d. Actual: %v.", WS_ERROR_DISCONNECTED, e)
+	}
+
+	if s := tc.lifecycle(); s != stateClosed {
+		t.Errorf("Expected: %s. Actual: %s.", stateNames[stateClosed], stateNames[s])
+	}
+
+	if _, _, e = tc.claim(context.Background(), Slot{}); e != errClosed {
+		t.Errorf("Expected: %v. Actual: %v.", errClosed, e)
+	}
+
+	test404(t, http.MethodGet, "/admin", nil, controller.Show, id)
+	test404(t, http.MethodDelete, "/admin", nil, controller.Close, id)
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+// Controller that retrieves the alerts fired on channels.
+type Alert struct {
+	alerts are_hub.AlertRepo
+}
+
+// Create a new alert controller.
+func NewAlert(alerts are_hub.AlertRepo) Alert {
+	return Alert{alerts}
+}
+
+// Get the alert history of a specific channel by the channel's ID.
+func (a Alert) Index(w http.ResponseWriter, r *http.Request) error {
+	h := w.Header()
+
+	h.Set("Access-Control-Allow-Methods", h.Get("Allow"))
+	h.Set("Access-Control-Allow-Origin", "*")
+
+	alerts, e := a.alerts.FindChannelID(r.Context(), uf.GetParam(r, "id"))
+
+	if e != nil {
+		return e
+	}
+
+	if alerts == nil {
+		// send an empty array rather than null
+		alerts = []are_hub.Alert{}
+	}
+
+	return uf.SendJSON(w, alerts)
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+// CRUD controller that manipulates the alert rules of channels. Live channels
+// are updated with the changed rules.
+type AlertRule struct {
+	rules are_hub.AlertRuleRepo
+	ts    *TelemetryServer
+}
+
+// Create a new alert rule controller.
+func NewAlertRule(rules are_hub.AlertRuleRepo, ts *TelemetryServer) AlertRule {
+	return AlertRule{rules, ts}
+}
+
+// Get all alert rules of a specific channel by the channel's ID.
+func (a AlertRule) Index(w http.ResponseWriter, r *http.Request) error {
+	h := w.Header()
+
+	h.Set("Access-Control-Allow-Methods", h.Get("Allow"))
+	h.Set("Access-Control-Allow-Origin", "*")
+
+	rules, e := a.rules.FindChannelID(r.Context(), uf.GetParam(r, "id"))
+
+	if e != nil {
+		return e
+	}
+
+	if rules == nil {
+		// send an empty array rather than null
+		rules = []are_hub.AlertRule{}
+	}
+
+	return uf.SendJSON(w, rules)
+}
+
+// Create a new alert rule on a specific channel.
+func (a AlertRule) Store(w http.ResponseWriter, r *http.Request) error {
+	// get the rule from the request's context
+	rule, e := are_hub.AlertRuleFromCtx(r.Context())
+
+	if e != nil {
+		return e
+	}
+
+	rule.ChannelID = uf.GetParam(r, "id")
+	e = a.rules.Insert(r.Context(), rule)
+
+	if e != nil {
+		return e
+	}
+
+	e = a.ts.changeRules(r.Context(), rule.ChannelID)
+
+	if e != nil {
+		return e
+	}
+
+	// return the created rule with the ID and timestamps
+	return uf.SendJSON(w, rule)
+}
+
+// Find a specific alert rule by its ID.
+func (a AlertRule) Show(w http.ResponseWriter, r *http.Request) error {
+	rule, e := a.find(r)
+
+	if e != nil {
+		return e
+	}
+
+	return uf.SendJSON(w, rule)
+}
+
+// Update a specific alert rule by its ID.
+func (a AlertRule) Update(w http.ResponseWriter, r *http.Request) error {
+	// get the rule embedded in the request's context
+	rule, e := are_hub.AlertRuleFromCtx(r.Context())
+
+	if e != nil {
+		return e
+	}
+
+	// the rule must belong to the channel in the URL
+	stored, e := a.find(r)
+
+	if e != nil {
+		return e
+	}
+
+	// the body replaces the rule but not when it was created
+	rule.ChannelID = uf.GetParam(r, "id")
+	rule.CreatedAt = stored.CreatedAt
+	e = a.rules.UpdateID(r.Context(), uf.GetParam(r, "rule"), rule)
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound(e.Error())
+		}
+
+		return e
+	}
+
+	e = a.ts.changeRules(r.Context(), rule.ChannelID)
+
+	if e != nil {
+		return e
+	}
+
+	// return the updated rule
+	return uf.SendJSON(w, rule)
+}
+
+// Delete a specific alert rule by its ID.
+func (a AlertRule) Delete(w http.ResponseWriter, r *http.Request) error {
+	// the rule must belong to the channel in the URL
+	_, e := a.find(r)
+
+	if e != nil {
+		return e
+	}
+
+	rule, e := a.rules.DeleteID(r.Context(), uf.GetParam(r, "rule"))
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound(e.Error())
+		}
+
+		return e
+	}
+
+	e = a.ts.changeRules(r.Context(), rule.ChannelID)
+
+	if e != nil {
+		return e
+	}
+
+	// return the deleted rule
+	return uf.SendJSON(w, rule)
+}
+
+// Find the alert rule in the URL, ensuring it belongs to the channel in the URL.
+func (a AlertRule) find(r *http.Request) (*are_hub.AlertRule, error) {
+	rule, e := a.rules.FindID(r.Context(), uf.GetParam(r, "rule"))
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return nil, uf.NotFound(e.Error())
+		}
+
+		return nil, e
+	}
+
+	if rule.ChannelID != uf.GetParam(r, "id") {
+		return nil, uf.NotFound("No alert rule matching: " + uf.GetParam(r, "rule"))
+	}
+
+	return rule, nil
+}
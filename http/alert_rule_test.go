@@ -0,0 +1,192 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/mock"
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Test data.
+var alertRules []are_hub.AlertRule = []are_hub.AlertRule{
+	{ChannelID: "1", Name: "FL pressure", Field: "physics.wheelsPressure.0", Operator: ">", Threshold: 28},
+	{ChannelID: "2", Name: "Low fuel", Field: "strategy.lapsRemaining", Operator: "<", Threshold: 2},
+}
+
+// Create a channel repository touching any channel.
+func touchChannels() *mock.ChannelRepo {
+	return &mock.ChannelRepo{TouchIDFunc: func(context.Context, string) error { return nil }}
+}
+
+// Does it call repo.Insert with the channel ID from the URL?
+// Are the rules of the live channel reloaded and the channel touched?
+func TestAlertRuleStore(t *testing.T) {
+	var stored []are_hub.AlertRule
+
+	repo := &mock.AlertRuleRepo{
+		InsertFunc: func(_ context.Context, a are_hub.Archetype) error {
+			rule := a.(*are_hub.AlertRule)
+			rule.ID = "1"
+			stored = append(stored, *rule)
+
+			return nil
+		},
+		FindChannelIDFunc: func(context.Context, string) ([]are_hub.AlertRule, error) {
+			return stored, nil
+		},
+	}
+
+	ts := NewTelemetryServer(nil, TelemetryRepos{Channels: touchChannels(), Rules: repo}, discardEvents())
+	tc := newTelemetryChannel(&are_hub.Channel{Common: are_hub.Common{ID: "1"}}, nil)
+	ts.addChannel(tc)
+
+	controller := NewAlertRule(repo, ts)
+	req, e := http.NewRequest(http.MethodPost, "/channel/1/rules", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	rule := are_hub.NewAlertRule("FL pressure", "physics.wheelsPressure.0", ">", 28)
+	*req = *req.WithContext(rule.ToCtx(req.Context()))
+	uf.EmbedParams(req, httprouter.Param{Key: "id", Value: "1"})
+
+	w := httptest.NewRecorder()
+	e = controller.Store(w, req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !repo.InsertCalled {
+		t.Error("Did not call repo.Insert")
+	}
+
+	res := w.Result()
+
+	checkCT(res, t)
+
+	defer res.Body.Close()
+	body, e := io.ReadAll(res.Body)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	received := are_hub.AlertRule{}
+	e = json.Unmarshal(body, &received)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if received.ID != "1" || received.ChannelID != "1" {
+		t.Fatalf("Expected: rule 1 on channel 1. Actual: %+v.", received)
+	}
+
+	if n := tc.streams[""].rules.Len(); n != 1 {
+		t.Fatalf("Expected: 1 rule on the live channel. Actual: %d.", n)
+	}
+
+	if !ts.repo.(*mock.ChannelRepo).TouchIDCalled {
+		t.Error("Expected the channel to be touched for the other servers")
+	}
+}
+
+// Does it call repo.DeleteID?
+// Does it return a 404 Not Found error for a rule on another channel?
+func TestAlertRuleDelete(t *testing.T) {
+	find := func(_ context.Context, id string) (*are_hub.AlertRule, error) {
+		switch id {
+		case "1":
+			return &alertRules[0], nil
+		case "2":
+			return &alertRules[1], nil
+		}
+
+		return nil, are_hub.NewNoObjectsFound("alertRules", "id == "+id)
+	}
+
+	repo := &mock.AlertRuleRepo{FindIDFunc: find, DeleteIDFunc: find}
+	ts := NewTelemetryServer(nil, TelemetryRepos{Channels: touchChannels(), Rules: repo}, discardEvents())
+	controller := NewAlertRule(repo, ts)
+
+	params := []httprouter.Param{{Key: "id", Value: "1"}, {Key: "rule", Value: "2"}}
+	test404(t, http.MethodDelete, "/channel/1/rules/2", nil, controller.Delete, params...)
+
+	if repo.DeleteIDCalled {
+		t.Fatal("Called repo.DeleteID for a rule on another channel")
+	}
+
+	params[1].Value = "1"
+	req, e := http.NewRequest(http.MethodDelete, "/channel/1/rules/1", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	uf.EmbedParams(req, params...)
+
+	w := httptest.NewRecorder()
+	e = controller.Delete(w, req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !repo.DeleteIDCalled {
+		t.Error("Did not call repo.DeleteID")
+	}
+
+	checkCT(w.Result(), t)
+}
+
+// Is the creation time of the stored rule kept when it is replaced?
+func TestAlertRuleUpdateCreatedAt(t *testing.T) {
+	created := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	var updated time.Time
+
+	repo := &mock.AlertRuleRepo{
+		FindIDFunc: func(context.Context, string) (*are_hub.AlertRule, error) {
+			rule := alertRules[0]
+			rule.CreatedAt = created
+
+			return &rule, nil
+		},
+		UpdateIDFunc: func(_ context.Context, _ string, a are_hub.Archetype) error {
+			updated = a.(*are_hub.AlertRule).CreatedAt
+
+			return nil
+		},
+	}
+
+	ts := NewTelemetryServer(nil, TelemetryRepos{Channels: touchChannels(), Rules: repo}, discardEvents())
+	controller := NewAlertRule(repo, ts)
+	rule := are_hub.NewAlertRule("FL pressure", "physics.wheelsPressure.0", ">", 29)
+	req := httptest.NewRequest(http.MethodPut, "/channel/1/rules/1", nil)
+	req = req.WithContext(rule.ToCtx(req.Context()))
+	uf.EmbedParams(req, httprouter.Param{Key: "id", Value: "1"}, httprouter.Param{Key: "rule", Value: "1"})
+	w := httptest.NewRecorder()
+
+	if e := controller.Update(w, req); e != nil {
+		t.Fatal(e)
+	}
+
+	received := are_hub.AlertRule{}
+
+	if e := json.NewDecoder(w.Result().Body).Decode(&received); e != nil {
+		t.Fatal(e)
+	}
+
+	if !updated.Equal(created) || !received.CreatedAt.Equal(created) {
+		t.Errorf("Expected: created at %v. Actual: %v, sent %v.", created, updated, received.CreatedAt)
+	}
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+const (
+	// Number of audit events returned unless requested otherwise.
+	AUDIT_LIMIT = 100
+
+	// Maximum number of audit events returned.
+	MAX_AUDIT_LIMIT = 1000
+)
+
+// Controller querying the audit log of channel changes and admin actions.
+type Audit struct {
+	audits are_hub.AuditRepo
+}
+
+// Create a new audit controller.
+func NewAudit(audits are_hub.AuditRepo) Audit {
+	return Audit{audits}
+}
+
+// Get the audit events matching the query string, newest first. Eg.
+// "?targetId=abc&action=channel.update&after=2021-05-01T00:00:00Z&limit=20".
+func (a Audit) Index(w http.ResponseWriter, r *http.Request) error {
+	values := r.URL.Query()
+	q := are_hub.AuditQuery{
+		Actor:    values.Get("actor"),
+		Action:   values.Get("action"),
+		TargetID: values.Get("targetId"),
+		Limit:    AUDIT_LIMIT,
+	}
+
+	for key, ptr := range map[string]*time.Time{"after": &q.After, "before": &q.Before} {
+		v := values.Get(key)
+
+		if len(v) == 0 {
+			continue
+		}
+
+		t, e := time.Parse(time.RFC3339, v)
+
+		if e != nil {
+			return uf.BadRequest(key + " must be an RFC 3339 timestamp")
+		}
+
+		*ptr = t.UTC()
+	}
+
+	if v := values.Get("limit"); len(v) > 0 {
+		n, e := strconv.ParseInt(v, 10, 64)
+
+		if e != nil || n < 0 {
+			return uf.BadRequest("limit must be a non-negative integer")
+		}
+
+		q.Limit = n
+	}
+
+	if q.Limit == 0 || q.Limit > MAX_AUDIT_LIMIT {
+		q.Limit = MAX_AUDIT_LIMIT
+	}
+
+	events, e := a.audits.Query(r.Context(), q)
+
+	if e != nil {
+		return e
+	}
+
+	if events == nil {
+		// send an empty array rather than null
+		events = []are_hub.AuditEvent{}
+	}
+
+	return uf.SendJSON(w, events)
+}
+
+// Record event in audits as having been made by r. The request ID is taken
+// from X-Request-ID (generated if absent) and echoed in w so must be called
+// before the response is written. The change has already been made so failing
+// to record it is logged rather than failing the request.
+func audit(audits are_hub.AuditRepo, w http.ResponseWriter, r *http.Request, event *are_hub.AuditEvent) {
+	event.RequestID = r.Header.Get("X-Request-ID")
+
+	if len(event.RequestID) == 0 {
+		event.RequestID = newID()
+	}
+
+	w.Header().Set("X-Request-ID", event.RequestID)
+	event.ClientIP = clientIP(r)
+
+	if e := audits.Insert(r.Context(), event); e != nil {
+		log.Printf("audit %s of %s: %v", event.Action, event.TargetID, e)
+	}
+}
+
+// Get the IP address r was sent from.
+func clientIP(r *http.Request) string {
+	host, _, e := net.SplitHostPort(r.RemoteAddr)
+
+	if e != nil {
+		// no port
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/mock"
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Create an audit repository recording events in events.
+func recordAudits(events *[]are_hub.AuditEvent) *mock.AuditRepo {
+	return &mock.AuditRepo{InsertFunc: func(_ context.Context, a are_hub.Archetype) error {
+		*events = append(*events, *a.(*are_hub.AuditEvent))
+
+		return nil
+	}}
+}
+
+// Are the filters parsed out of the query string?
+// Are invalid filters rejected?
+// Is an empty array sent rather than null?
+func TestAuditIndex(t *testing.T) {
+	var query are_hub.AuditQuery
+
+	repo := &mock.AuditRepo{QueryFunc: func(_ context.Context, q are_hub.AuditQuery) ([]are_hub.AuditEvent, error) {
+		query = q
+
+		return nil, nil
+	}}
+
+	controller := NewAudit(repo)
+
+	r := httptest.NewRequest(http.MethodGet, "/audit?actor=admin&action=channel.update&targetId=1&after=2021-05-01T00:00:00Z&limit=5000", nil)
+	w := httptest.NewRecorder()
+
+	if e := controller.Index(w, r); e != nil {
+		t.Fatal(e)
+	}
+
+	if body := w.Body.String(); body != "[]" && body != "[]\n" {
+		t.Errorf("Expected: []. Actual: %s.", body)
+	}
+
+	after := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	expected := are_hub.AuditQuery{Actor: "admin", Action: "channel.update", TargetID: "1", After: after, Limit: MAX_AUDIT_LIMIT}
+
+	if query != expected {
+		t.Errorf("Expected: %+v. Actual: %+v.", expected, query)
+	}
+
+	for _, q := range []string{"?before=yesterday", "?limit=-1", "?limit=ten"} {
+		e := controller.Index(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/audit"+q, nil))
+
+		if he, ok := e.(uf.HttpError); !ok || he.Code != http.StatusBadRequest {
+			t.Errorf("%s: Expected: %d. Actual: %v.", q, http.StatusBadRequest, e)
+		}
+	}
+}
+
+// Are channel updates recorded with the changed fields and a redacted password?
+// Are the request ID and client IP recorded and the request ID echoed?
+func TestChannelAudit(t *testing.T) {
+	var events []are_hub.AuditEvent
+
+	repo := &mock.ChannelRepo{
+		FindIDFunc: findChannelID,
+		UpdateIDFunc: func(_ context.Context, _ string, version int64, v are_hub.Versioned) error {
+			v.SetVersion(version + 1)
+
+			return nil
+		},
+	}
+
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), recordAudits(&events))
+	wrt := are_hub.Channel{Name: "Belgian Audi Club WRT", Password: "lol123"}
+	r := httptest.NewRequest(http.MethodPut, "/channel/1", nil)
+	r = r.WithContext(wrt.ToCtx(r.Context()))
+	r.RemoteAddr = "192.0.2.1:51234"
+	r.Header.Set("If-Match", `"0"`)
+	r.Header.Set("X-Request-ID", "abc")
+	uf.EmbedParams(r, httprouter.Param{Key: "id", Value: "1"})
+	w := httptest.NewRecorder()
+
+	if e := controller.Update(w, r); e != nil {
+		t.Fatal(e)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected: 1 event. Actual: %+v.", events)
+	}
+
+	event := events[0]
+
+	if event.Actor != are_hub.ACTOR_ANONYMOUS || event.Action != are_hub.AUDIT_CHANNEL_UPDATE || event.TargetID != "1" {
+		t.Errorf("Expected: anonymous channel.update of 1. Actual: %+v.", event)
+	}
+
+	if event.RequestID != "abc" || event.ClientIP != "192.0.2.1" {
+		t.Errorf("Expected: abc from 192.0.2.1. Actual: %s from %s.", event.RequestID, event.ClientIP)
+	}
+
+	if id := w.Header().Get("X-Request-ID"); id != "abc" {
+		t.Errorf("Expected: X-Request-ID: abc. Actual: %s.", id)
+	}
+
+	if c := event.Changes["name"]; c.Before != channels[0].Name || c.After != wrt.Name {
+		t.Errorf("Expected: %s -> %s. Actual: %+v.", channels[0].Name, wrt.Name, c)
+	}
+
+	if c := event.Changes["password"]; c.Before != are_hub.REDACTED || c.After != are_hub.REDACTED {
+		t.Errorf("Expected: a redacted password change. Actual: %+v.", c)
+	}
+
+	if len(event.Changes) != 2 {
+		t.Errorf("Expected: 2 changes. Actual: %+v.", event.Changes)
+	}
+}
+
+// Are admin actions recorded in the audit log?
+func TestAdminAudit(t *testing.T) {
+	var events []are_hub.AuditEvent
+
+	hub := newTestHub(t)
+	hub.subscribe(t, testChannelID, testChannelPassword)
+
+	controller := NewAdmin(hub.ts, recordAudits(&events))
+
+	var action are_hub.AuditEvent
+	callAdmin(t, http.MethodDelete, controller.Close, &action, httprouter.Param{Key: "id", Value: testChannelID})
+
+	if len(events) != 1 {
+		t.Fatalf("Expected: 1 event. Actual: %+v.", events)
+	}
+
+	if e := events[0]; e.Actor != are_hub.ACTOR_ADMIN || e.Action != are_hub.ADMIN_CLOSE_CHANNEL || e.TargetID != testChannelID || len(e.RequestID) == 0 {
+		t.Errorf("Expected: admin %s of %s. Actual: %+v.", are_hub.ADMIN_CLOSE_CHANNEL, testChannelID, e)
+	}
+}
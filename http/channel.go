@@ -1,37 +1,174 @@
 package http
 
 import (
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/blacksfk/are_hub"
 	"github.com/blacksfk/are_hub/hash"
 	uf "github.com/blacksfk/microframework"
 )
 
+const (
+	// Number of channels listed per page unless requested otherwise.
+	PAGE_LIMIT = 50
+
+	// Maximum number of channels listed per page.
+	MAX_PAGE_LIMIT = 200
+)
+
 // CRUD controller that manipulates channel data in the provided repository.
+// Creating and deleting channels emits events. Updates are applied to the
+// channel if it is live on ts. Every change is recorded in audits.
 type Channel struct {
 	channels are_hub.ChannelRepo
+	sessions are_hub.SessionRepo
+	events   are_hub.Emitter
+	ts       *TelemetryServer
+	audits   are_hub.AuditRepo
 }
 
 // Create a new channel controller.
-func NewChannel(channels are_hub.ChannelRepo) Channel {
-	return Channel{channels}
+func NewChannel(channels are_hub.ChannelRepo, sessions are_hub.SessionRepo, events are_hub.Emitter, ts *TelemetryServer, audits are_hub.AuditRepo) Channel {
+	return Channel{channels, sessions, events, ts, audits}
 }
 
-// Get all channels.
+// Get a page of channels filtered and sorted by the query string. The number
+// of matching channels is sent in X-Total-Count and the next page, if any, is
+// linked in Link.
 func (c Channel) Index(w http.ResponseWriter, r *http.Request) error {
+	return c.index(w, r, false)
+}
+
+// Get the public channels, discoverable by spectators. Accepts the same query
+// parameters as Index.
+func (c Channel) Directory(w http.ResponseWriter, r *http.Request) error {
+	return c.index(w, r, true)
+}
+
+// Get a page of channels matching the query parameters of r, only public
+// channels if public.
+func (c Channel) index(w http.ResponseWriter, r *http.Request, public bool) error {
 	h := w.Header()
 
 	h.Set("Access-Control-Allow-Methods", h.Get("Allow"))
 	h.Set("Access-Control-Allow-Origin", "*")
+	h.Set("Access-Control-Expose-Headers", "X-Total-Count, Link")
 
-	channels, e := c.channels.All(r.Context())
+	values := r.URL.Query()
+	q, e := channelQuery(values)
 
 	if e != nil {
 		return e
 	}
 
-	return uf.SendJSON(w, channels)
+	if public {
+		q.Visibility = are_hub.VISIBILITY_PUBLIC
+	}
+
+	page, e := c.channels.Query(r.Context(), q)
+
+	if e != nil {
+		return e
+	}
+
+	h.Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+
+	if next := q.Offset + int64(len(page.Channels)); len(page.Channels) > 0 && next < page.Total {
+		values.Set("offset", strconv.FormatInt(next, 10))
+		values.Set("limit", strconv.FormatInt(q.Limit, 10))
+
+		u := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+		h.Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.String()))
+	}
+
+	if page.Channels == nil {
+		// send an empty array rather than null
+		page.Channels = []are_hub.Channel{}
+	}
+
+	return uf.SendJSON(w, page.Channels)
+}
+
+// Parse a channel query out of values. Eg.
+// "?name=wrt&track=spa&tag=endurance&updatedAfter=2021-05-01T00:00:00Z&sort=-updatedAt&limit=20".
+// Sort fields prefixed with "-" are in descending order.
+func channelQuery(values url.Values) (are_hub.ChannelQuery, error) {
+	q := are_hub.ChannelQuery{
+		Name:       values.Get("name"),
+		Visibility: values.Get("visibility"),
+		Sim:        values.Get("sim"),
+		CarModel:   values.Get("carModel"),
+		Track:      values.Get("track"),
+		Event:      values.Get("event"),
+		Team:       values.Get("team"),
+		Tags:       values["tag"],
+		Limit:      PAGE_LIMIT,
+	}
+	sort := values.Get("sort")
+
+	if len(q.Visibility) > 0 && !are_hub.IsVisibility(q.Visibility) {
+		return q, uf.BadRequest("Unknown visibility: " + q.Visibility)
+	}
+
+	if strings.HasPrefix(sort, "-") {
+		q.Descending = true
+		sort = sort[1:]
+	}
+
+	if len(sort) > 0 && !are_hub.IsChannelSort(sort) {
+		return q, uf.BadRequest("Unknown sort field: " + sort)
+	}
+
+	q.Sort = sort
+
+	for key, ptr := range map[string]*time.Time{
+		"createdAfter":  &q.CreatedAfter,
+		"createdBefore": &q.CreatedBefore,
+		"updatedAfter":  &q.UpdatedAfter,
+		"updatedBefore": &q.UpdatedBefore,
+	} {
+		v := values.Get(key)
+
+		if len(v) == 0 {
+			continue
+		}
+
+		t, e := time.Parse(time.RFC3339, v)
+
+		if e != nil {
+			return q, uf.BadRequest(key + " must be an RFC 3339 timestamp")
+		}
+
+		*ptr = t.UTC()
+	}
+
+	for key, ptr := range map[string]*int64{"offset": &q.Offset, "limit": &q.Limit} {
+		v := values.Get(key)
+
+		if len(v) == 0 {
+			continue
+		}
+
+		n, e := strconv.ParseInt(v, 10, 64)
+
+		if e != nil || n < 0 {
+			return q, uf.BadRequest(key + " must be a non-negative integer")
+		}
+
+		*ptr = n
+	}
+
+	if q.Limit == 0 || q.Limit > MAX_PAGE_LIMIT {
+		q.Limit = MAX_PAGE_LIMIT
+	}
+
+	return q, nil
 }
 
 // Create a new channel.
@@ -57,14 +194,24 @@ func (c Channel) Store(w http.ResponseWriter, r *http.Request) error {
 	e = c.channels.Insert(r.Context(), channel)
 
 	if e != nil {
+		if are_hub.IsDuplicate(e) {
+			return uf.HttpError{Code: http.StatusConflict, Message: e.Error()}
+		}
+
 		return e
 	}
 
+	c.events.Emit(are_hub.NewEvent(are_hub.EVENT_CHANNEL_CREATED, channel.ID, channel))
+	c.audit(w, r, are_hub.AUDIT_CHANNEL_CREATE, channel.ID, nil, channel, true)
+
 	// return the created channel with the ID and timestamps
+	w.Header().Set("ETag", etag(channel.Version))
+
 	return uf.SendJSON(w, channel)
 }
 
-// Find a specific channel by its ID.
+// Find a specific channel by its ID. The channel's version is sent as its
+// ETag.
 func (c Channel) Show(w http.ResponseWriter, r *http.Request) error {
 	channel, e := c.channels.FindID(r.Context(), uf.GetParam(r, "id"))
 
@@ -76,10 +223,14 @@ func (c Channel) Show(w http.ResponseWriter, r *http.Request) error {
 		return e
 	}
 
+	w.Header().Set("ETag", etag(channel.Version))
+
 	return uf.SendJSON(w, channel)
 }
 
-// Update a specific channel by its ID.
+// Update a specific channel by its ID. If-Match must be the ETag of the
+// version the update is based on so that changes made since aren't
+// overwritten.
 func (c Channel) Update(w http.ResponseWriter, r *http.Request) error {
 	// get the channel embedded in the request's context
 	channel, e := are_hub.ChannelFromCtx(r.Context())
@@ -88,6 +239,19 @@ func (c Channel) Update(w http.ResponseWriter, r *http.Request) error {
 		return e
 	}
 
+	version, e := ifMatch(r)
+
+	if e != nil {
+		return e
+	}
+
+	// the channel as it was for the audit log
+	before, e := c.channels.FindID(r.Context(), uf.GetParam(r, "id"))
+
+	if e != nil {
+		return updateFailed(e)
+	}
+
 	// hash the new password
 	hash, e := hash.Password(channel.PasswordStr())
 
@@ -98,8 +262,84 @@ func (c Channel) Update(w http.ResponseWriter, r *http.Request) error {
 	// replace the channel's plaintext password with the generated hash
 	channel.SetPasswordStr(hash)
 
+	// the whole channel is replaced so keep when it was created
+	channel.CreatedAt = before.CreatedAt
+
 	// update the channel in the repository
-	e = c.channels.UpdateID(r.Context(), uf.GetParam(r, "id"), channel)
+	e = c.channels.UpdateID(r.Context(), uf.GetParam(r, "id"), version, channel)
+
+	if e != nil {
+		return updateFailed(e)
+	}
+
+	// sessions were granted under the replaced password
+	e = c.sessions.DeleteChannelID(r.Context(), uf.GetParam(r, "id"))
+
+	if e != nil {
+		return e
+	}
+
+	// live channels pick up the changes without going offline where possible
+	channel.ID = uf.GetParam(r, "id")
+	c.ts.refresh(channel)
+	// the password is replaced along with the rest of the channel so it is
+	// recorded as changed rather than verified against the old hash
+	c.audit(w, r, are_hub.AUDIT_CHANNEL_UPDATE, channel.ID, before, channel, true)
+
+	// return the updated channel
+	w.Header().Set("ETag", etag(channel.Version))
+
+	return uf.SendJSON(w, channel)
+}
+
+// Update only the fields of a specific channel provided in the request. The
+// password is left as is. If-Match must be the ETag of the version the update
+// is based on.
+func (c Channel) Patch(w http.ResponseWriter, r *http.Request) error {
+	// get the fields embedded in the request's context
+	patch, e := are_hub.ChannelPatchFromCtx(r.Context())
+
+	if e != nil {
+		return e
+	}
+
+	version, e := ifMatch(r)
+
+	if e != nil {
+		return e
+	}
+
+	// the channel as it was for the audit log
+	before, e := c.channels.FindID(r.Context(), uf.GetParam(r, "id"))
+
+	if e != nil {
+		return updateFailed(e)
+	}
+
+	channel, e := c.channels.PatchID(r.Context(), uf.GetParam(r, "id"), version, *patch)
+
+	if e != nil {
+		return updateFailed(e)
+	}
+
+	c.ts.refresh(channel)
+	c.audit(w, r, are_hub.AUDIT_CHANNEL_UPDATE, channel.ID, before, channel, false)
+	w.Header().Set("ETag", etag(channel.Version))
+
+	return uf.SendJSON(w, channel)
+}
+
+// Change the password of a specific channel. The current password must be
+// provided.
+func (c Channel) Password(w http.ResponseWriter, r *http.Request) error {
+	// get the passwords embedded in the request's context
+	change, e := are_hub.PasswordChangeFromCtx(r.Context())
+
+	if e != nil {
+		return e
+	}
+
+	channel, e := c.channels.FindID(r.Context(), uf.GetParam(r, "id"))
 
 	if e != nil {
 		if are_hub.IsNoObjectsFound(e) {
@@ -109,11 +349,45 @@ func (c Channel) Update(w http.ResponseWriter, r *http.Request) error {
 		return e
 	}
 
-	// return the updated channel
+	match, e := verify(channel.PasswordStr(), change.Current)
+
+	if e != nil {
+		return e
+	}
+
+	if !match {
+		return uf.Forbidden("Incorrect current password.")
+	}
+
+	hash, e := hash.Password(change.New)
+
+	if e != nil {
+		return e
+	}
+
+	// fails if the channel was updated since it was found
+	channel, e = c.channels.PatchID(r.Context(), uf.GetParam(r, "id"), channel.Version, are_hub.ChannelPatch{Password: &hash})
+
+	if e != nil {
+		return updateFailed(e)
+	}
+
+	// sessions were granted under the old password
+	e = c.sessions.DeleteChannelID(r.Context(), channel.ID)
+
+	if e != nil {
+		return e
+	}
+
+	c.ts.refresh(channel)
+	c.audit(w, r, are_hub.AUDIT_CHANNEL_PASSWORD, channel.ID, nil, nil, true)
+	w.Header().Set("ETag", etag(channel.Version))
+
 	return uf.SendJSON(w, channel)
 }
 
-// Delete a specific channel by its ID.
+// Move a specific channel to the trash by its ID. Channels are permanently
+// deleted once they have been in the trash for the retention period.
 func (c Channel) Delete(w http.ResponseWriter, r *http.Request) error {
 	// delete and get the deleted channel
 	channel, e := c.channels.DeleteID(r.Context(), uf.GetParam(r, "id"))
@@ -126,6 +400,124 @@ func (c Channel) Delete(w http.ResponseWriter, r *http.Request) error {
 		return e
 	}
 
+	c.events.Emit(are_hub.NewEvent(are_hub.EVENT_CHANNEL_DELETED, channel.ID, channel))
+
+	// disconnect the channel's subscribers
+	c.ts.closeChannel(channel.ID, "Channel deleted")
+	c.audit(w, r, are_hub.AUDIT_CHANNEL_DELETE, channel.ID, channel, nil, false)
+
 	// return the deleted channel
 	return uf.SendJSON(w, channel)
 }
+
+// Get the channels in the trash.
+func (c Channel) Trash(w http.ResponseWriter, r *http.Request) error {
+	channels, e := c.channels.Trash(r.Context())
+
+	if e != nil {
+		return e
+	}
+
+	if channels == nil {
+		// send an empty array rather than null
+		channels = []are_hub.Channel{}
+	}
+
+	return uf.SendJSON(w, channels)
+}
+
+// Restore a specific channel from the trash by its ID.
+func (c Channel) Restore(w http.ResponseWriter, r *http.Request) error {
+	channel, e := c.channels.RestoreID(r.Context(), uf.GetParam(r, "id"))
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound(e.Error())
+		}
+
+		// another channel has taken the name since
+		if are_hub.IsDuplicate(e) {
+			return uf.HttpError{Code: http.StatusConflict, Message: e.Error()}
+		}
+
+		return e
+	}
+
+	c.events.Emit(are_hub.NewEvent(are_hub.EVENT_CHANNEL_RESTORED, channel.ID, channel))
+	c.audit(w, r, are_hub.AUDIT_CHANNEL_RESTORE, channel.ID, nil, nil, false)
+
+	return uf.SendJSON(w, channel)
+}
+
+// Record action on the channel matching id in the audit log with the fields
+// changed from before to after (either may be nil). A redacted password change
+// is included if password is true. Channel routes are not authorised so
+// changes are made anonymously.
+func (c Channel) audit(w http.ResponseWriter, r *http.Request, action, id string, before, after *are_hub.Channel, password bool) {
+	event := are_hub.NewAuditEvent(are_hub.ACTOR_ANONYMOUS, action, id)
+	changes, e := are_hub.Diff(before, after)
+
+	if e != nil {
+		log.Printf("audit %s of %s: %v", action, id, e)
+	}
+
+	if password {
+		if changes == nil {
+			changes = make(map[string]are_hub.Change)
+		}
+
+		change := are_hub.Change{Before: are_hub.REDACTED, After: are_hub.REDACTED}
+
+		if before == nil && after != nil {
+			// the password was set when the channel was created
+			change.Before = nil
+		}
+
+		changes["password"] = change
+	}
+
+	if len(changes) > 0 {
+		event.Changes = changes
+	}
+
+	audit(c.audits, w, r, event)
+}
+
+// Format a version as an entity tag. Eg. "3" (including the quotes).
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// Parse the version in the If-Match header of r.
+func ifMatch(r *http.Request) (int64, error) {
+	v := r.Header.Get("If-Match")
+
+	if len(v) == 0 {
+		return 0, uf.HttpError{Code: http.StatusPreconditionRequired, Message: "Expected the channel's ETag in If-Match."}
+	}
+
+	version, e := strconv.ParseInt(strings.Trim(strings.TrimPrefix(v, "W/"), `"`), 10, 64)
+
+	if e != nil || version < 0 {
+		return 0, uf.BadRequest("If-Match is not an ETag of the channel: " + v)
+	}
+
+	return version, nil
+}
+
+// Convert the errors of updating a channel into HTTP errors.
+func updateFailed(e error) error {
+	if are_hub.IsNoObjectsFound(e) {
+		return uf.NotFound(e.Error())
+	}
+
+	if are_hub.IsVersionConflict(e) {
+		return uf.HttpError{Code: http.StatusPreconditionFailed, Message: e.Error()}
+	}
+
+	if are_hub.IsDuplicate(e) {
+		return uf.HttpError{Code: http.StatusConflict, Message: e.Error()}
+	}
+
+	return e
+}
@@ -6,10 +6,14 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/acc"
 	"github.com/blacksfk/are_hub/mock"
 	uf "github.com/blacksfk/microframework"
 	"github.com/julienschmidt/httprouter"
@@ -21,18 +25,18 @@ var channels []are_hub.Channel = []are_hub.Channel{
 	{Name: "Mercedes-AMG Team Black Falcon", Password: "lol123"},
 }
 
-// Does it call repo.All?
+// Does it call repo.Query?
 // Is the Content-Type header set to application/json?
 // Does it return all channels?
 func TestChannelIndex(t *testing.T) {
-	// mock All function
-	fn := func(_ context.Context) ([]are_hub.Channel, error) {
-		return channels, nil
+	// mock Query function
+	fn := func(_ context.Context, q are_hub.ChannelQuery) (*are_hub.ChannelPage, error) {
+		return &are_hub.ChannelPage{Channels: channels, Total: int64(len(channels))}, nil
 	}
 
 	// create the mock repo and controller
-	repo := &mock.ChannelRepo{AllFunc: fn}
-	controller := NewChannel(repo)
+	repo := &mock.ChannelRepo{QueryFunc: fn}
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), discardAudits())
 
 	// create a mock request
 	req, e := http.NewRequest(http.MethodGet, "/channel", nil)
@@ -53,8 +57,13 @@ func TestChannelIndex(t *testing.T) {
 	res := w.Result()
 
 	// check if the repo was hit
-	if !repo.AllCalled {
-		t.Error("Did not call repo.All")
+	if !repo.QueryCalled {
+		t.Error("Did not call repo.Query")
+	}
+
+	// everything fits on the first page
+	if total, link := res.Header.Get("X-Total-Count"), res.Header.Get("Link"); total != "2" || len(link) > 0 {
+		t.Errorf("Expected: total of 2 without a next page. Actual: %s, %q.", total, link)
 	}
 
 	// ensure the content type is application/json
@@ -91,6 +100,159 @@ func TestChannelIndex(t *testing.T) {
 	}
 }
 
+// Is the query string parsed into the repository query?
+// Is the next page linked?
+// Are invalid queries rejected?
+func TestChannelIndexQuery(t *testing.T) {
+	var query are_hub.ChannelQuery
+
+	repo := &mock.ChannelRepo{
+		QueryFunc: func(_ context.Context, q are_hub.ChannelQuery) (*are_hub.ChannelPage, error) {
+			query = q
+
+			return &are_hub.ChannelPage{Channels: channels, Total: 5}, nil
+		},
+	}
+
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), discardAudits())
+	url := "/channel?name=wrt&track=spa&tag=gt3&tag=endurance&createdAfter=2021-05-01T10:00:00%2B10:00&sort=-updatedAt&offset=1&limit=2"
+	req, e := http.NewRequest(http.MethodGet, url, nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	w := httptest.NewRecorder()
+	e = controller.Index(w, req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	expected := are_hub.ChannelQuery{
+		Name:         "wrt",
+		Track:        "spa",
+		Tags:         []string{"gt3", "endurance"},
+		CreatedAfter: time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC),
+		Sort:         are_hub.SORT_UPDATED,
+		Descending:   true,
+		Offset:       1,
+		Limit:        2,
+	}
+
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("Expected: %+v. Actual: %+v.", expected, query)
+	}
+
+	res := w.Result()
+	next := `</channel?createdAfter=2021-05-01T10%3A00%3A00%2B10%3A00&limit=2&name=wrt&offset=3&sort=-updatedAt&tag=gt3&tag=endurance&track=spa>; rel="next"`
+
+	if link := res.Header.Get("Link"); link != next {
+		t.Errorf("Expected: %s. Actual: %s.", next, link)
+	}
+
+	if total := res.Header.Get("X-Total-Count"); total != "5" {
+		t.Errorf("Expected: 5. Actual: %s.", total)
+	}
+
+	for _, q := range []string{"sort=password", "limit=-1", "offset=x", "updatedBefore=yesterday", "visibility=secret"} {
+		req, e = http.NewRequest(http.MethodGet, "/channel?"+q, nil)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		e = controller.Index(httptest.NewRecorder(), req)
+
+		if he, ok := e.(uf.HttpError); !ok || he.Code != http.StatusBadRequest {
+			t.Errorf("%s: Expected: %d. Actual: %v.", q, http.StatusBadRequest, e)
+		}
+	}
+}
+
+// Are only public channels listed in the directory?
+func TestChannelDirectory(t *testing.T) {
+	var query are_hub.ChannelQuery
+
+	repo := &mock.ChannelRepo{
+		QueryFunc: func(_ context.Context, q are_hub.ChannelQuery) (*are_hub.ChannelPage, error) {
+			query = q
+
+			return &are_hub.ChannelPage{}, nil
+		},
+	}
+
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), discardAudits())
+	req, e := http.NewRequest(http.MethodGet, "/directory?visibility=private&name=wrt", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	w := httptest.NewRecorder()
+	e = controller.Directory(w, req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if query.Visibility != are_hub.VISIBILITY_PUBLIC || query.Name != "wrt" {
+		t.Errorf("Expected: public channels named wrt. Actual: %+v.", query)
+	}
+
+	if body := strings.TrimSpace(w.Body.String()); body != "[]" {
+		t.Errorf("Expected: []. Actual: %s.", body)
+	}
+}
+
+// Is when the channel was created kept rather than replaced by the zero time?
+// Are the sessions granted under the replaced password deleted?
+func TestChannelUpdateCreatedAt(t *testing.T) {
+	created := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	var updated time.Time
+
+	repo := &mock.ChannelRepo{
+		FindIDFunc: func(context.Context, string) (*are_hub.Channel, error) {
+			c := are_hub.NewChannel("Audi Sport Team WRT", "abc123")
+			c.CreatedAt = created
+
+			return c, nil
+		},
+		UpdateIDFunc: func(_ context.Context, _ string, _ int64, v are_hub.Versioned) error {
+			updated = v.(*are_hub.Channel).CreatedAt
+
+			return nil
+		},
+	}
+
+	sessions := discardSessions()
+	controller := NewChannel(repo, sessions, discardEvents(), testTelemetry(), discardAudits())
+	wrt := are_hub.Channel{Name: "Belgian Audi Club WRT", Password: "abc123"}
+	req := httptest.NewRequest(http.MethodPut, "/channel/1", nil)
+	req = req.WithContext(wrt.ToCtx(req.Context()))
+	req.Header.Set("If-Match", `"0"`)
+	uf.EmbedParams(req, httprouter.Param{Key: "id", Value: "1"})
+	w := httptest.NewRecorder()
+
+	if e := controller.Update(w, req); e != nil {
+		t.Fatal(e)
+	}
+
+	received := are_hub.Channel{}
+
+	if e := json.NewDecoder(w.Result().Body).Decode(&received); e != nil {
+		t.Fatal(e)
+	}
+
+	if !updated.Equal(created) || !received.CreatedAt.Equal(created) {
+		t.Errorf("Expected: created at %v. Actual: %v, sent %v.", created, updated, received.CreatedAt)
+	}
+
+	if !sessions.DeleteChannelIDCalled {
+		t.Error("Expected the sessions granted under the replaced password to be deleted")
+	}
+}
+
 // Does it call repo.Store?
 // Is the content type set to application/json?
 // Does it return the new channel?
@@ -102,7 +264,13 @@ func TestChannelStore(t *testing.T) {
 
 	// create mock repo and controller
 	repo := &mock.ChannelRepo{InsertFunc: fn}
-	controller := NewChannel(repo)
+	events := &mock.Emitter{EmitFunc: func(ev are_hub.Event) {
+		if ev.Type != are_hub.EVENT_CHANNEL_CREATED {
+			t.Errorf("Expected: %s. Actual: %s.", are_hub.EVENT_CHANNEL_CREATED, ev.Type)
+		}
+	}}
+
+	controller := NewChannel(repo, discardSessions(), events, testTelemetry(), discardAudits())
 
 	// create and embed a new channel
 	msport := are_hub.Channel{Name: "Bentley Team M-Sport", Password: "abc123"}
@@ -130,6 +298,10 @@ func TestChannelStore(t *testing.T) {
 		t.Error("Did not call repo.Insert")
 	}
 
+	if !events.EmitCalled {
+		t.Error("Did not emit channel.created")
+	}
+
 	// get the response
 	res := w.Result()
 
@@ -158,6 +330,43 @@ func TestChannelStore(t *testing.T) {
 	}
 }
 
+// Do Store and Update return 409 Conflict for duplicate channel names?
+func TestChannelDuplicate(t *testing.T) {
+	duplicate := are_hub.NewDuplicate("channels", "name")
+	repo := &mock.ChannelRepo{
+		FindIDFunc: findChannelID,
+		InsertFunc: func(_ context.Context, _ are_hub.Archetype) error {
+			return duplicate
+		},
+		UpdateIDFunc: func(_ context.Context, _ string, _ int64, _ are_hub.Versioned) error {
+			return duplicate
+		},
+	}
+
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), discardAudits())
+
+	for method, h := range map[string]uf.Handler{
+		http.MethodPost: controller.Store,
+		http.MethodPut:  controller.Update,
+	} {
+		wrt := are_hub.Channel{Name: "Audi Sport Team WRT", Password: "abc123"}
+		req, e := http.NewRequest(method, "/channel/1", nil)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		req = req.WithContext(wrt.ToCtx(req.Context()))
+		req.Header.Set("If-Match", `"0"`)
+		uf.EmbedParams(req, httprouter.Param{Key: "id", Value: "1"})
+		e = h(httptest.NewRecorder(), req)
+
+		if he, ok := e.(uf.HttpError); !ok || he.Code != http.StatusConflict {
+			t.Errorf("%s: Expected: %d. Actual: %v.", method, http.StatusConflict, e)
+		}
+	}
+}
+
 // Does it call repo.FindID?
 // Is the content type set to application/json?
 // Does it return the correct channel?
@@ -168,7 +377,7 @@ func TestChannelShow(t *testing.T) {
 
 	// create the mock repo and controller
 	repo := &mock.ChannelRepo{FindIDFunc: findChannelID}
-	controller := NewChannel(repo)
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), discardAudits())
 
 	// create a mock request
 	p := httprouter.Param{Key: "id", Value: "1"}
@@ -220,6 +429,10 @@ func TestChannelShow(t *testing.T) {
 		t.Fatalf("Expected: %+v. Actual: %+v.", wrt, received)
 	}
 
+	if tag := res.Header.Get("ETag"); tag != `"0"` {
+		t.Errorf("Expected: \"0\". Actual: %s.", tag)
+	}
+
 	// check show returns 404 for an invalid ID
 	p = httprouter.Param{Key: "id", Value: "-1"}
 	test404(t, http.MethodGet, "/channel/"+p.Value, nil, controller.Show, p)
@@ -227,20 +440,29 @@ func TestChannelShow(t *testing.T) {
 
 // Does it call repo.UpdateID?
 // Is the content type set to application/json?
-// Does it return the updated channel?
+// Does it return the updated channel with its new version as the ETag?
 func TestChannelUpdate(t *testing.T) {
 	// mock UpdateID function
-	fn := func(_ context.Context, str string, v are_hub.Archetype) error {
+	fn := func(_ context.Context, str string, version int64, v are_hub.Versioned) error {
 		_, e := findChannelID(nil, str)
 
-		// the update itself has no bearing on the test so simply return
-		// the error (if there was one)
-		return e
+		if e != nil {
+			return e
+		}
+
+		// every channel is at version 2
+		if version != 2 {
+			return are_hub.NewVersionConflict("channels", str, version)
+		}
+
+		v.SetVersion(version + 1)
+
+		return nil
 	}
 
 	// create mock repo and controller
-	repo := &mock.ChannelRepo{UpdateIDFunc: fn}
-	controller := NewChannel(repo)
+	repo := &mock.ChannelRepo{FindIDFunc: findChannelID, UpdateIDFunc: fn}
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), discardAudits())
 
 	// mock channel
 	wrt := are_hub.Channel{Name: "Belgian Audi Club WRT", Password: "abc123"}
@@ -255,6 +477,7 @@ func TestChannelUpdate(t *testing.T) {
 
 	// embed the updated channel in the request's context
 	req = req.WithContext(wrt.ToCtx(req.Context()))
+	req.Header.Set("If-Match", `"2"`)
 
 	// embed parameters in the request's context
 	uf.EmbedParams(req, p)
@@ -297,6 +520,10 @@ func TestChannelUpdate(t *testing.T) {
 		t.Fatalf("Expected: %+v. Actual: %+v", wrt, received)
 	}
 
+	if tag := res.Header.Get("ETag"); received.Version != 3 || tag != `"3"` {
+		t.Errorf("Expected: version 3. Actual: %d, ETag: %s.", received.Version, tag)
+	}
+
 	// check if Update returns a 404 error on an invalid ID
 	p = httprouter.Param{Key: "id", Value: "-1"}
 	req, e = http.NewRequest(http.MethodPut, "/channel/"+p.Value, nil)
@@ -308,6 +535,7 @@ func TestChannelUpdate(t *testing.T) {
 	// embed non-existant channel into the request's context
 	gpx := are_hub.Channel{Name: "Grand Prix Extreme", Password: "porsche"}
 	req = req.WithContext(gpx.ToCtx(req.Context()))
+	req.Header.Set("If-Match", `"2"`)
 
 	// embed parameters
 	uf.EmbedParams(req, p)
@@ -331,6 +559,194 @@ func TestChannelUpdate(t *testing.T) {
 	}
 }
 
+// Is If-Match required?
+// Are updates based on an old version rejected with 412 Precondition Failed?
+func TestChannelUpdatePrecondition(t *testing.T) {
+	repo := &mock.ChannelRepo{
+		FindIDFunc: findChannelID,
+		UpdateIDFunc: func(_ context.Context, id string, version int64, _ are_hub.Versioned) error {
+			return are_hub.NewVersionConflict("channels", id, version)
+		},
+	}
+
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), discardAudits())
+
+	for tag, code := range map[string]int{
+		"":       http.StatusPreconditionRequired,
+		"*":      http.StatusBadRequest,
+		`W/"1"`:  http.StatusPreconditionFailed,
+		`"1"`:    http.StatusPreconditionFailed,
+		`"-1"`:   http.StatusBadRequest,
+		`"nope"`: http.StatusBadRequest,
+	} {
+		wrt := are_hub.Channel{Name: "Audi Sport Team WRT", Password: "abc123"}
+		req, e := http.NewRequest(http.MethodPut, "/channel/1", nil)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		req = req.WithContext(wrt.ToCtx(req.Context()))
+		uf.EmbedParams(req, httprouter.Param{Key: "id", Value: "1"})
+
+		if len(tag) > 0 {
+			req.Header.Set("If-Match", tag)
+		}
+
+		e = controller.Update(httptest.NewRecorder(), req)
+
+		if he, ok := e.(uf.HttpError); !ok || he.Code != code {
+			t.Errorf("%q: Expected: %d. Actual: %v.", tag, code, e)
+		}
+	}
+}
+
+// Does it call repo.PatchID with only the fields provided?
+// Does it return the patched channel with its new version as the ETag?
+func TestChannelPatch(t *testing.T) {
+	var patched are_hub.ChannelPatch
+
+	repo := &mock.ChannelRepo{
+		FindIDFunc: findChannelID,
+		PatchIDFunc: func(_ context.Context, id string, version int64, p are_hub.ChannelPatch) (*are_hub.Channel, error) {
+			if version != 2 {
+				return nil, are_hub.NewVersionConflict("channels", id, version)
+			}
+
+			patched = p
+			c := are_hub.NewChannel(*p.Name, "hash")
+			c.ID = id
+			c.Version = version + 1
+
+			return c, nil
+		},
+	}
+
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), discardAudits())
+	name := "Team WRT"
+
+	for tag, code := range map[string]int{"": http.StatusPreconditionRequired, `"1"`: http.StatusPreconditionFailed, `"2"`: http.StatusOK} {
+		req, e := http.NewRequest(http.MethodPatch, "/channel/1", nil)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		patch := &are_hub.ChannelPatch{Name: &name}
+		req = req.WithContext(patch.ToCtx(req.Context()))
+		uf.EmbedParams(req, httprouter.Param{Key: "id", Value: "1"})
+
+		if len(tag) > 0 {
+			req.Header.Set("If-Match", tag)
+		}
+
+		w := httptest.NewRecorder()
+		e = controller.Patch(w, req)
+
+		if code != http.StatusOK {
+			if he, ok := e.(uf.HttpError); !ok || he.Code != code {
+				t.Errorf("%q: Expected: %d. Actual: %v.", tag, code, e)
+			}
+
+			continue
+		}
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		res := w.Result()
+		checkCT(res, t)
+
+		if etag := res.Header.Get("ETag"); etag != `"3"` {
+			t.Errorf("Expected: \"3\". Actual: %s.", etag)
+		}
+	}
+
+	if patched.Name == nil || *patched.Name != name || patched.Password != nil {
+		t.Errorf("Expected: only the name patched. Actual: %+v.", patched)
+	}
+}
+
+// Is the current password required?
+// Is only the password patched, based on the version found?
+func TestChannelPassword(t *testing.T) {
+	var patched are_hub.ChannelPatch
+
+	repo := &mock.ChannelRepo{
+		FindIDFunc: func(_ context.Context, id string) (*are_hub.Channel, error) {
+			c := are_hub.NewChannel("Audi Sport Team WRT", testHash)
+			c.ID = id
+			c.Version = 4
+
+			return c, nil
+		},
+		PatchIDFunc: func(_ context.Context, id string, version int64, p are_hub.ChannelPatch) (*are_hub.Channel, error) {
+			if version != 4 {
+				return nil, are_hub.NewVersionConflict("channels", id, version)
+			}
+
+			patched = p
+			c := are_hub.NewChannel("Audi Sport Team WRT", *p.Password)
+			c.ID = id
+			c.Version = version + 1
+
+			return c, nil
+		},
+	}
+
+	sessions := discardSessions()
+	controller := NewChannel(repo, sessions, discardEvents(), testTelemetry(), discardAudits())
+
+	// in order so that the incorrect password is tried first
+	for _, attempt := range []struct {
+		current string
+		code    int
+	}{{"nope", http.StatusForbidden}, {testChannelPassword, http.StatusOK}} {
+		current, code := attempt.current, attempt.code
+		req, e := http.NewRequest(http.MethodPut, "/channel/1/password", nil)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		change := &are_hub.PasswordChange{Current: current, New: "def456"}
+		req = req.WithContext(change.ToCtx(req.Context()))
+		uf.EmbedParams(req, httprouter.Param{Key: "id", Value: "1"})
+
+		w := httptest.NewRecorder()
+		e = controller.Password(w, req)
+
+		if code != http.StatusOK {
+			if he, ok := e.(uf.HttpError); !ok || he.Code != code {
+				t.Errorf("%s: Expected: %d. Actual: %v.", current, code, e)
+			}
+
+			if repo.PatchIDCalled || sessions.DeleteChannelIDCalled {
+				t.Error("Expected the password and sessions to be left as is")
+			}
+
+			continue
+		}
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if etag := w.Result().Header.Get("ETag"); etag != `"5"` {
+			t.Errorf("Expected: \"5\". Actual: %s.", etag)
+		}
+	}
+
+	if patched.Password == nil || *patched.Password == "def456" || patched.Name != nil {
+		t.Errorf("Expected: only the hashed password patched. Actual: %+v.", patched)
+	}
+
+	if !sessions.DeleteChannelIDCalled {
+		t.Error("Expected the channel's sessions to be deleted")
+	}
+}
+
 // Does it call repo.DeleteID with the correct ID?
 // Is the content type set to application/json?
 // Does it return the deleted channel?
@@ -344,7 +760,13 @@ func TestChannelDelete(t *testing.T) {
 	// so just use the findID function which has the the same signature
 	// and performs the operation we need
 	repo := &mock.ChannelRepo{DeleteIDFunc: findChannelID}
-	controller := NewChannel(repo)
+	events := &mock.Emitter{EmitFunc: func(ev are_hub.Event) {
+		if ev.Type != are_hub.EVENT_CHANNEL_DELETED {
+			t.Errorf("Expected: %s. Actual: %s.", are_hub.EVENT_CHANNEL_DELETED, ev.Type)
+		}
+	}}
+
+	controller := NewChannel(repo, discardSessions(), events, testTelemetry(), discardAudits())
 
 	// create a mock request
 	p := httprouter.Param{Key: "id", Value: "1"}
@@ -372,6 +794,10 @@ func TestChannelDelete(t *testing.T) {
 		t.Error("Did not call repo.DeleteID")
 	}
 
+	if !events.EmitCalled {
+		t.Error("Did not emit channel.deleted")
+	}
+
 	// ensure the content type is application/json
 	checkCT(res, t)
 
@@ -399,6 +825,70 @@ func TestChannelDelete(t *testing.T) {
 	test404(t, http.MethodDelete, "/channel/"+p.Value, nil, controller.Delete, p)
 }
 
+// Does it list the channels in the trash, sending an empty array if there are
+// none?
+func TestChannelTrash(t *testing.T) {
+	var trash []are_hub.Channel
+
+	repo := &mock.ChannelRepo{TrashFunc: func(_ context.Context) ([]are_hub.Channel, error) {
+		return trash, nil
+	}}
+
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), discardAudits())
+
+	for _, expected := range []string{"[]", `"deletedAt":"2021-05-01T00:00:00Z"`} {
+		req, e := http.NewRequest(http.MethodGet, "/trash/channel", nil)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		w := httptest.NewRecorder()
+		e = controller.Trash(w, req)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		checkCT(w.Result(), t)
+
+		if body := w.Body.String(); !strings.Contains(body, expected) {
+			t.Errorf("Expected: %s. Actual: %s.", expected, body)
+		}
+
+		deleted := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+		trash = []are_hub.Channel{channels[0]}
+		trash[0].DeletedAt = &deleted
+	}
+}
+
+// Does it call repo.RestoreID and emit channel.restored?
+// Does it return 404 for channels not in the trash?
+func TestChannelRestore(t *testing.T) {
+	repo := &mock.ChannelRepo{RestoreIDFunc: findChannelID}
+	events := &mock.Emitter{EmitFunc: func(ev are_hub.Event) {
+		if ev.Type != are_hub.EVENT_CHANNEL_RESTORED {
+			t.Errorf("Expected: %s. Actual: %s.", are_hub.EVENT_CHANNEL_RESTORED, ev.Type)
+		}
+	}}
+
+	controller := NewChannel(repo, discardSessions(), events, testTelemetry(), discardAudits())
+	received := are_hub.Channel{}
+
+	callAdmin(t, http.MethodPost, controller.Restore, &received, httprouter.Param{Key: "id", Value: "1"})
+
+	if received.Name != channels[0].Name {
+		t.Errorf("Expected: %s. Actual: %s.", channels[0].Name, received.Name)
+	}
+
+	if !repo.RestoreIDCalled || !events.EmitCalled {
+		t.Error("Expected the channel to be restored and channel.restored emitted")
+	}
+
+	p := httprouter.Param{Key: "id", Value: "-1"}
+	test404(t, http.MethodPost, "/channel/"+p.Value+"/restore", nil, controller.Restore, p)
+}
+
 func findChannelID(_ context.Context, str string) (*are_hub.Channel, error) {
 	id64, e := strconv.ParseInt(str, 10, 64)
 
@@ -422,3 +912,90 @@ func checkCT(res *http.Response, t *testing.T) {
 		t.Fatalf("Incorrect content type. Expected: application/json. Actual: %s", ct)
 	}
 }
+
+// Does it return 409 Conflict when another channel has taken the name since?
+func TestChannelRestoreConflict(t *testing.T) {
+	repo := &mock.ChannelRepo{RestoreIDFunc: func(context.Context, string) (*are_hub.Channel, error) {
+		return nil, are_hub.NewDuplicate("channels", "name")
+	}}
+
+	controller := NewChannel(repo, discardSessions(), discardEvents(), testTelemetry(), discardAudits())
+	req := httptest.NewRequest(http.MethodPost, "/channel/1/restore", nil)
+	uf.EmbedParams(req, httprouter.Param{Key: "id", Value: "1"})
+	e := controller.Restore(httptest.NewRecorder(), req)
+
+	if he, ok := e.(uf.HttpError); !ok || he.Code != http.StatusConflict {
+		t.Errorf("Expected: %d. Actual: %v.", http.StatusConflict, e)
+	}
+}
+
+// Is the ETag of a channel still current after its metadata is filled from
+// telemetry?
+func TestChannelUpdateAfterFill(t *testing.T) {
+	hub := newTestHub(t)
+	hub.schema = acc.SCHEMA
+	stored := are_hub.NewChannel("Audi Sport Team WRT", testHash)
+	stored.ID = testChannelID
+	stored.Schema = acc.SCHEMA
+	stored.Version = 3
+
+	// version the channel like the repository does
+	repo := hub.ts.repo.(*mock.ChannelRepo)
+	repo.FindIDFunc = func(context.Context, string) (*are_hub.Channel, error) {
+		hub.mtx.Lock()
+		defer hub.mtx.Unlock()
+
+		c := *stored
+
+		return &c, nil
+	}
+	repo.FillIDFunc = func(_ context.Context, _ string, m are_hub.Metadata) (*are_hub.Channel, error) {
+		hub.mtx.Lock()
+		defer hub.mtx.Unlock()
+
+		stored.Sim = m.Sim
+
+		return stored, nil
+	}
+	repo.UpdateIDFunc = func(_ context.Context, id string, version int64, v are_hub.Versioned) error {
+		hub.mtx.Lock()
+		defer hub.mtx.Unlock()
+
+		if version != stored.Version {
+			return are_hub.NewVersionConflict("channels", id, version)
+		}
+
+		stored.Version++
+
+		return nil
+	}
+
+	controller := NewChannel(repo, discardSessions(), discardEvents(), hub.ts, discardAudits())
+	req := httptest.NewRequest(http.MethodGet, "/channel/"+testChannelID, nil)
+	uf.EmbedParams(req, httprouter.Param{Key: "id", Value: testChannelID})
+	w := httptest.NewRecorder()
+
+	if e := controller.Show(w, req); e != nil {
+		t.Fatal(e)
+	}
+
+	tag := w.Result().Header.Get("ETag")
+
+	if res := hub.publish(t, testChannelID, testChannelPassword, `{"version":1}`); res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+
+	if stored.Sim != acc.MetadataFields.Sim {
+		t.Fatalf("Expected the metadata to be filled. Actual: %+v.", stored.Metadata)
+	}
+
+	wrt := are_hub.Channel{Name: "Belgian Audi Club WRT", Password: "abc123"}
+	req = httptest.NewRequest(http.MethodPut, "/channel/"+testChannelID, nil)
+	req = req.WithContext(wrt.ToCtx(req.Context()))
+	req.Header.Set("If-Match", tag)
+	uf.EmbedParams(req, httprouter.Param{Key: "id", Value: testChannelID})
+
+	if e := controller.Update(httptest.NewRecorder(), req); e != nil {
+		t.Errorf("Expected the update to succeed with %s. Actual: %v.", tag, e)
+	}
+}
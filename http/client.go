@@ -1,8 +1,10 @@
 package http
 
 import (
+	"crypto/rand"
 	"encoding/hex"
-	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"nhooyr.io/websocket"
@@ -13,35 +15,85 @@ const (
 	ID_LEN = 6
 
 	// How many messages to queue for the client before the connection
-	// is dropped for being too slow.
+	// is dropped for being too slow unless configured otherwise. See
+	// are_hub.Limits.
 	BUF_LEN = 16
 )
 
-// Wraps a websocket.Conn and implements a message buffer.
+// Wraps a websocket.Conn and implements a message buffer. Clients streaming
+// over server-sent events have a nil conn and are notified of being dropped
+// through done.
 type client struct {
 	id     string
 	conn   *websocket.Conn
-	buffer chan []byte
-}
+	buffer chan *envelope
 
-// Create a new client.
-func newClient(conn *websocket.Conn) *client {
-	// allocate a byte slice
-	bytes := make([]byte, ID_LEN)
+	// Car followed on team channels. All cars are followed if empty.
+	car string
 
-	// firstly seed the default math/rand source with the current epoch timestamp.
-	rand.Seed(time.Now().Unix())
+	// Slow subscriber policy chosen by the client. The channel's policy applies
+	// if empty. See are_hub.Limits.SlowPolicy.
+	policy string
 
-	// math/rand.Read always returns a nil error.
-	rand.Read(bytes)
+	// Frames discarded since the client was last told and in total. Guarded
+	// by the channel's subMtx.
+	missed uint64
+	lost   uint64
 
-	// encode the random bytes as hex
-	id := hex.EncodeToString(bytes)
+	// Address of the connected client and when it connected.
+	remote    string
+	connected time.Time
 
-	return &client{id, conn, make(chan []byte, BUF_LEN)}
+	// Messages delivered to the client.
+	sent atomic.Uint64
+
+	// Closed when the client is dropped. code is the status it was dropped
+	// with and is only read once done is closed.
+	done chan struct{}
+	once sync.Once
+	code websocket.StatusCode
+}
+
+// Create a new client connected from remote queueing up to buf messages.
+func newClient(conn *websocket.Conn, remote string, buf int) *client {
+	return &client{
+		id:        newID(),
+		conn:      conn,
+		buffer:    make(chan *envelope, buf),
+		remote:    remote,
+		connected: time.Now().UTC(),
+		done:      make(chan struct{}),
+	}
 }
 
 // Disconnect the client.
 func (c *client) drop(code websocket.StatusCode, reason string) {
-	c.conn.Close(code, reason)
+	c.once.Do(func() {
+		c.code = code
+		close(c.done)
+
+		if c.conn != nil {
+			c.conn.Close(code, reason)
+		}
+	})
+}
+
+// Check whether the client receives env. Messages relating to the entire
+// channel are received by every client.
+func (c *client) follows(env *envelope) bool {
+	return len(c.car) == 0 || len(env.car) == 0 || env.car == c.car
+}
+
+// Create a random hex encoded ID of ID_LEN bytes.
+func newID() string {
+	// allocate a byte slice
+	bytes := make([]byte, ID_LEN)
+
+	// re-seeding math/rand with the current epoch timestamp handed out the same ID
+	// to every client connecting within the same second so use crypto/rand.
+	// Reading can only fail if the system's entropy source is unavailable.
+	rand.Read(bytes)
+
+	// encode the random bytes as hex
+	return hex.EncodeToString(bytes)
 }
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/hash"
+	uf "github.com/blacksfk/microframework"
+)
+
+// How long sessions redeemed from invitations are accepted for.
+const SESSION_TTL = time.Hour * 24
+
+// Controller managing the invitations of the channel in the URL and redeeming
+// them for sessions. The routes managing invitations must be guarded with
+// TelemetryServer.Owner. Every change is recorded in audits.
+type Invite struct {
+	invites  are_hub.InviteRepo
+	sessions are_hub.SessionRepo
+	audits   are_hub.AuditRepo
+}
+
+// Create a new invitation controller.
+func NewInvite(invites are_hub.InviteRepo, sessions are_hub.SessionRepo, audits are_hub.AuditRepo) Invite {
+	return Invite{invites, sessions, audits}
+}
+
+// Get the invitations of the channel in the URL that can still be redeemed.
+func (i Invite) Index(w http.ResponseWriter, r *http.Request) error {
+	invites, e := i.invites.FindChannelID(r.Context(), uf.GetParam(r, "id"))
+
+	if e != nil {
+		return e
+	}
+
+	if invites == nil {
+		// send an empty array rather than null
+		invites = []are_hub.Invite{}
+	}
+
+	return uf.SendJSON(w, invites)
+}
+
+// Create a new invitation. Its token is only sent in this response.
+func (i Invite) Store(w http.ResponseWriter, r *http.Request) error {
+	// get the invitation from the request's context
+	invite, e := are_hub.InviteFromCtx(r.Context())
+
+	if e != nil {
+		return e
+	}
+
+	invite.Token, e = hash.NewToken(are_hub.INVITE_PREFIX)
+
+	if e != nil {
+		return e
+	}
+
+	invite.ChannelID = uf.GetParam(r, "id")
+	invite.TokenHash = hash.Token(invite.Token)
+	e = i.invites.Insert(r.Context(), invite)
+
+	if e != nil {
+		return e
+	}
+
+	i.audit(w, r, are_hub.AUDIT_INVITE_CREATE, invite)
+
+	// return the created invitation with the token, ID, and timestamps
+	return uf.SendJSON(w, invite)
+}
+
+// Revoke a specific invitation by its ID along with the sessions already
+// redeemed from it.
+func (i Invite) Delete(w http.ResponseWriter, r *http.Request) error {
+	invite, e := i.invites.FindID(r.Context(), uf.GetParam(r, "invite"))
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound(e.Error())
+		}
+
+		return e
+	}
+
+	// the invitation must belong to the channel in the URL
+	if invite.ChannelID != uf.GetParam(r, "id") {
+		return uf.NotFound("No invitation matching: " + uf.GetParam(r, "invite"))
+	}
+
+	invite, e = i.invites.DeleteID(r.Context(), invite.ID)
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound(e.Error())
+		}
+
+		return e
+	}
+
+	e = i.sessions.DeleteInviteID(r.Context(), invite.ID)
+
+	if e != nil {
+		return e
+	}
+
+	i.audit(w, r, are_hub.AUDIT_INVITE_REVOKE, invite)
+
+	// return the revoked invitation
+	return uf.SendJSON(w, invite)
+}
+
+// Redeem the invitation token in the request body (`{"token": "inv_..."}`)
+// for a session. The session's token is accepted in place of the channel's
+// password until it expires.
+func (i Invite) Redeem(w http.ResponseWriter, r *http.Request) error {
+	body := struct {
+		Token string `json:"token"`
+	}{}
+
+	e := uf.DecodeBodyJSON(r, &body)
+
+	if e != nil {
+		return e
+	}
+
+	if len(body.Token) == 0 {
+		return uf.BadRequest("Invitation token required.")
+	}
+
+	invite, e := i.invites.Redeem(r.Context(), hash.Token(body.Token))
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound("Invitation does not exist, has expired, or has been used up.")
+		}
+
+		return e
+	}
+
+	session := &are_hub.Session{
+		ChannelID: invite.ChannelID,
+		InviteID:  invite.ID,
+		Role:      invite.Role,
+		ExpiresAt: time.Now().Add(SESSION_TTL),
+	}
+
+	session.Token, e = hash.NewToken(are_hub.SESSION_PREFIX)
+
+	if e != nil {
+		return e
+	}
+
+	session.TokenHash = hash.Token(session.Token)
+	e = i.sessions.Insert(r.Context(), session)
+
+	if e != nil {
+		return e
+	}
+
+	i.audit(w, r, are_hub.AUDIT_INVITE_REDEEM, invite)
+
+	// return the session with its token
+	return uf.SendJSON(w, session)
+}
+
+// Record action on invite in the audit log.
+func (i Invite) audit(w http.ResponseWriter, r *http.Request, action string, invite *are_hub.Invite) {
+	event := are_hub.NewAuditEvent(are_hub.ACTOR_ANONYMOUS, action, invite.ChannelID)
+	event.Detail = invite.ID
+
+	if action == are_hub.AUDIT_INVITE_CREATE {
+		event.Changes = map[string]are_hub.Change{
+			"role":      {After: invite.Role},
+			"expiresAt": {After: invite.ExpiresAt},
+			"maxUses":   {After: invite.MaxUses},
+		}
+	}
+
+	audit(i.audits, w, r, event)
+}
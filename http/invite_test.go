@@ -0,0 +1,219 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/hash"
+	"github.com/blacksfk/are_hub/mock"
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Create an invitation controller of the test channel with invites.
+func newTestInvite(invites map[string]*are_hub.Invite, sessions *[]are_hub.Session) Invite {
+	repo := &mock.InviteRepo{
+		InsertFunc: func(_ context.Context, a are_hub.Archetype) error {
+			invite := a.(*are_hub.Invite)
+			invite.ID = invite.TokenHash[:6]
+			invites[invite.ID] = invite
+
+			return nil
+		},
+		FindIDFunc: func(_ context.Context, id string) (*are_hub.Invite, error) {
+			invite, ok := invites[id]
+
+			if !ok {
+				return nil, are_hub.NewNoObjectsFound("invites", "id == "+id)
+			}
+
+			return invite, nil
+		},
+		DeleteIDFunc: func(_ context.Context, id string) (*are_hub.Invite, error) {
+			invite := invites[id]
+			delete(invites, id)
+
+			return invite, nil
+		},
+		RedeemFunc: func(_ context.Context, hash string) (*are_hub.Invite, error) {
+			for _, invite := range invites {
+				if invite.TokenHash == hash && invite.Uses < invite.MaxUses && invite.ExpiresAt.After(time.Now()) {
+					invite.Uses++
+
+					return invite, nil
+				}
+			}
+
+			return nil, are_hub.NewNoObjectsFound("invites", "redeemable token")
+		},
+	}
+
+	sessionRepo := &mock.SessionRepo{
+		InsertFunc: func(_ context.Context, a are_hub.Archetype) error {
+			*sessions = append(*sessions, *a.(*are_hub.Session))
+
+			return nil
+		},
+		DeleteInviteIDFunc: func(_ context.Context, id string) error {
+			kept := (*sessions)[:0]
+
+			for _, s := range *sessions {
+				if s.InviteID != id {
+					kept = append(kept, s)
+				}
+			}
+
+			*sessions = kept
+
+			return nil
+		},
+	}
+
+	return NewInvite(repo, sessionRepo, discardAudits())
+}
+
+// Call h on the test channel and return the error. The channel's password is
+// checked by middleware. See TestOwner.
+func callInvite(h uf.Handler, body interface{}, w http.ResponseWriter, params ...httprouter.Param) error {
+	b, _ := json.Marshal(body)
+	r := httptest.NewRequest(http.MethodPost, "/channel/"+testChannelID+"/invites", bytes.NewReader(b))
+	r.Header.Set("Content-Type", "application/json")
+	uf.EmbedParams(r, append(params, httprouter.Param{Key: "id", Value: testChannelID})...)
+
+	if invite, ok := body.(*are_hub.Invite); ok {
+		r = r.WithContext(invite.ToCtx(r.Context()))
+	}
+
+	return h(w, r)
+}
+
+// Is the token sent once and only its hash stored?
+// Can invitations be redeemed for sessions until they are used up?
+func TestInviteRedeem(t *testing.T) {
+	invites := make(map[string]*are_hub.Invite)
+	var sessions []are_hub.Session
+
+	controller := newTestInvite(invites, &sessions)
+	invite := are_hub.NewInvite(are_hub.ROLE_SUBSCRIBER, time.Now().Add(time.Hour), 1)
+
+	w := httptest.NewRecorder()
+
+	if e := callInvite(controller.Store, invite, w); e != nil {
+		t.Fatal(e)
+	}
+
+	created := are_hub.Invite{}
+
+	if e := json.NewDecoder(w.Body).Decode(&created); e != nil {
+		t.Fatal(e)
+	}
+
+	if !strings.HasPrefix(created.Token, are_hub.INVITE_PREFIX) || created.ChannelID != testChannelID {
+		t.Fatalf("Expected: an invitation token of %s. Actual: %+v.", testChannelID, created)
+	}
+
+	if stored := invites[created.ID]; stored.TokenHash != hash.Token(created.Token) {
+		t.Errorf("Expected: the token's hash stored. Actual: %+v.", stored)
+	}
+
+	redeem := map[string]string{"token": created.Token}
+	w = httptest.NewRecorder()
+
+	if e := callInvite(controller.Redeem, redeem, w); e != nil {
+		t.Fatal(e)
+	}
+
+	session := are_hub.Session{}
+
+	if e := json.NewDecoder(w.Body).Decode(&session); e != nil {
+		t.Fatal(e)
+	}
+
+	if !strings.HasPrefix(session.Token, are_hub.SESSION_PREFIX) || session.Role != are_hub.ROLE_SUBSCRIBER {
+		t.Errorf("Expected: a subscriber session token. Actual: %+v.", session)
+	}
+
+	if len(sessions) != 1 || sessions[0].TokenHash != hash.Token(session.Token) {
+		t.Errorf("Expected: the session's token hash stored. Actual: %+v.", sessions)
+	}
+
+	// single-use
+	e := callInvite(controller.Redeem, redeem, httptest.NewRecorder())
+
+	if he, ok := e.(uf.HttpError); !ok || he.Code != http.StatusNotFound {
+		t.Errorf("Expected: %d. Actual: %v.", http.StatusNotFound, e)
+	}
+}
+
+// Are revoked invitations and the sessions redeemed from them deleted?
+// Are invitations of other channels left alone?
+func TestInviteDelete(t *testing.T) {
+	invites := map[string]*are_hub.Invite{
+		"1": {ChannelID: testChannelID, Common: are_hub.Common{ID: "1"}},
+		"2": {ChannelID: "other", Common: are_hub.Common{ID: "2"}},
+	}
+
+	sessions := []are_hub.Session{
+		{ChannelID: testChannelID, InviteID: "1", Role: are_hub.ROLE_PUBLISHER},
+		{ChannelID: "other", InviteID: "2", Role: are_hub.ROLE_SUBSCRIBER},
+	}
+
+	controller := newTestInvite(invites, &sessions)
+
+	for id, code := range map[string]int{"2": http.StatusNotFound, "3": http.StatusNotFound, "1": http.StatusOK} {
+		e := callInvite(controller.Delete, nil, httptest.NewRecorder(), httprouter.Param{Key: "invite", Value: id})
+
+		if code == http.StatusOK {
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			continue
+		}
+
+		if he, ok := e.(uf.HttpError); !ok || he.Code != code {
+			t.Errorf("%s: Expected: %d. Actual: %v.", id, code, e)
+		}
+	}
+
+	if _, ok := invites["1"]; ok || len(invites) != 1 {
+		t.Errorf("Expected: only invitation 2. Actual: %+v.", invites)
+	}
+
+	if len(sessions) != 1 || sessions[0].InviteID != "2" {
+		t.Errorf("Expected: only the session of invitation 2. Actual: %+v.", sessions)
+	}
+}
+
+// Are session tokens accepted in place of the password by the roles they
+// permit?
+func TestTelemetrySession(t *testing.T) {
+	hub := newTestHub(t)
+	hub.sessions[hash.Token("ses_sub")] = are_hub.Session{ChannelID: testChannelID, Role: are_hub.ROLE_SUBSCRIBER}
+	hub.sessions[hash.Token("ses_pub")] = are_hub.Session{ChannelID: testChannelID, Role: are_hub.ROLE_PUBLISHER}
+
+	conn := hub.subscribe(t, testChannelID, "ses_sub")
+
+	if res := hub.publish(t, testChannelID, "ses_sub", `{}`); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusUnauthorized, res.StatusCode)
+	}
+
+	if res := hub.publish(t, testChannelID, "ses_pub", `{"speedKmh":212.5}`); res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+
+	if msg := readResponse(t, conn); msg.Status != WS_OK {
+		t.Fatalf("Expected: %d. Actual: %+v.", WS_OK, msg)
+	}
+
+	if res := hub.publish(t, testChannelID, "ses_expired", `{}`); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusUnauthorized, res.StatusCode)
+	}
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+// Controller that retrieves the laps completed on channels.
+type Lap struct {
+	laps are_hub.LapRepo
+}
+
+// Create a new lap controller.
+func NewLap(laps are_hub.LapRepo) Lap {
+	return Lap{laps}
+}
+
+// Get all laps completed on a specific channel by the channel's ID. Optionally
+// filtered by the "session" query parameter.
+func (l Lap) Index(w http.ResponseWriter, r *http.Request) error {
+	h := w.Header()
+
+	h.Set("Access-Control-Allow-Methods", h.Get("Allow"))
+	h.Set("Access-Control-Allow-Origin", "*")
+
+	session := r.URL.Query().Get("session")
+	laps, e := l.laps.FindChannelID(r.Context(), uf.GetParam(r, "id"), session)
+
+	if e != nil {
+		return e
+	}
+
+	if laps == nil {
+		// send an empty array rather than null
+		laps = []are_hub.Lap{}
+	}
+
+	return uf.SendJSON(w, laps)
+}
+
+// Find a specific lap by its ID.
+func (l Lap) Show(w http.ResponseWriter, r *http.Request) error {
+	lap, e := l.laps.FindID(r.Context(), uf.GetParam(r, "lap"))
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound(e.Error())
+		}
+
+		return e
+	}
+
+	// the lap must belong to the channel in the URL
+	if lap.ChannelID != uf.GetParam(r, "id") {
+		return uf.NotFound("No lap matching: " + uf.GetParam(r, "lap"))
+	}
+
+	return uf.SendJSON(w, lap)
+}
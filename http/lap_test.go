@@ -0,0 +1,161 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/mock"
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Test data.
+var laps []are_hub.Lap = []are_hub.Lap{
+	{ChannelID: "1", Session: "a", Number: 1, Time: 104230, Valid: true},
+	{ChannelID: "1", Session: "b", Number: 1, Time: 103870, Valid: false},
+	{ChannelID: "2", Session: "c", Number: 1, Time: 98550, Valid: true},
+}
+
+// Does it call repo.FindChannelID with the channel ID and session?
+// Is the content type set to application/json?
+// Does it return the laps?
+func TestLapIndex(t *testing.T) {
+	var id, session string
+
+	fn := func(_ context.Context, i, s string) ([]are_hub.Lap, error) {
+		var found []are_hub.Lap
+
+		id, session = i, s
+
+		for _, l := range laps {
+			if l.ChannelID == i && (len(s) == 0 || l.Session == s) {
+				found = append(found, l)
+			}
+		}
+
+		return found, nil
+	}
+
+	repo := &mock.LapRepo{FindChannelIDFunc: fn}
+	controller := NewLap(repo)
+
+	p := httprouter.Param{Key: "id", Value: "1"}
+	req, e := http.NewRequest(http.MethodGet, "/channel/1/laps?session=b", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	uf.EmbedParams(req, p)
+
+	w := httptest.NewRecorder()
+	e = controller.Index(w, req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !repo.FindChannelIDCalled {
+		t.Error("Did not call repo.FindChannelID")
+	}
+
+	if id != "1" || session != "b" {
+		t.Errorf("Expected: 1, b. Actual: %s, %s.", id, session)
+	}
+
+	res := w.Result()
+
+	checkCT(res, t)
+
+	defer res.Body.Close()
+	body, e := io.ReadAll(res.Body)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var received []are_hub.Lap
+	e = json.Unmarshal(body, &received)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if len(received) != 1 || received[0].Time != laps[1].Time {
+		t.Fatalf("Expected: [%+v]. Actual: %+v.", laps[1], received)
+	}
+}
+
+// Does it call repo.FindID?
+// Does it return the lap?
+// Does it return a 404 Not Found error for an invalid ID or a lap on another channel?
+func TestLapShow(t *testing.T) {
+	fn := func(_ context.Context, id string) (*are_hub.Lap, error) {
+		switch id {
+		case "1":
+			return &laps[0], nil
+		case "3":
+			return &laps[2], nil
+		}
+
+		return nil, are_hub.NewNoObjectsFound("laps", "id == "+id)
+	}
+
+	repo := &mock.LapRepo{FindIDFunc: fn}
+	controller := NewLap(repo)
+
+	params := []httprouter.Param{{Key: "id", Value: "1"}, {Key: "lap", Value: "1"}}
+	req, e := http.NewRequest(http.MethodGet, "/channel/1/laps/1", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	uf.EmbedParams(req, params...)
+
+	w := httptest.NewRecorder()
+	e = controller.Show(w, req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !repo.FindIDCalled {
+		t.Error("Did not call repo.FindID")
+	}
+
+	res := w.Result()
+
+	checkCT(res, t)
+
+	defer res.Body.Close()
+	body, e := io.ReadAll(res.Body)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	received := are_hub.Lap{}
+	e = json.Unmarshal(body, &received)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if received.Time != laps[0].Time {
+		t.Fatalf("Expected: %+v. Actual: %+v.", laps[0], received)
+	}
+
+	// non-existent lap
+	params[1].Value = "-1"
+	test404(t, http.MethodGet, "/channel/1/laps/-1", nil, controller.Show, params...)
+
+	// lap belonging to another channel
+	params[1].Value = "3"
+	test404(t, http.MethodGet, "/channel/1/laps/3", nil, controller.Show, params...)
+}
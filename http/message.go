@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+// Controller that retrieves chat messages and annotations posted to channels.
+type Message struct {
+	messages are_hub.MessageRepo
+}
+
+// Create a new message controller.
+func NewMessage(messages are_hub.MessageRepo) Message {
+	return Message{messages}
+}
+
+// Get all messages posted to a specific channel by the channel's ID.
+func (m Message) Index(w http.ResponseWriter, r *http.Request) error {
+	h := w.Header()
+
+	h.Set("Access-Control-Allow-Methods", h.Get("Allow"))
+	h.Set("Access-Control-Allow-Origin", "*")
+
+	messages, e := m.messages.FindChannelID(r.Context(), uf.GetParam(r, "id"))
+
+	if e != nil {
+		return e
+	}
+
+	if messages == nil {
+		// send an empty array rather than null
+		messages = []are_hub.Message{}
+	}
+
+	return uf.SendJSON(w, messages)
+}
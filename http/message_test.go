@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/mock"
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Test data.
+var messages []are_hub.Message = []are_hub.Message{
+	{ChannelID: "1", Author: "Race engineer", Body: "lap 23: lockup T1", Seq: 1104},
+	{ChannelID: "1", Author: "Strategist", Body: "box this lap", Seq: 1290},
+	{ChannelID: "2", Author: "Race engineer", Body: "blue flag", Seq: 52},
+}
+
+// Does it call repo.FindChannelID with the correct ID?
+// Is the content type set to application/json?
+// Does it only return messages posted to the channel?
+func TestMessageIndex(t *testing.T) {
+	var id string
+
+	// mock FindChannelID function
+	fn := func(_ context.Context, str string) ([]are_hub.Message, error) {
+		var found []are_hub.Message
+
+		id = str
+
+		for _, m := range messages {
+			if m.ChannelID == str {
+				found = append(found, m)
+			}
+		}
+
+		return found, nil
+	}
+
+	// create the mock repo and controller
+	repo := &mock.MessageRepo{FindChannelIDFunc: fn}
+	controller := NewMessage(repo)
+
+	// create a mock request
+	p := httprouter.Param{Key: "id", Value: "1"}
+	req, e := http.NewRequest(http.MethodGet, "/channel/"+p.Value+"/messages", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	uf.EmbedParams(req, p)
+
+	// create a response recorder and run the controller method
+	w := httptest.NewRecorder()
+	e = controller.Index(w, req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// check if the repo was hit with the correct ID
+	if !repo.FindChannelIDCalled {
+		t.Error("Did not call repo.FindChannelID")
+	}
+
+	if id != p.Value {
+		t.Errorf("Expected: %s. Actual: %s.", p.Value, id)
+	}
+
+	res := w.Result()
+
+	// ensure the content type is application/json
+	checkCT(res, t)
+
+	// extract the body and confirm only the channel's messages were returned
+	defer res.Body.Close()
+	body, e := io.ReadAll(res.Body)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var received []are_hub.Message
+	e = json.Unmarshal(body, &received)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if l := len(received); l != 2 {
+		t.Fatalf("Expected: 2 messages. Actual: %d.", l)
+	}
+
+	for i, m := range received {
+		if m.Body != messages[i].Body || m.Seq != messages[i].Seq {
+			t.Fatalf("Expected: %+v. Actual: %+v.", messages[i], m)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/blacksfk/are_hub/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	liveChannelsDesc = prometheus.NewDesc(
+		metrics.NAMESPACE+"_live_channels",
+		"Telemetry channels live on the hub.",
+		nil, nil,
+	)
+
+	subscribersDesc = prometheus.NewDesc(
+		metrics.NAMESPACE+"_subscribers",
+		"Subscribers connected to a live channel.",
+		[]string{"channel"}, nil,
+	)
+)
+
+// Controller exposing metrics in the Prometheus text exposition format.
+type Metrics struct {
+	handler http.Handler
+}
+
+// Create a new metrics controller exposing the metrics gathered by g.
+func NewMetrics(g prometheus.Gatherer) Metrics {
+	return Metrics{promhttp.HandlerFor(g, promhttp.HandlerOpts{})}
+}
+
+// Write the gathered metrics.
+func (m Metrics) Index(w http.ResponseWriter, r *http.Request) error {
+	m.handler.ServeHTTP(w, r)
+
+	return nil
+}
+
+// Describe implements prometheus.Collector.
+func (ts *TelemetryServer) Describe(ch chan<- *prometheus.Desc) {
+	ch <- liveChannelsDesc
+	ch <- subscribersDesc
+}
+
+// Collect implements prometheus.Collector by reporting the live channels and
+// the subscribers connected to each.
+func (ts *TelemetryServer) Collect(ch chan<- prometheus.Metric) {
+	channels := ts.channels.all()
+	ch <- prometheus.MustNewConstMetric(liveChannelsDesc, prometheus.GaugeValue, float64(len(channels)))
+
+	for _, tc := range channels {
+		tc.subMtx.Lock()
+		subs := len(tc.subs)
+		tc.subMtx.Unlock()
+
+		ch <- prometheus.MustNewConstMetric(subscribersDesc, prometheus.GaugeValue, float64(subs), tc.ID)
+	}
+}
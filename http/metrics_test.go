@@ -0,0 +1,116 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"nhooyr.io/websocket"
+)
+
+// Does it expose the live channels and their subscribers in the text exposition format?
+// Are published frames, bytes, password verifications, and handshake failures counted?
+func TestMetricsIndex(t *testing.T) {
+	hub := newTestHub(t)
+	frames := testutil.ToFloat64(metrics.FramesPublished.WithLabelValues(testChannelID))
+	in := testutil.ToFloat64(metrics.BytesIn.WithLabelValues(testChannelID))
+	out := testutil.ToFloat64(metrics.BytesOut.WithLabelValues(testChannelID))
+	failures := testutil.ToFloat64(metrics.HandshakeFailures.WithLabelValues("4401"))
+
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+	hub.publish(t, testChannelID, testChannelPassword, `{"speedKmh":212.5}`)
+	msg := readResponse(t, conn)
+
+	if d := testutil.ToFloat64(metrics.FramesPublished.WithLabelValues(testChannelID)) - frames; d != 1 {
+		t.Fatalf("Expected: 1 frame published. Actual: %v.", d)
+	}
+
+	if d := testutil.ToFloat64(metrics.BytesIn.WithLabelValues(testChannelID)) - in; d != 18 {
+		t.Fatalf("Expected: 18 bytes in. Actual: %v.", d)
+	}
+
+	// the subscriber has received the frame so it has been counted
+	if d := testutil.ToFloat64(metrics.BytesOut.WithLabelValues(testChannelID)) - out; d < float64(len(msg.Data)) {
+		t.Fatalf("Expected: at least %d bytes out. Actual: %v.", len(msg.Data), d)
+	}
+
+	// subscribe with the wrong password
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	url := "ws" + strings.TrimPrefix(hub.URL, "http") + "/subscribe/" + testChannelID
+	bad, _, e := websocket.Dial(ctx, url, nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	readResponse(t, bad)
+	bad.Write(ctx, websocket.MessageText, []byte("lol123"))
+
+	if _, _, e = bad.Read(ctx); websocket.CloseStatus(e) != WS_ERROR_UNAUTHORISED {
+		t.Fatalf("Expected: %d. Actual: %v.", WS_ERROR_UNAUTHORISED, e)
+	}
+
+	// the failure is counted after the connection is closed
+	deadline := time.Now().Add(time.Second)
+
+	for testutil.ToFloat64(metrics.HandshakeFailures.WithLabelValues("4401"))-failures != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Handshake failure not counted")
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(hub.ts)
+
+	controller := NewMetrics(prometheus.Gatherers{metrics.Registry, reg})
+	req, e := http.NewRequest(http.MethodGet, "/metrics", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	w := httptest.NewRecorder()
+	e = controller.Index(w, req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("Expected: text/plain. Actual: %s.", ct)
+	}
+
+	body, e := io.ReadAll(res.Body)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	expected := []string{
+		"are_hub_live_channels 1\n",
+		`are_hub_subscribers{channel="` + testChannelID + `"} 1` + "\n",
+		`are_hub_password_verify_duration_seconds_count{result="match"}`,
+		`are_hub_password_verify_duration_seconds_count{result="mismatch"}`,
+		`are_hub_websocket_handshake_failures_total{code="4401"}`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(string(body), line) {
+			t.Fatalf("Expected: %s in:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Package auth implements microframework.Middleware authorising requests.
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	uf "github.com/blacksfk/microframework"
+)
+
+// Authorises requests to the admin API with a bearer token.
+type Admin struct {
+	token []byte
+}
+
+// Create a new admin authoriser accepting token. Every request is forbidden if
+// token is empty so that the admin API is disabled unless configured.
+func NewAdmin(token string) Admin {
+	return Admin{[]byte(token)}
+}
+
+// Check that the request carries the admin token in the Authorization header
+// (Authorization: Bearer <token>).
+func (a Admin) Authorise(r *http.Request) error {
+	if len(a.token) == 0 {
+		return uf.Forbidden("Admin API disabled.")
+	}
+
+	auth := r.Header.Get("Authorization")
+
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return uf.Unauthorized("Admin token required.")
+	}
+
+	token := []byte(strings.TrimPrefix(auth, "Bearer "))
+
+	// compare in constant time so that the token cannot be guessed by timing
+	if subtle.ConstantTimeCompare(token, a.token) != 1 {
+		return uf.Unauthorized("Incorrect credentials.")
+	}
+
+	return nil
+}
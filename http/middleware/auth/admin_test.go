@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	uf "github.com/blacksfk/microframework"
+)
+
+func TestAuthorise(t *testing.T) {
+	tests := []struct {
+		token  string
+		header string
+		code   int
+	}{
+		{"", "Bearer ", http.StatusForbidden},
+		{"", "Bearer lol123", http.StatusForbidden},
+		{"s3cret", "", http.StatusUnauthorized},
+		{"s3cret", "s3cret", http.StatusUnauthorized},
+		{"s3cret", "Bearer lol123", http.StatusUnauthorized},
+		{"s3cret", "Bearer s3cret", 0},
+	}
+
+	for _, test := range tests {
+		r, e := http.NewRequest(http.MethodGet, "/admin/channels", nil)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if len(test.header) > 0 {
+			r.Header.Set("Authorization", test.header)
+		}
+
+		e = NewAdmin(test.token).Authorise(r)
+
+		if test.code == 0 {
+			if e != nil {
+				t.Errorf("%q: unexpected error: %v", test.header, e)
+			}
+
+			continue
+		}
+
+		he, ok := e.(uf.HttpError)
+
+		if !ok || he.Code != test.code {
+			t.Errorf("%q: expected: %d. Actual: %v.", test.header, test.code, e)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package validate
+
+import (
+	"net/http"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/go-playground/validator/v10"
+)
+
+type AlertRule struct {
+	request
+}
+
+// Create a new alert rule validator which exports methods matching the
+// microframework.Middleware signature.
+func NewAlertRule() AlertRule {
+	return AlertRule{request{validator.New()}}
+}
+
+type alertRuleStore struct {
+	Name     string `validate:"required"`
+	Field    string `validate:"required"`
+	Operator string `validate:"required,oneof=> >= < <="`
+
+	// pointer so that a threshold of 0 can be distinguished from a missing threshold
+	Threshold  *float64 `validate:"required"`
+	Unit       string
+	Duration   int64   `validate:"gte=0"`
+	Hysteresis float64 `validate:"gte=0"`
+}
+
+// Validate the request body with rules defined above. If successful,
+// create an are_hub.AlertRule and attach it to the request's context.
+func (a AlertRule) Store(r *http.Request) error {
+	temp := alertRuleStore{}
+	e := a.bodyStruct(r, &temp)
+
+	if e != nil {
+		return e
+	}
+
+	// create an alert rule (domain type) out of the validation object
+	rule := are_hub.NewAlertRule(temp.Name, temp.Field, temp.Operator, *temp.Threshold)
+	rule.Unit = temp.Unit
+	rule.Duration = temp.Duration
+	rule.Hysteresis = temp.Hysteresis
+
+	// insert the created rule into r's context
+	*r = *r.WithContext(rule.ToCtx(r.Context()))
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package validate
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+// Does it store valid alert rules (including a threshold of 0) in the request's context?
+// Does it reject unknown operators and missing thresholds?
+func TestAlertRuleStore(t *testing.T) {
+	bodies := map[string]int{
+		`{"name":"Wet","field":"graphics.rainIntensity","operator":">","threshold":0}`:            http.StatusOK,
+		`{"name":"Hot","field":"physics.brakeTemp.0","operator":"!=","threshold":800}`:            http.StatusBadRequest,
+		`{"name":"Hot","field":"physics.brakeTemp.0","operator":">"}`:                             http.StatusBadRequest,
+		`{"name":"Hot","field":"physics.brakeTemp.0","operator":">","threshold":1,"duration":-1}`: http.StatusBadRequest,
+	}
+
+	v := NewAlertRule()
+
+	for body, code := range bodies {
+		r, e := http.NewRequest(http.MethodPost, "/channel/1/rules", bytes.NewReader([]byte(body)))
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		r.Header.Set("Content-Type", "application/json")
+		e = v.Store(r)
+
+		if code == http.StatusOK {
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if _, e = are_hub.AlertRuleFromCtx(r.Context()); e != nil {
+				t.Fatal(e)
+			}
+
+			continue
+		}
+
+		he, ok := e.(uf.HttpError)
+
+		if !ok || he.Code != code {
+			t.Fatalf("%s. Expected: %d. Actual: %v.", body, code, e)
+		}
+	}
+}
@@ -1,27 +1,55 @@
 package validate
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
 	"github.com/go-playground/validator/v10"
 )
 
 type Channel struct {
 	request
+
+	// maximum subscriber capacity and buffering channels may be given
+	max are_hub.Limits
 }
 
 // Create a new channel validator which exports methods matching the
-// microframework.Middleware signature.
-func NewChannel() Channel {
+// microframework.Middleware signature. Channels may not exceed the limits
+// in max.
+func NewChannel(max are_hub.Limits) Channel {
 	// dependency injection? never heard of it...
-	return Channel{request{validator.New()}}
+	return Channel{request{validator.New()}, max}
 }
 
 type channelStore struct {
 	Name            string `validate:"required"`
 	Password        string `validate:"required,eqfield=ConfirmPassword"`
 	ConfirmPassword string `validate:"required"`
+	Schema          string `validate:"omitempty,oneof=acc"`
+
+	// car numbers of team channels
+	Cars []string `validate:"omitempty,unique,dive,required"`
+
+	// zero for the server's defaults
+	MaxSubscribers int `validate:"gte=0"`
+	BufferLength   int `validate:"gte=0"`
+
+	// empty for the server's default
+	SlowPolicy string `validate:"omitempty,oneof=disconnect drop-oldest drop-newest conflate block"`
+
+	// empty for private
+	Visibility string `validate:"omitempty,oneof=private unlisted public"`
+
+	// empty sim, car model, and track are filled from the telemetry
+	Sim      string   `validate:"max=100"`
+	CarModel string   `validate:"max=100"`
+	Track    string   `validate:"max=100"`
+	Event    string   `validate:"max=100"`
+	Team     string   `validate:"max=100"`
+	Tags     []string `validate:"omitempty,max=20,unique,dive,required,max=32"`
 }
 
 // Validate the request body with rules defined above. If successful,
@@ -34,11 +62,122 @@ func (c Channel) Store(r *http.Request) error {
 		return e
 	}
 
+	// the maximums are configured so they can't be expressed as tags
+	if temp.MaxSubscribers > c.max.MaxSubscribers {
+		return exceeds("channelStore.MaxSubscribers", c.max.MaxSubscribers, temp.MaxSubscribers)
+	}
+
+	if temp.BufferLength > c.max.BufferLength {
+		return exceeds("channelStore.BufferLength", c.max.BufferLength, temp.BufferLength)
+	}
+
 	// create a channel (domain type) out of the validation object
 	channel := are_hub.NewChannel(temp.Name, temp.Password)
+	channel.Schema = temp.Schema
+	channel.Cars = temp.Cars
+	channel.Limits = are_hub.Limits{
+		MaxSubscribers: temp.MaxSubscribers,
+		BufferLength:   temp.BufferLength,
+		SlowPolicy:     temp.SlowPolicy,
+	}
+	channel.Visibility = temp.Visibility
+	channel.Metadata = are_hub.Metadata{
+		Sim:      temp.Sim,
+		CarModel: temp.CarModel,
+		Track:    temp.Track,
+		Event:    temp.Event,
+		Team:     temp.Team,
+		Tags:     temp.Tags,
+	}
 
 	// insert the create channel into r's context
 	*r = *r.WithContext(channel.ToCtx(r.Context()))
 
 	return nil
 }
+
+// Fields of a channel to update. Omitted fields are left as they are.
+type channelUpdate struct {
+	Name   *string   `validate:"omitempty,min=1"`
+	Schema *string   `validate:"omitempty,len=0|oneof=acc"`
+	Cars   *[]string `validate:"omitempty,unique,dive,required"`
+
+	MaxSubscribers *int    `validate:"omitempty,gte=0"`
+	BufferLength   *int    `validate:"omitempty,gte=0"`
+	SlowPolicy     *string `validate:"omitempty,len=0|oneof=disconnect drop-oldest drop-newest conflate block"`
+	Visibility     *string `validate:"omitempty,len=0|oneof=private unlisted public"`
+
+	Sim      *string   `validate:"omitempty,max=100"`
+	CarModel *string   `validate:"omitempty,max=100"`
+	Track    *string   `validate:"omitempty,max=100"`
+	Event    *string   `validate:"omitempty,max=100"`
+	Team     *string   `validate:"omitempty,max=100"`
+	Tags     *[]string `validate:"omitempty,max=20,unique,dive,required,max=32"`
+}
+
+// Validate the request body with the rules defined above. If successful,
+// create an are_hub.ChannelPatch and attach it to the request's context.
+func (c Channel) Update(r *http.Request) error {
+	temp := channelUpdate{}
+	e := c.bodyStruct(r, &temp)
+
+	if e != nil {
+		return e
+	}
+
+	if temp.MaxSubscribers != nil && *temp.MaxSubscribers > c.max.MaxSubscribers {
+		return exceeds("channelUpdate.MaxSubscribers", c.max.MaxSubscribers, *temp.MaxSubscribers)
+	}
+
+	if temp.BufferLength != nil && *temp.BufferLength > c.max.BufferLength {
+		return exceeds("channelUpdate.BufferLength", c.max.BufferLength, *temp.BufferLength)
+	}
+
+	patch := &are_hub.ChannelPatch{
+		Name:           temp.Name,
+		Schema:         temp.Schema,
+		Cars:           temp.Cars,
+		MaxSubscribers: temp.MaxSubscribers,
+		BufferLength:   temp.BufferLength,
+		SlowPolicy:     temp.SlowPolicy,
+		Visibility:     temp.Visibility,
+		Sim:            temp.Sim,
+		CarModel:       temp.CarModel,
+		Track:          temp.Track,
+		Event:          temp.Event,
+		Team:           temp.Team,
+		Tags:           temp.Tags,
+	}
+
+	*r = *r.WithContext(patch.ToCtx(r.Context()))
+
+	return nil
+}
+
+type channelPassword struct {
+	CurrentPassword string `validate:"required"`
+	Password        string `validate:"required,eqfield=ConfirmPassword"`
+	ConfirmPassword string `validate:"required"`
+}
+
+// Validate the request body with the rules defined above. If successful,
+// create an are_hub.PasswordChange and attach it to the request's context.
+func (c Channel) Password(r *http.Request) error {
+	temp := channelPassword{}
+	e := c.bodyStruct(r, &temp)
+
+	if e != nil {
+		return e
+	}
+
+	change := &are_hub.PasswordChange{Current: temp.CurrentPassword, New: temp.Password}
+	*r = *r.WithContext(change.ToCtx(r.Context()))
+
+	return nil
+}
+
+// Create a 400 Bad Request error in the same format as validation errors for a
+// field (eg. "channelStore.BufferLength") exceeding max.
+func exceeds(field string, max, value int) error {
+	return uf.BadRequest(fmt.Sprintf("%s: expected: lte=%d, received: %d. ", field, max, value))
+}
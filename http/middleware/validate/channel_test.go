@@ -10,6 +10,9 @@ import (
 	uf "github.com/blacksfk/microframework"
 )
 
+// Maximum channel limits used by the tests.
+var testMax = are_hub.Limits{MaxSubscribers: 100, BufferLength: 64}
+
 // Does it store valid channels in the request's context?
 func TestStoreValid(t *testing.T) {
 	// create mock data
@@ -37,7 +40,7 @@ func TestStoreValid(t *testing.T) {
 	r.Header.Set("Content-Type", "application/json")
 
 	// create a validator and run the validation method
-	v := NewChannel()
+	v := NewChannel(testMax)
 	e = v.Store(r)
 
 	if e != nil {
@@ -75,7 +78,7 @@ func TestStoreBadCT(t *testing.T) {
 	}
 
 	// create a validator and run the validation method with no content-type set
-	v := NewChannel()
+	v := NewChannel(testMax)
 	e = v.Store(r)
 
 	// an error should be returned
@@ -119,7 +122,7 @@ func TestStoreInvalid(t *testing.T) {
 	r.Header.Set("Content-Type", "application/json")
 
 	// create a validator and run the validation method
-	v := NewChannel()
+	v := NewChannel(testMax)
 	e = v.Store(r)
 
 	// an error should be returned
@@ -164,7 +167,7 @@ func TestStoreMismatch(t *testing.T) {
 	r.Header.Set("Content-Type", "application/json")
 
 	// create a validator and run the store method
-	v := NewChannel()
+	v := NewChannel(testMax)
 	e = v.Store(r)
 
 	// an error should be returned
@@ -196,7 +199,7 @@ func TestStoreMalformed(t *testing.T) {
 	r.Header.Set("Content-Type", "application/json")
 
 	// create a validator and run the validation method
-	v := NewChannel()
+	v := NewChannel(testMax)
 	e = v.Store(r)
 
 	// an error should be returned
@@ -214,3 +217,286 @@ func TestStoreMalformed(t *testing.T) {
 		t.Fatalf("Expected: %d. Actual: %d.", http.StatusBadRequest, he.Code)
 	}
 }
+
+// Does it reject channels with an unknown schema?
+func TestStoreUnknownSchema(t *testing.T) {
+	embedded := channelStore{
+		Name:            "Orange1 FFF",
+		Password:        "abc123",
+		ConfirmPassword: "abc123",
+		Schema:          "iracing",
+	}
+
+	body, e := json.Marshal(embedded)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	r, e := http.NewRequest(http.MethodPost, "/channel", bytes.NewReader(body))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	r.Header.Set("Content-Type", "application/json")
+
+	v := NewChannel(testMax)
+	e = v.Store(r)
+
+	if e == nil {
+		t.Fatal("Expected: 400 Bad Request error. Actual: nil.")
+	}
+
+	he, ok := e.(uf.HttpError)
+
+	if !ok {
+		t.Fatalf("Expected: 400 Bad Request error. Actual: %s.", e)
+	}
+
+	if he.Code != http.StatusBadRequest {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusBadRequest, he.Code)
+	}
+
+	// the known schema should be accepted and stored on the channel
+	embedded.Schema = "acc"
+	body, e = json.Marshal(embedded)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	r, e = http.NewRequest(http.MethodPost, "/channel", bytes.NewReader(body))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	r.Header.Set("Content-Type", "application/json")
+	e = v.Store(r)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	channel, e := are_hub.ChannelFromCtx(r.Context())
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if channel.Schema != "acc" {
+		t.Fatalf("Expected: acc. Actual: %s.", channel.Schema)
+	}
+}
+
+// Does it store the cars of team channels? Does it reject duplicate and empty car numbers?
+func TestStoreCars(t *testing.T) {
+	bodies := map[string]int{
+		`{"name":"WRT","password":"abc123","confirmPassword":"abc123","cars":["31","32"]}`: http.StatusOK,
+		`{"name":"WRT","password":"abc123","confirmPassword":"abc123","cars":["31","31"]}`: http.StatusBadRequest,
+		`{"name":"WRT","password":"abc123","confirmPassword":"abc123","cars":[""]}`:        http.StatusBadRequest,
+	}
+
+	v := NewChannel(testMax)
+
+	for body, code := range bodies {
+		r, e := http.NewRequest(http.MethodPost, "/channel", bytes.NewReader([]byte(body)))
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		r.Header.Set("Content-Type", "application/json")
+		e = v.Store(r)
+
+		if code == http.StatusOK {
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			channel, e := are_hub.ChannelFromCtx(r.Context())
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if len(channel.Cars) != 2 {
+				t.Fatalf("Expected: 2 cars. Actual: %v.", channel.Cars)
+			}
+
+			continue
+		}
+
+		he, ok := e.(uf.HttpError)
+
+		if !ok || he.Code != code {
+			t.Fatalf("%s. Expected: %d. Actual: %v.", body, code, e)
+		}
+	}
+}
+
+// Does it store the subscriber capacity, buffering, and slow policy? Does it
+// reject limits exceeding the maximums and unknown policies?
+func TestStoreLimits(t *testing.T) {
+	bodies := map[string]int{
+		`{"name":"League","password":"abc123","confirmPassword":"abc123","maxSubscribers":100,"bufferLength":32,"slowPolicy":"conflate"}`: http.StatusOK,
+		`{"name":"League","password":"abc123","confirmPassword":"abc123","maxSubscribers":101}`:                                           http.StatusBadRequest,
+		`{"name":"League","password":"abc123","confirmPassword":"abc123","bufferLength":65}`:                                              http.StatusBadRequest,
+		`{"name":"League","password":"abc123","confirmPassword":"abc123","maxSubscribers":-1}`:                                            http.StatusBadRequest,
+	}
+
+	v := NewChannel(testMax)
+
+	for body, code := range bodies {
+		r, e := http.NewRequest(http.MethodPost, "/channel", bytes.NewReader([]byte(body)))
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		r.Header.Set("Content-Type", "application/json")
+		e = v.Store(r)
+
+		if code == http.StatusOK {
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			channel, e := are_hub.ChannelFromCtx(r.Context())
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if channel.MaxSubscribers != 100 || channel.BufferLength != 32 || channel.SlowPolicy != are_hub.POLICY_CONFLATE {
+				t.Fatalf("Expected: 100 conflated subscribers with a buffer of 32. Actual: %+v.", channel.Limits)
+			}
+
+			continue
+		}
+
+		he, ok := e.(uf.HttpError)
+
+		if !ok || he.Code != code {
+			t.Fatalf("%s. Expected: %d. Actual: %v.", body, code, e)
+		}
+	}
+}
+
+// Does it only patch the fields provided? Does it reject invalid fields?
+func TestUpdate(t *testing.T) {
+	bodies := map[string]int{
+		`{"name":"League"}`:                               http.StatusOK,
+		`{"schema":"","maxSubscribers":0}`:                http.StatusOK,
+		`{"cars":["31","32"],"slowPolicy":"drop-oldest"}`: http.StatusOK,
+		`{"name":""}`:                                     http.StatusBadRequest,
+		`{"schema":"iracing"}`:                            http.StatusBadRequest,
+		`{"cars":["31","31"]}`:                            http.StatusBadRequest,
+		`{"maxSubscribers":101}`:                          http.StatusBadRequest,
+		`{"bufferLength":-1}`:                             http.StatusBadRequest,
+		`{"slowPolicy":"ignore"}`:                         http.StatusBadRequest,
+		`{"visibility":"public"}`:                         http.StatusOK,
+		`{"visibility":"hidden"}`:                         http.StatusBadRequest,
+		`{"event":"Spa 24 Hours","tags":["endurance"]}`:   http.StatusOK,
+		`{"track":"","tags":[]}`:                          http.StatusOK,
+		`{"tags":["gt3","gt3"]}`:                          http.StatusBadRequest,
+		`{"tags":[""]}`:                                   http.StatusBadRequest,
+	}
+
+	v := NewChannel(testMax)
+
+	for body, code := range bodies {
+		r, e := http.NewRequest(http.MethodPatch, "/channel/1", bytes.NewReader([]byte(body)))
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		r.Header.Set("Content-Type", "application/json")
+		e = v.Update(r)
+
+		if code == http.StatusOK {
+			if e != nil {
+				t.Fatalf("%s. %v", body, e)
+			}
+
+			continue
+		}
+
+		he, ok := e.(uf.HttpError)
+
+		if !ok || he.Code != code {
+			t.Fatalf("%s. Expected: %d. Actual: %v.", body, code, e)
+		}
+	}
+
+	r, e := http.NewRequest(http.MethodPatch, "/channel/1", bytes.NewReader([]byte(`{"name":"League"}`)))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	r.Header.Set("Content-Type", "application/json")
+	e = v.Update(r)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	patch, e := are_hub.ChannelPatchFromCtx(r.Context())
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if patch.Name == nil || *patch.Name != "League" || patch.Schema != nil || patch.Cars != nil || patch.MaxSubscribers != nil || patch.Password != nil {
+		t.Errorf("Expected: only the name patched. Actual: %+v.", patch)
+	}
+}
+
+// Does it require the current password and a confirmed new password?
+func TestPassword(t *testing.T) {
+	bodies := map[string]int{
+		`{"currentPassword":"abc123","password":"def456","confirmPassword":"def456"}`: http.StatusOK,
+		`{"password":"def456","confirmPassword":"def456"}`:                            http.StatusBadRequest,
+		`{"currentPassword":"abc123","password":"def456","confirmPassword":"def457"}`: http.StatusBadRequest,
+	}
+
+	v := NewChannel(testMax)
+
+	for body, code := range bodies {
+		r, e := http.NewRequest(http.MethodPut, "/channel/1/password", bytes.NewReader([]byte(body)))
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		r.Header.Set("Content-Type", "application/json")
+		e = v.Password(r)
+
+		if code == http.StatusOK {
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			change, e := are_hub.PasswordChangeFromCtx(r.Context())
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if change.Current != "abc123" || change.New != "def456" {
+				t.Fatalf("Expected: abc123 changed to def456. Actual: %+v.", change)
+			}
+
+			continue
+		}
+
+		he, ok := e.(uf.HttpError)
+
+		if !ok || he.Code != code {
+			t.Fatalf("%s. Expected: %d. Actual: %v.", body, code, e)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package validate
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/go-playground/validator/v10"
+)
+
+type Invite struct {
+	request
+}
+
+// Create a new invitation validator which exports methods matching the
+// microframework.Middleware signature.
+func NewInvite() Invite {
+	return Invite{request{validator.New()}}
+}
+
+type inviteStore struct {
+	Role string `validate:"required,oneof=subscriber publisher"`
+
+	// must be in the future
+	ExpiresAt time.Time `validate:"required,gt"`
+
+	// invitations are single-use if omitted
+	MaxUses int `validate:"gte=0"`
+}
+
+// Validate the request body with rules defined above. If successful,
+// create an are_hub.Invite and attach it to the request's context.
+func (i Invite) Store(r *http.Request) error {
+	temp := inviteStore{}
+	e := i.bodyStruct(r, &temp)
+
+	if e != nil {
+		return e
+	}
+
+	if temp.MaxUses == 0 {
+		temp.MaxUses = 1
+	}
+
+	// create an invitation (domain type) out of the validation object
+	invite := are_hub.NewInvite(temp.Role, temp.ExpiresAt, temp.MaxUses)
+
+	// insert the created invitation into r's context
+	*r = *r.WithContext(invite.ToCtx(r.Context()))
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package validate
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+// Does it store valid invitations in the request's context?
+// Are invitations single-use unless stated otherwise?
+// Does it reject unknown roles, expiry times in the past, and negative uses?
+func TestInviteStore(t *testing.T) {
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	bodies := map[string]int{
+		`{"role":"subscriber","expiresAt":"` + future + `"}`:              http.StatusOK,
+		`{"role":"publisher","expiresAt":"` + future + `","maxUses":5}`:   http.StatusOK,
+		`{"role":"owner","expiresAt":"` + future + `"}`:                   http.StatusBadRequest,
+		`{"role":"subscriber","expiresAt":"` + past + `"}`:                http.StatusBadRequest,
+		`{"role":"subscriber"}`:                                           http.StatusBadRequest,
+		`{"role":"subscriber","expiresAt":"` + future + `","maxUses":-1}`: http.StatusBadRequest,
+	}
+
+	v := NewInvite()
+
+	for body, code := range bodies {
+		r, e := http.NewRequest(http.MethodPost, "/channel/1/invites", bytes.NewReader([]byte(body)))
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		r.Header.Set("Content-Type", "application/json")
+		e = v.Store(r)
+
+		if code == http.StatusOK {
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			invite, e := are_hub.InviteFromCtx(r.Context())
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if invite.MaxUses < 1 {
+				t.Errorf("%s. Expected: at least 1 use. Actual: %d.", body, invite.MaxUses)
+			}
+
+			continue
+		}
+
+		he, ok := e.(uf.HttpError)
+
+		if !ok || he.Code != code {
+			t.Fatalf("%s. Expected: %d. Actual: %v.", body, code, e)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package validate
+
+import (
+	"net/http"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/go-playground/validator/v10"
+)
+
+type Webhook struct {
+	request
+}
+
+// Create a new webhook validator which exports methods matching the
+// microframework.Middleware signature.
+func NewWebhook() Webhook {
+	return Webhook{request{validator.New()}}
+}
+
+type webhookStore struct {
+	URL    string   `validate:"required,url,startswith=http"`
+	Secret string   `validate:"required"`
+	Events []string `validate:"dive,oneof=channel.created channel.deleted channel.restored session.started publisher.offline alert.fired driver.swapped"`
+}
+
+// Validate the request body with rules defined above. If successful,
+// create an are_hub.Webhook and attach it to the request's context.
+func (w Webhook) Store(r *http.Request) error {
+	temp := webhookStore{}
+	e := w.bodyStruct(r, &temp)
+
+	if e != nil {
+		return e
+	}
+
+	// create a webhook (domain type) out of the validation object
+	webhook := are_hub.NewWebhook(temp.URL, temp.Secret, temp.Events)
+
+	// insert the created webhook into r's context
+	*r = *r.WithContext(webhook.ToCtx(r.Context()))
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package validate
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+// Does it store valid webhooks in the request's context?
+// Does it reject non-HTTP URLs, missing secrets, and unknown events?
+func TestWebhookStore(t *testing.T) {
+	bodies := map[string]int{
+		`{"url":"https://discord.com/api/webhooks/1","secret":"s3cret","events":["alert.fired"]}`: http.StatusOK,
+		`{"url":"ftp://example.com","secret":"s3cret"}`:                                           http.StatusBadRequest,
+		`{"url":"https://example.com"}`:                                                           http.StatusBadRequest,
+		`{"url":"https://example.com","secret":"s3cret","events":["lap.completed"]}`:              http.StatusBadRequest,
+	}
+
+	v := NewWebhook()
+
+	for body, code := range bodies {
+		r, e := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader([]byte(body)))
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		r.Header.Set("Content-Type", "application/json")
+		e = v.Store(r)
+
+		if code == http.StatusOK {
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if _, e = are_hub.WebhookFromCtx(r.Context()); e != nil {
+				t.Fatal(e)
+			}
+
+			continue
+		}
+
+		he, ok := e.(uf.HttpError)
+
+		if !ok || he.Code != code {
+			t.Fatalf("%s. Expected: %d. Actual: %v.", body, code, e)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	"sync"
+)
+
+// Live telemetry channels by ID. A channel going live is created exactly once
+// regardless of how many clients connect to it at the same time.
+type registry struct {
+	mtx      sync.Mutex
+	channels map[string]*telemetryChannel
+
+	// Channels being created by ID. Clients connecting while a channel is
+	// being created wait for it rather than creating another.
+	pending map[string]*creation
+}
+
+// A telemetry channel being created.
+type creation struct {
+	// Closed once tc and e are set.
+	done chan struct{}
+	tc   *telemetryChannel
+	e    error
+}
+
+func newRegistry() *registry {
+	return &registry{
+		channels: make(map[string]*telemetryChannel),
+		pending:  make(map[string]*creation),
+	}
+}
+
+// Get the live channel matching id.
+func (r *registry) get(id string) (*telemetryChannel, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	tc, ok := r.channels[id]
+
+	return tc, ok
+}
+
+// Get the live channel matching id or create it with create and make it live.
+// create is only called by one caller at a time per ID and is called without
+// holding the lock. Callers waiting for a channel being created receive the
+// same channel or error as the caller creating it.
+func (r *registry) getOrCreate(ctx context.Context, id string, create func(context.Context) (*telemetryChannel, error)) (*telemetryChannel, error) {
+	r.mtx.Lock()
+
+	if tc, ok := r.channels[id]; ok {
+		r.mtx.Unlock()
+
+		return tc, nil
+	}
+
+	if c, ok := r.pending[id]; ok {
+		r.mtx.Unlock()
+
+		select {
+		case <-c.done:
+			return c.tc, c.e
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c := &creation{done: make(chan struct{})}
+	r.pending[id] = c
+	r.mtx.Unlock()
+
+	c.tc, c.e = create(ctx)
+
+	r.mtx.Lock()
+	delete(r.pending, id)
+
+	if c.e == nil {
+		c.tc.open()
+		r.channels[id] = c.tc
+	}
+
+	r.mtx.Unlock()
+	close(c.done)
+
+	return c.tc, c.e
+}
+
+// Make tc live, replacing any live channel with the same ID.
+func (r *registry) add(tc *telemetryChannel) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	tc.open()
+	r.channels[tc.ID] = tc
+}
+
+// Remove the live channel matching id.
+func (r *registry) remove(id string) (*telemetryChannel, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	tc, ok := r.channels[id]
+	delete(r.channels, id)
+
+	return tc, ok
+}
+
+// Get the live channels.
+func (r *registry) all() []*telemetryChannel {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	channels := make([]*telemetryChannel, 0, len(r.channels))
+
+	for _, tc := range r.channels {
+		channels = append(channels, tc)
+	}
+
+	return channels
+}
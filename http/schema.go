@@ -0,0 +1,40 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/blacksfk/are_hub/acc"
+	uf "github.com/blacksfk/microframework"
+)
+
+// JSON Schemas of telemetry frames by schema name. See are_hub.Channel.Schema.
+var jsonSchemas = map[string][]byte{
+	acc.SCHEMA: acc.JSONSchema(),
+}
+
+// Controller that publishes the JSON Schemas frames are validated against for
+// client authors.
+type Schema struct{}
+
+// Create a new schema controller.
+func NewSchema() Schema {
+	return Schema{}
+}
+
+// Get a specific schema by its name.
+func (s Schema) Show(w http.ResponseWriter, r *http.Request) error {
+	h := w.Header()
+
+	h.Set("Access-Control-Allow-Methods", h.Get("Allow"))
+	h.Set("Access-Control-Allow-Origin", "*")
+
+	name := uf.GetParam(r, "name")
+	schema, ok := jsonSchemas[name]
+
+	if !ok {
+		return uf.NotFound("No schema named " + name)
+	}
+
+	return uf.SendJSON(w, json.RawMessage(schema))
+}
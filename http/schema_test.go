@@ -0,0 +1,65 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Is the content type set to application/json?
+// Does it return the requested schema?
+// Does it return a 404 Not Found error for an unknown schema?
+func TestSchemaShow(t *testing.T) {
+	controller := NewSchema()
+
+	p := httprouter.Param{Key: "name", Value: "acc"}
+	req, e := http.NewRequest(http.MethodGet, "/schema/"+p.Value, nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	uf.EmbedParams(req, p)
+
+	w := httptest.NewRecorder()
+	e = controller.Show(w, req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	res := w.Result()
+
+	// ensure the content type is application/json
+	checkCT(res, t)
+
+	defer res.Body.Close()
+	body, e := io.ReadAll(res.Body)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var schema struct {
+		ID string `json:"$id"`
+	}
+
+	e = json.Unmarshal(body, &schema)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if schema.ID != "are_hub/acc/v1" {
+		t.Fatalf("Expected: are_hub/acc/v1. Actual: %s.", schema.ID)
+	}
+
+	// check show returns 404 for an unknown schema
+	p = httprouter.Param{Key: "name", Value: "iracing"}
+	test404(t, http.MethodGet, "/schema/"+p.Value, nil, controller.Show, p)
+}
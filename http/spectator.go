@@ -0,0 +1,318 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/acc"
+	"github.com/blacksfk/are_hub/frame"
+	"github.com/blacksfk/are_hub/metrics"
+	uf "github.com/blacksfk/microframework"
+	"nhooyr.io/websocket"
+)
+
+const (
+	// Maximum number of spectators of a channel unless configured otherwise.
+	MAX_SPECTATORS = 500
+
+	// Minimum milliseconds between frames sent to spectators of each car
+	// unless configured otherwise.
+	SPECTATOR_INTERVAL = 1000
+)
+
+// Spectator capacity and downsampling unless configured otherwise.
+var defaultSpectatorLimits = SpectatorLimits{
+	MaxSpectators: MAX_SPECTATORS,
+	FrameInterval: SPECTATOR_INTERVAL,
+}
+
+// Fields of frames sent to spectators by schema name. Spectators of channels
+// whose schema is not listed receive no frames.
+var spectatorFields = map[string][]string{
+	acc.SCHEMA: acc.SpectatorFields,
+}
+
+// Capacity and downsampling of the spectators of unlisted and public
+// channels.
+type SpectatorLimits struct {
+	// Maximum number of spectators connected to a channel at once, separate
+	// from its subscribers.
+	MaxSpectators int `json:"maxSpectators"`
+
+	// Minimum milliseconds between frames sent to spectators of each car.
+	// Frames published in between are skipped.
+	FrameInterval int `json:"frameInterval"`
+}
+
+// Get l with zero values replaced by those of defaults.
+func (l SpectatorLimits) Or(defaults SpectatorLimits) SpectatorLimits {
+	if l.MaxSpectators == 0 {
+		l.MaxSpectators = defaults.MaxSpectators
+	}
+
+	if l.FrameInterval == 0 {
+		l.FrameInterval = defaults.FrameInterval
+	}
+
+	return l
+}
+
+// Set the spectator capacity and downsampling of channels. Zero values keep
+// the current limits. Only applies to channels going live afterwards.
+func (ts *TelemetryServer) SetSpectatorLimits(limits SpectatorLimits) {
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+
+	ts.spectators = limits.Or(ts.spectators)
+}
+
+// Handle upgrading spectators of unlisted and public channels to a websocket.
+// Spectators skip the password challenge and receive downsampled frames with
+// only the fields in the schema's whitelist. Spectators of team channels may
+// follow a single car with the "car" query parameter.
+func (ts *TelemetryServer) Spectate(w http.ResponseWriter, r *http.Request) error {
+	id := uf.GetParam(r, "id")
+
+	if len(id) == 0 {
+		return uf.BadRequest("Expected channel ID as URL parameter")
+	}
+
+	conn, e := websocket.Accept(w, r, ts.accept)
+
+	if e != nil {
+		// the library writes its own HTTP error responses
+		metrics.HandshakeFailures.WithLabelValues("upgrade").Inc()
+
+		return nil
+	}
+
+	// connection established; HTTP handling has finished.
+	go ts.spectate(id, r.URL.Query().Get("car"), r.RemoteAddr, conn)
+
+	return nil
+}
+
+// Spectating procedure and message handling.
+func (ts *TelemetryServer) spectate(id, car, remote string, conn *websocket.Conn) {
+	channel, e := ts.repo.FindID(context.TODO(), id)
+
+	if e == nil && !channel.Spectatable() {
+		// don't reveal that the private channel exists (or make it live)
+		e = notSpectatable(id)
+	}
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			e = wsNotFound(e.Error())
+		}
+
+		handshakeFailed(e, conn)
+
+		return
+	}
+
+	tc, e := ts.channels.getOrCreate(context.TODO(), id, func(ctx context.Context) (*telemetryChannel, error) {
+		return ts.newChannel(ctx, channel)
+	})
+
+	if e != nil {
+		handshakeFailed(e, conn)
+
+		return
+	}
+
+	if len(car) > 0 {
+		if _, e = tc.stream(car); e != nil {
+			handshakeFailed(wsNotFound("No car matching: "+car), conn)
+
+			return
+		}
+	}
+
+	spec := newClient(conn, remote, tc.bufferLength())
+	spec.car = car
+	e = tc.addSpectator(spec)
+
+	if e != nil {
+		handshakeFailed(e, conn)
+
+		return
+	}
+
+	defer tc.removeSpectator(spec)
+
+	// no password challenge; tell the spectator it is connected
+	bytes, e := json.Marshal(challengeSucceededResponse())
+
+	if e != nil {
+		handshakeFailed(e, conn)
+
+		return
+	}
+
+	e = writeTimeout(context.TODO(), timeout, conn, bytes)
+
+	if e != nil {
+		handshakeFailed(e, conn)
+
+		return
+	}
+
+	// spectators are read-only; the connection is closed if they send anything
+	ctx := conn.CloseRead(context.Background())
+
+	for {
+		select {
+		case env := <-spec.buffer:
+			e = writeTimeout(ctx, timeout, conn, env.ws)
+
+			if e != nil {
+				handleError(e, conn)
+
+				return
+			}
+
+			metrics.BytesOut.WithLabelValues(tc.ID).Add(float64(len(env.ws)))
+			spec.sent.Add(1)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Add a spectator to the channel if it is live, may be spectated, and has
+// room. The visibility is checked under subMtx so that spectators can't join
+// after those of a channel made private were dropped. See refresh.
+func (c *telemetryChannel) addSpectator(spec *client) error {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	if c.lifecycle() != stateLive {
+		return wsChannelClosed()
+	}
+
+	if !c.spectatable() {
+		return wsNotFound(notSpectatable(c.ID).Error())
+	}
+
+	if len(c.specs) >= c.specLimits.MaxSpectators {
+		return wsChannelFull()
+	}
+
+	c.specs[spec.id] = spec
+	c.listen()
+
+	return nil
+}
+
+// Returned when spectating a private channel as if it didn't exist.
+func notSpectatable(id string) error {
+	return are_hub.NewNoObjectsFound("channels", "spectatable id == "+id)
+}
+
+func (c *telemetryChannel) removeSpectator(spec *client) {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	delete(c.specs, spec.id)
+	c.ignore()
+}
+
+// Disconnect every spectator with reason. Eg. when the channel is made
+// private.
+func (c *telemetryChannel) dropSpectators(reason string) {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	for id, spec := range c.specs {
+		delete(c.specs, id)
+		go spec.drop(WS_ERROR_DISCONNECTED, reason)
+	}
+
+	c.ignore()
+}
+
+// Check whether a frame published on s is due to be sent to spectators: the
+// channel's schema defines the fields spectators receive, there are spectators,
+// and the frame interval has passed since the last. subMtx must be held.
+func (c *telemetryChannel) spectating(s *stream) bool {
+	if _, ok := spectatorFields[c.Schema]; !ok || len(c.specs) == 0 {
+		return false
+	}
+
+	return time.Since(s.spectated) >= time.Duration(c.specLimits.FrameInterval)*time.Millisecond
+}
+
+// Send the whitelisted fields of a frame published by driver on s to the
+// spectators following s. The frame must be due (see spectating). subMtx must
+// be held.
+func (c *telemetryChannel) spectate(s *stream, driver string, seq uint64, f frame.Frame) {
+	picked, e := json.Marshal(f.Pick(spectatorFields[c.Schema]))
+
+	if e != nil {
+		return
+	}
+
+	var data interface{} = json.RawMessage(picked)
+
+	if c.team() {
+		data = teamFrame{s.car, driver, json.RawMessage(picked)}
+	}
+
+	env, e := newEnvelope(dataResponse(seq, data))
+
+	if e != nil {
+		return
+	}
+
+	env.car = s.car
+	s.spectated = time.Now()
+
+	for _, spec := range c.specs {
+		if !spec.follows(env) {
+			continue
+		}
+
+		// only the latest frame matters to spectators so conflate rather than
+		// disconnect those falling behind
+		if !c.enqueue(spec, env) {
+			for c.discard(spec) {
+			}
+
+			if !c.enqueue(spec, env) {
+				c.miss(spec, env)
+			}
+		}
+	}
+}
+
+// Send a frame broadcast on s by another node to the spectators. subMtx must
+// be held.
+func (c *telemetryChannel) spectateRelayed(s *stream, b are_hub.Broadcast) {
+	if !c.spectating(s) {
+		return
+	}
+
+	raw, driver := b.Data, ""
+
+	if c.team() {
+		tf := teamFrame{}
+
+		if json.Unmarshal(b.Data, &tf) != nil {
+			return
+		}
+
+		raw, driver = tf.Frame, tf.Driver
+	}
+
+	f, e := frame.Decode(raw)
+
+	if e != nil {
+		return
+	}
+
+	c.spectate(s, driver, b.Seq, f)
+}
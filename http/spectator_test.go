@@ -0,0 +1,166 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/acc"
+	"nhooyr.io/websocket"
+)
+
+// Connect a spectator without a password.
+func (hub *testHub) spectate(t *testing.T, id string) (*websocket.Conn, testResponse) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	url := "ws" + strings.TrimPrefix(hub.URL, "http") + "/spectate/" + id
+	conn, _, e := websocket.Dial(ctx, url, nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	t.Cleanup(func() { conn.Close(websocket.StatusNormalClosure, "") })
+
+	return conn, readResponse(t, conn)
+}
+
+// Are spectators of public channels connected without the password challenge?
+// Do they only receive the whitelisted fields?
+// Are frames published within the frame interval skipped?
+func TestSpectate(t *testing.T) {
+	hub := newTestHub(t)
+	hub.schema = acc.SCHEMA
+	hub.visibility = are_hub.VISIBILITY_PUBLIC
+	hub.ts.SetSpectatorLimits(SpectatorLimits{FrameInterval: 60000})
+
+	conn, res := hub.spectate(t, testChannelID)
+
+	if res.Status != WS_CHALLENGE_SUCCESS {
+		t.Fatalf("Expected: %d. Actual: %d.", WS_CHALLENGE_SUCCESS, res.Status)
+	}
+
+	for _, f := range []string{
+		`{"version":1,"physics":{"gas":0.5,"fuel":40}}`,
+		`{"version":1,"physics":{"gas":0.6,"fuel":39}}`,
+	} {
+		if r := hub.publish(t, testChannelID, testChannelPassword, f); r.StatusCode != http.StatusOK {
+			t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, r.StatusCode)
+		}
+	}
+
+	res = readResponse(t, conn)
+	physics := struct {
+		Physics map[string]interface{} `json:"physics"`
+	}{}
+
+	if e := json.Unmarshal(res.Data, &physics); e != nil {
+		t.Fatal(e)
+	}
+
+	if _, ok := physics.Physics["fuel"]; res.Seq != 1 || ok || physics.Physics["gas"] != 0.5 {
+		t.Fatalf("Expected: seq 1 without fuel. Actual: %d, %s.", res.Seq, res.Data)
+	}
+
+	// pretend the interval has passed
+	tc := hub.channel(t)
+	tc.subMtx.Lock()
+	tc.streams[""].spectated = time.Time{}
+	tc.subMtx.Unlock()
+
+	hub.publish(t, testChannelID, testChannelPassword, `{"version":1,"physics":{"gas":0.7}}`)
+
+	// the second frame was skipped
+	if res = readResponse(t, conn); res.Seq != 3 {
+		t.Fatalf("Expected: seq 3. Actual: %d.", res.Seq)
+	}
+}
+
+// Is the frame decoded for spectators also used to evaluate the alert rules?
+func TestSpectateDerived(t *testing.T) {
+	hub := newTestHub(t)
+	hub.schema = acc.SCHEMA
+	hub.visibility = are_hub.VISIBILITY_PUBLIC
+	hub.rules = []are_hub.AlertRule{{
+		Name:      "Full throttle",
+		Field:     "physics.gas",
+		Operator:  ">",
+		Threshold: 0.4,
+		Common:    are_hub.Common{ID: "rule1"},
+	}}
+
+	spec, _ := hub.spectate(t, testChannelID)
+	sub := hub.subscribe(t, testChannelID, testChannelPassword)
+	hub.publish(t, testChannelID, testChannelPassword, `{"version":1,"physics":{"gas":0.5,"fuel":40}}`)
+
+	if res := readResponse(t, spec); res.Seq != 1 || strings.Contains(string(res.Data), "fuel") {
+		t.Fatalf("Expected: seq 1 without fuel. Actual: %d, %s.", res.Seq, res.Data)
+	}
+
+	if res := readResponse(t, sub); res.Status != WS_OK || res.Seq != 1 {
+		t.Fatalf("Expected: frame 1. Actual: %+v.", res)
+	}
+
+	// the strategy estimate is sent before the alert
+	res := readResponse(t, sub)
+
+	if res.Status == WS_STRATEGY {
+		res = readResponse(t, sub)
+	}
+
+	if res.Status != WS_ALERT || res.Seq != 1 {
+		t.Fatalf("Expected: status %d, seq 1. Actual: %+v.", WS_ALERT, res)
+	}
+}
+
+// Are spectators of private channels rejected as if the channel didn't exist?
+func TestSpectatePrivate(t *testing.T) {
+	hub := newTestHub(t)
+	conn, _, e := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(hub.URL, "http")+"/spectate/"+testChannelID, nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	_, _, e = conn.Read(context.Background())
+
+	var ce websocket.CloseError
+
+	if !errors.As(e, &ce) || ce.Code != WS_ERROR_NOT_FOUND {
+		t.Fatalf("Expected: %d. Actual: %v.", WS_ERROR_NOT_FOUND, e)
+	}
+}
+
+// Are spectators rejected once the live channel is made private even if they
+// found it spectatable?
+func TestSpectateMadePrivate(t *testing.T) {
+	hub := newTestHub(t)
+	hub.visibility = are_hub.VISIBILITY_PUBLIC
+	hub.subscribe(t, testChannelID, testChannelPassword)
+
+	// the channel is made private after the spectator looked it up
+	private := are_hub.NewChannel("Audi Sport Team WRT", testHash)
+	hub.channel(t).update(private)
+
+	conn, _, e := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(hub.URL, "http")+"/spectate/"+testChannelID, nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	_, _, e = conn.Read(context.Background())
+
+	if code := websocket.CloseStatus(e); code != WS_ERROR_NOT_FOUND {
+		t.Fatalf("Expected: %d. Actual: %v.", WS_ERROR_NOT_FOUND, e)
+	}
+}
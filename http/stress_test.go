@@ -0,0 +1,300 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/hash"
+	"github.com/blacksfk/are_hub/mock"
+	uf "github.com/blacksfk/microframework"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// Channels, cars per channel, and subscribers per channel of the stress tests.
+	stressChannels    = 4
+	stressCars        = 25
+	stressSubscribers = 50
+
+	// Frames published by each car.
+	stressFrames = 40
+)
+
+// The default argon2 parameters use 64 MiB per comparison which is too much for
+// hundreds of clients authenticating at once so hash the test password cheaply.
+var stressHash = func() string {
+	salt := make([]byte, hash.ARGON2_SALT_LEN)
+	rand.Read(salt)
+
+	key := argon2.IDKey([]byte(testChannelPassword), salt, 1, 8, 1, hash.ARGON2_KEY_LEN)
+
+	return fmt.Sprintf(
+		hash.ARGON2_FORMAT,
+		argon2.Version, 1, 8, 1,
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(key),
+	)
+}()
+
+// Create a telemetry server with stressChannels team channels. finds counts
+// the channels found in the repository by ID.
+func newStressServer(finds *sync.Map) *TelemetryServer {
+	cars := make([]string, stressCars)
+
+	for i := range cars {
+		cars[i] = strconv.Itoa(i + 1)
+	}
+
+	channels := &mock.ChannelRepo{
+		FindIDFunc: func(_ context.Context, id string) (*are_hub.Channel, error) {
+			n, _ := finds.LoadOrStore(id, new(atomic.Int32))
+			n.(*atomic.Int32).Add(1)
+
+			c := are_hub.NewChannel("Stress "+id, stressHash)
+			c.ID = id
+			c.Cars = cars
+			c.Limits = are_hub.Limits{MaxSubscribers: stressSubscribers, SlowPolicy: are_hub.POLICY_DROP_OLDEST}
+
+			return c, nil
+		},
+	}
+
+	discard := func(context.Context, are_hub.Archetype) error { return nil }
+	rules := &mock.AlertRuleRepo{
+		FindChannelIDFunc: func(context.Context, string) ([]are_hub.AlertRule, error) {
+			return nil, nil
+		},
+	}
+
+	return NewTelemetryServer(nil, TelemetryRepos{
+		Channels: channels,
+		Messages: &mock.MessageRepo{InsertFunc: discard},
+		Laps:     &mock.LapRepo{InsertFunc: discard},
+		Rules:    rules,
+		Alerts:   &mock.AlertRepo{InsertFunc: discard},
+	}, discardEvents())
+}
+
+// Do hundreds of publishers and subscribers connecting to the same channels at
+// once share a single telemetry channel per ID? Does every subscriber receive
+// frames in sequence order without duplicates? Run with -race.
+func TestStressPublishSubscribe(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+
+	finds := &sync.Map{}
+	ts := newStressServer(finds)
+	ctx := context.Background()
+
+	var subs, pubs sync.WaitGroup
+	errs := make(chan error, stressChannels*(stressCars+stressSubscribers))
+	start := make(chan struct{})
+
+	// subscribers read until the publishers have finished
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for c := 0; c < stressChannels; c++ {
+		id := fmt.Sprintf("stress%d", c)
+
+		for i := 0; i < stressSubscribers; i++ {
+			subs.Add(1)
+
+			go func(id string, i int) {
+				defer subs.Done()
+				<-start
+
+				o := SubscribeOptions{RemoteAddr: "stress"}
+
+				if i%2 == 0 {
+					// follow a single car
+					o.Car = strconv.Itoa(i%stressCars + 1)
+				}
+
+				sub, e := ts.Subscription(ctx, id, testChannelPassword, o)
+
+				if e != nil {
+					errs <- e
+
+					return
+				}
+
+				defer sub.Close()
+
+				var last uint64
+
+				for {
+					ev, e := sub.Next(subCtx)
+
+					if e == context.Canceled {
+						// publishers have finished
+						return
+					}
+
+					if e != nil {
+						errs <- e
+
+						return
+					}
+
+					if ev.Status != WS_OK {
+						continue
+					}
+
+					if ev.Seq <= last {
+						errs <- fmt.Errorf("Subscriber received seq %d after %d", ev.Seq, last)
+
+						return
+					}
+
+					last = ev.Seq
+				}
+			}(id, i)
+		}
+
+		for car := 1; car <= stressCars; car++ {
+			pubs.Add(1)
+
+			go func(id string, car int) {
+				defer pubs.Done()
+				<-start
+
+				slot := Slot{Car: strconv.Itoa(car), Driver: "Driver " + strconv.Itoa(car)}
+				pub, e := ts.Publisher(ctx, id, testChannelPassword, slot)
+
+				if e != nil {
+					errs <- e
+
+					return
+				}
+
+				for f := 0; f < stressFrames; f++ {
+					if e = pub.Broadcast(ctx, []byte(`{"speedKmh":212.5}`)); e != nil {
+						errs <- e
+
+						return
+					}
+				}
+			}(id, car)
+		}
+	}
+
+	// meanwhile inspect and reconfigure the channels
+	var admin sync.WaitGroup
+	admin.Add(1)
+
+	go func() {
+		defer admin.Done()
+		<-start
+
+		for {
+			select {
+			case <-subCtx.Done():
+				return
+			default:
+			}
+
+			for _, tc := range ts.channels.all() {
+				tc.status()
+				tc.setLimits(are_hub.Limits{MaxSubscribers: stressSubscribers, BufferLength: BUF_LEN, SlowPolicy: are_hub.POLICY_DROP_OLDEST})
+			}
+		}
+	}()
+
+	close(start)
+	pubs.Wait()
+	cancel()
+	subs.Wait()
+	admin.Wait()
+	close(errs)
+
+	for e := range errs {
+		t.Error(e)
+	}
+
+	finds.Range(func(id, n interface{}) bool {
+		if c := n.(*atomic.Int32).Load(); c != 1 {
+			t.Errorf("Expected: channel %s found once. Actual: %d.", id, c)
+		}
+
+		return true
+	})
+
+	for _, tc := range ts.channels.all() {
+		if seq := tc.sequence(); seq != stressCars*stressFrames {
+			t.Errorf("Expected: %d frames on %s. Actual: %d.", stressCars*stressFrames, tc.ID, seq)
+		}
+	}
+}
+
+// Are clients connecting while channels are being closed and recreated either
+// added to a live channel or rejected as closed?
+func TestStressClose(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+
+	ts := newStressServer(&sync.Map{})
+	ctx := context.Background()
+	id := "stress"
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if i%10 == 0 {
+				ts.closeChannel(id, "Closed")
+
+				return
+			}
+
+			if i%2 == 0 {
+				sub, e := ts.Subscription(ctx, id, testChannelPassword, SubscribeOptions{})
+
+				if e == nil {
+					sub.Close()
+				} else if e != errClosed {
+					t.Error(e)
+				}
+
+				return
+			}
+
+			pub, e := ts.Publisher(ctx, id, testChannelPassword, Slot{Car: "1", Driver: "Driver " + strconv.Itoa(i)})
+
+			if e != nil {
+				t.Error(e)
+
+				return
+			}
+
+			// other drivers may hold the slot
+			e = pub.Broadcast(ctx, []byte(`{}`))
+
+			if he, ok := e.(uf.HttpError); e != nil && (!ok || he.Code != http.StatusConflict && he.Code != http.StatusGone) {
+				t.Error(e)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, tc := range ts.channels.all() {
+		if s := tc.lifecycle(); s != stateLive {
+			t.Errorf("Expected: registered channels to be live. Actual: %s.", stateNames[s])
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/metrics"
+	uf "github.com/blacksfk/microframework"
+	"nhooyr.io/websocket"
+)
+
+// Returned from Subscription.Next when the subscriber was dropped from the channel
+// for not keeping up with the messages broadcast.
+var ErrDropped = errors.New("Subscriber dropped: message buffer full")
+
+// Returned from Subscription.Next when the subscriber was disconnected by an
+// administrator.
+var ErrDisconnected = errors.New("Subscriber disconnected by an administrator")
+
+// The publisher slot of a car. Channels without cars have a single slot with
+// an empty car.
+type Slot struct {
+	Car    string `json:"car"`
+	Driver string `json:"driver"`
+}
+
+// Publishes frames to a telemetry channel on behalf of clients connected through
+// transports other than HTTP. Eg. gRPC.
+type Publisher struct {
+	ts   *TelemetryServer
+	tc   *telemetryChannel
+	slot Slot
+}
+
+// Authenticate a publisher on the channel matching id publishing in slot.
+// Returns the same errors as the HTTP handlers (microframework.HttpError) for
+// the caller to translate.
+func (ts *TelemetryServer) Publisher(ctx context.Context, id, password string, slot Slot) (*Publisher, error) {
+	tc, e := ts.authenticate(ctx, id, password, are_hub.ROLE_PUBLISHER)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return &Publisher{ts, tc, slot}, nil
+}
+
+// Broadcast a JSON encoded frame to every subscriber on the channel.
+func (p *Publisher) Broadcast(ctx context.Context, frame []byte) error {
+	return p.ts.publish(ctx, p.tc, p.slot, frame)
+}
+
+// A message received through a Subscription.
+type Event struct {
+	// Websocket status code. Eg. WS_OK for telemetry frames.
+	Status websocket.StatusCode
+
+	// Sequence number of the frame (or the last frame for other message types).
+	Seq uint64
+
+	// JSON encoded data.
+	Data []byte
+}
+
+// Subscribes clients connected through transports other than websockets and
+// server-sent events (eg. gRPC) to a telemetry channel. A Subscription is a
+// regular subscriber and shares the same buffering and drop policy.
+type Subscription struct {
+	tc  *telemetryChannel
+	sub *client
+}
+
+// How a subscriber is subscribed to a channel.
+type SubscribeOptions struct {
+	// Car followed on team channels. All cars are followed if empty.
+	Car string
+
+	// Slow subscriber policy (one of the are_hub.POLICY_ constants). The
+	// channel's policy applies if empty.
+	Policy string
+
+	// Address of the subscriber.
+	RemoteAddr string
+}
+
+// Authenticate and add a subscriber to the channel matching id. Returns the same
+// errors as the HTTP handlers (microframework.HttpError) for the caller to
+// translate.
+func (ts *TelemetryServer) Subscription(ctx context.Context, id, password string, o SubscribeOptions) (*Subscription, error) {
+	if len(o.Policy) > 0 && !are_hub.IsSlowPolicy(o.Policy) {
+		return nil, uf.BadRequest("Unknown slow subscriber policy: " + o.Policy)
+	}
+
+	tc, e := ts.authenticate(ctx, id, password, are_hub.ROLE_SUBSCRIBER)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if len(o.Car) > 0 {
+		if _, e = tc.stream(o.Car); e != nil {
+			return nil, e
+		}
+	}
+
+	sub := newClient(nil, o.RemoteAddr, tc.bufferLength())
+	sub.car = o.Car
+	sub.policy = o.Policy
+	e = tc.addSub(sub)
+
+	if e != nil {
+		return nil, httpError(e)
+	}
+
+	return &Subscription{tc, sub}, nil
+}
+
+// Block until the next message is received. Returns ErrDropped if the subscriber
+// was dropped by the channel, ErrDisconnected if it was disconnected by an
+// administrator, or ctx's error if ctx is done first.
+func (s *Subscription) Next(ctx context.Context) (Event, error) {
+	select {
+	case env := <-s.sub.buffer:
+		metrics.BytesOut.WithLabelValues(s.tc.ID).Add(float64(len(env.data)))
+		s.sub.sent.Add(1)
+
+		return Event{env.status, env.seq, env.data}, nil
+	case <-s.sub.done:
+		if s.sub.code == WS_ERROR_DISCONNECTED {
+			return Event{}, ErrDisconnected
+		}
+
+		return Event{}, ErrDropped
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+// Remove the subscriber from the channel.
+func (s *Subscription) Close() {
+	s.tc.removeSub(s.sub)
+}
+
+// Translate the websocket errors returned when adding subscribers for clients
+// not connected through websockets: channel full to 503 Service Unavailable
+// and channel closed to 410 Gone.
+func httpError(e error) error {
+	er, ok := e.(*errorResponse)
+
+	if !ok {
+		return e
+	}
+
+	switch er.Status {
+	case WS_ERROR_CHANNEL_FULL:
+		return uf.HttpError{Code: http.StatusServiceUnavailable, Message: "Channel full."}
+	case WS_ERROR_DISCONNECTED:
+		return errClosed
+	}
+
+	return e
+}
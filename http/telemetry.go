@@ -3,12 +3,17 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/backplane"
 	"github.com/blacksfk/are_hub/hash"
+	"github.com/blacksfk/are_hub/metrics"
 	uf "github.com/blacksfk/microframework"
 	"nhooyr.io/websocket"
 )
@@ -16,27 +21,94 @@ import (
 var (
 	// How long to wait in seconds until dropping the connection to a client.
 	timeout time.Duration = time.Second * 10
+
+	// How long without frames until a publisher is considered offline.
+	publisherTimeout time.Duration = time.Second * 30
+
+	// How long publishers are blocked waiting for subscribers with the block
+	// policy to make room for a message.
+	blockDeadline time.Duration = time.Millisecond * 100
 )
 
+// Repositories used by the telemetry server.
+type TelemetryRepos struct {
+	Channels are_hub.ChannelRepo
+	Messages are_hub.MessageRepo
+	Laps     are_hub.LapRepo
+	Rules    are_hub.AlertRuleRepo
+	Alerts   are_hub.AlertRepo
+
+	// Sessions redeemed from invitations, accepted in place of passwords.
+	Sessions are_hub.SessionRepo
+}
+
 // Handles receiving data from publishers and forwarding that data along to
 // subscribers on the same channel.
 type TelemetryServer struct {
-	accept *websocket.AcceptOptions
-	repo   are_hub.ChannelRepo
-
-	mtx      sync.Mutex
-	channels map[string]*telemetryChannel
+	accept   *websocket.AcceptOptions
+	repo     are_hub.ChannelRepo
+	messages are_hub.MessageRepo
+	laps     are_hub.LapRepo
+	rules    are_hub.AlertRuleRepo
+	alerts   are_hub.AlertRepo
+	sessions are_hub.SessionRepo
+	events   are_hub.Emitter
+
+	// Subscriber capacity and buffering of channels without their own, the
+	// spectator capacity and downsampling of channels, and the backplane
+	// relaying messages between nodes. Guarded by mtx.
+	mtx        sync.Mutex
+	defaults   are_hub.Limits
+	spectators SpectatorLimits
+	backplane  are_hub.Backplane
+
+	// Identifies this node to the backplane.
+	node string
+
+	channels *registry
 }
 
-// Create a new telemetry server.
-func NewTelemetryServer(o *websocket.AcceptOptions, r are_hub.ChannelRepo) *TelemetryServer {
+// Create a new telemetry server. Sessions starting, publishers going offline,
+// and alerts firing are emitted to events. Channels are only served by this
+// node until another backplane is set. See SetBackplane.
+func NewTelemetryServer(o *websocket.AcceptOptions, repos TelemetryRepos, events are_hub.Emitter) *TelemetryServer {
 	return &TelemetryServer{
-		accept:   o,
-		repo:     r,
-		channels: make(map[string]*telemetryChannel),
+		accept:     o,
+		repo:       repos.Channels,
+		messages:   repos.Messages,
+		laps:       repos.Laps,
+		rules:      repos.Rules,
+		alerts:     repos.Alerts,
+		sessions:   repos.Sessions,
+		events:     events,
+		defaults:   defaultLimits,
+		spectators: defaultSpectatorLimits,
+		backplane:  backplane.NewLocal(),
+		node:       newID(),
+		channels:   newRegistry(),
 	}
 }
 
+// Relay messages broadcast on channels to and from other nodes through bp and
+// coordinate the publisher slots of channels across nodes with it. Only
+// applies to channels going live afterwards.
+func (ts *TelemetryServer) SetBackplane(bp are_hub.Backplane) {
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+
+	ts.backplane = bp
+}
+
+// Set the subscriber capacity and buffering of channels that don't define
+// their own. Zero values keep the current defaults. Only applies to channels
+// going live afterwards.
+func (ts *TelemetryServer) SetDefaults(defaults are_hub.Limits) {
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+
+	ts.defaults = defaults.Or(ts.defaults)
+}
+
 // Broadcast messages to connected clients (connected via websockets).
 func (ts *TelemetryServer) Publish(w http.ResponseWriter, r *http.Request) error {
 	id := uf.GetParam(r, "id")
@@ -55,50 +127,208 @@ func (ts *TelemetryServer) Publish(w http.ResponseWriter, r *http.Request) error
 		return uf.BadRequest("Channel password required.")
 	}
 
-	// check if the channel already exists in the map
-	tc, ok := ts.channels[id]
+	tc, e := ts.authenticate(r.Context(), id, plaintext, are_hub.ROLE_PUBLISHER)
 
-	if !ok {
-		// channel not in map, find it in the DB
-		c, e := ts.repo.FindID(r.Context(), id)
+	if e != nil {
+		return e
+	}
 
-		if e != nil {
-			if are_hub.IsNoObjectsFound(e) {
-				return uf.NotFound(e.Error())
-			}
+	// expect JSON but don't decode it
+	bytes, e := uf.ReadBody(r, "application/json")
 
-			return e
-		}
+	if e != nil {
+		return e
+	}
 
-		tc = newTelemetryChannel(c)
-		ts.addChannel(tc)
+	// publishers of team channels identify their car
+	slot := Slot{Car: r.Header.Get("Channel-Car"), Driver: r.Header.Get("Channel-Driver")}
+
+	// broadcast the encoded json bytes to the clients
+	e = ts.publish(r.Context(), tc, slot, bytes)
+
+	if e != nil {
+		return e
 	}
 
-	// compare the recieved password with the known password
-	match, e := hash.CmpPassword(tc.PasswordStr(), plaintext)
+	w.WriteHeader(200)
+
+	return nil
+}
+
+// Hand the publisher slot of a car over to a new driver. The driver takes over
+// the car with their first frame without the car's laps or strategy resetting.
+func (ts *TelemetryServer) Swap(w http.ResponseWriter, r *http.Request) error {
+	id := uf.GetParam(r, "id")
+
+	if len(id) == 0 {
+		return uf.BadRequest("Channel ID required.")
+	}
+
+	plaintext := r.Header.Get("Channel-Password")
+
+	if len(plaintext) == 0 {
+		return uf.BadRequest("Channel password required.")
+	}
+
+	tc, e := ts.authenticate(r.Context(), id, plaintext, are_hub.ROLE_PUBLISHER)
 
 	if e != nil {
 		return e
 	}
 
-	if !match {
-		return uf.Unauthorized("Incorrect credentials.")
+	slot := Slot{}
+	e = uf.DecodeBodyJSON(r, &slot)
+
+	if e != nil {
+		return e
 	}
 
-	// expect JSON but don't decode it
-	bytes, e := uf.ReadBody(r, "application/json")
+	if len(slot.Driver) == 0 {
+		return uf.BadRequest("Driver required.")
+	}
+
+	e = tc.handoff(r.Context(), slot.Car, slot.Driver)
 
 	if e != nil {
 		return e
 	}
 
-	// broadcast the encoded json bytes to the clients
-	tc.broadcast(bytes)
-	w.WriteHeader(200)
+	w.WriteHeader(http.StatusAccepted)
 
 	return nil
 }
 
+// Stream telemetry frames and chat messages to a subscriber as server-sent events.
+// The channel password (or a session token) is expected either as a bearer
+// token in the Authorization header or in the "Channel-Password" header. Subscribers of team channels may
+// follow a single car with the "car" query parameter and choose a slow
+// subscriber policy with the "policy" query parameter.
+func (ts *TelemetryServer) Events(w http.ResponseWriter, r *http.Request) error {
+	id := uf.GetParam(r, "id")
+
+	if len(id) == 0 {
+		return uf.BadRequest("Channel ID required.")
+	}
+
+	policy := r.URL.Query().Get("policy")
+
+	if len(policy) > 0 && !are_hub.IsSlowPolicy(policy) {
+		return uf.BadRequest("Unknown slow subscriber policy: " + policy)
+	}
+
+	plaintext := r.Header.Get("Channel-Password")
+
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		plaintext = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	if len(plaintext) == 0 {
+		return uf.Unauthorized("Channel password required.")
+	}
+
+	tc, e := ts.authenticate(r.Context(), id, plaintext, are_hub.ROLE_SUBSCRIBER)
+
+	if e != nil {
+		return e
+	}
+
+	flusher, ok := w.(http.Flusher)
+
+	if !ok {
+		return uf.InternalServerError("Streaming is not supported.")
+	}
+
+	sub := newClient(nil, r.RemoteAddr, tc.bufferLength())
+	sub.car = r.URL.Query().Get("car")
+	sub.policy = policy
+
+	if len(sub.car) > 0 {
+		if _, e = tc.stream(sub.car); e != nil {
+			return e
+		}
+	}
+
+	// resume from the last event the client received if it is reconnecting
+	if last := r.Header.Get("Last-Event-ID"); len(last) > 0 {
+		seq, e := strconv.ParseUint(last, 10, 64)
+
+		if e != nil {
+			return uf.BadRequest("Last-Event-ID must be a sequence number.")
+		}
+
+		e = tc.resumeSub(sub, seq)
+	} else {
+		e = tc.addSub(sub)
+	}
+
+	if e != nil {
+		return httpError(e)
+	}
+
+	defer tc.removeSub(sub)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case env := <-sub.buffer:
+			n, e := w.Write(env.event())
+
+			if e != nil {
+				// client has gone away
+				return nil
+			}
+
+			metrics.BytesOut.WithLabelValues(tc.ID).Add(float64(n))
+			sub.sent.Add(1)
+
+			flusher.Flush()
+		case <-sub.done:
+			// dropped by the channel
+			return nil
+		case <-r.Context().Done():
+			// client disconnected
+			return nil
+		}
+	}
+}
+
+// Get the current strategy estimate of a live channel. Team channels require
+// the car in the "car" query parameter.
+func (ts *TelemetryServer) Strategy(w http.ResponseWriter, r *http.Request) error {
+	h := w.Header()
+
+	h.Set("Access-Control-Allow-Methods", h.Get("Allow"))
+	h.Set("Access-Control-Allow-Origin", "*")
+
+	id := uf.GetParam(r, "id")
+
+	tc, ok := ts.channels.get(id)
+
+	if !ok {
+		return uf.NotFound("Channel " + id + " is not live.")
+	}
+
+	s, e := tc.stream(r.URL.Query().Get("car"))
+
+	if e != nil {
+		return e
+	}
+
+	est, ok := tc.strategy(s)
+
+	if !ok {
+		return uf.NotFound("Channel " + id + " has no schema to estimate strategy with.")
+	}
+
+	return uf.SendJSON(w, est)
+}
+
 // // Handle upgrading publisher clients to a websocket.
 // func (ts *TelemetryServer) Publish(w http.ResponseWriter, r *http.Request) error {
 // 	id := uf.GetParam(r, "id")
@@ -122,7 +352,9 @@ func (ts *TelemetryServer) Publish(w http.ResponseWriter, r *http.Request) error
 // 	return nil
 // }
 
-// Handle upgrade subscriber clients to a websocket.
+// Handle upgrade subscriber clients to a websocket. Subscribers of team channels
+// may follow a single car with the "car" query parameter and choose a slow
+// subscriber policy with the "policy" query parameter.
 func (ts *TelemetryServer) Subscribe(w http.ResponseWriter, r *http.Request) error {
 	id := uf.GetParam(r, "id")
 
@@ -131,36 +363,55 @@ func (ts *TelemetryServer) Subscribe(w http.ResponseWriter, r *http.Request) err
 		return uf.BadRequest("Expected channel ID as URL parameter")
 	}
 
+	q := r.URL.Query()
+	o := SubscribeOptions{Car: q.Get("car"), Policy: q.Get("policy"), RemoteAddr: r.RemoteAddr}
+
+	if len(o.Policy) > 0 && !are_hub.IsSlowPolicy(o.Policy) {
+		return uf.BadRequest("Unknown slow subscriber policy: " + o.Policy)
+	}
+
 	conn, e := websocket.Accept(w, r, ts.accept)
 
 	if e != nil {
 		// the library writes its own HTTP error responses instead of letting the
 		// user handle the HTTP errors themselves
+		metrics.HandshakeFailures.WithLabelValues("upgrade").Inc()
+
 		return nil
 	}
 
 	// connection established; HTTP handling has finished.
-	go ts.subscribe(id, conn)
+	go ts.subscribe(id, o, conn)
 
 	return nil
 }
 
 // Subscription procedure and message handling.
-func (ts *TelemetryServer) subscribe(id string, conn *websocket.Conn) {
+func (ts *TelemetryServer) subscribe(id string, o SubscribeOptions, conn *websocket.Conn) {
 	tc, e := ts.procedure(id, conn)
 
 	if e != nil {
-		handleError(e, conn)
+		handshakeFailed(e, conn)
 
 		return
 	}
 
+	if len(o.Car) > 0 {
+		if _, e = tc.stream(o.Car); e != nil {
+			handshakeFailed(wsNotFound("No car matching: "+o.Car), conn)
+
+			return
+		}
+	}
+
 	// create a client out of the connection and add it to the channel
-	sub := newClient(conn)
+	sub := newClient(conn, o.RemoteAddr, tc.bufferLength())
+	sub.car = o.Car
+	sub.policy = o.Policy
 	e = tc.addSub(sub)
 
 	if e != nil {
-		handleError(e, conn)
+		handshakeFailed(e, conn)
 
 		return
 	}
@@ -169,7 +420,7 @@ func (ts *TelemetryServer) subscribe(id string, conn *websocket.Conn) {
 	bytes, e := json.Marshal(challengeSucceededResponse())
 
 	if e != nil {
-		handleError(e, conn)
+		handshakeFailed(e, conn)
 		tc.removeSub(sub)
 
 		return
@@ -178,55 +429,96 @@ func (ts *TelemetryServer) subscribe(id string, conn *websocket.Conn) {
 	e = writeTimeout(context.TODO(), timeout, conn, bytes)
 
 	if e != nil {
-		handleError(e, conn)
+		handshakeFailed(e, conn)
 		tc.removeSub(sub)
 
 		return
 	}
 
-	// handle sending/receiving of messages
-	for {
-		ctx := context.Background()
+	// cancelled when the subscriber disconnects or sends a bad message
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// receive chat messages from the subscriber
+	go ts.receive(ctx, cancel, tc, sub)
 
+	// handle sending of messages
+	for {
 		select {
-		case msg := <-sub.buffer:
+		case env := <-sub.buffer:
 			// message received, send it to the subscriber
-			// unmarshal the received data as generic JSON
-			// to re-marshal it as a part of a WS data response
-			var generic interface{}
-
-			e = json.Unmarshal(msg, &generic)
+			e = writeTimeout(ctx, timeout, conn, env.ws)
 
 			if e != nil {
-				// received busted JSON
 				handleError(e, conn)
 				tc.removeSub(sub)
 
 				return
 			}
 
-			bytes, e = json.Marshal(dataResponse(&generic))
+			metrics.BytesOut.WithLabelValues(tc.ID).Add(float64(len(env.ws)))
+			sub.sent.Add(1)
+		case <-ctx.Done():
+			// the receiving goroutine has closed the connection
+			tc.removeSub(sub)
 
-			if e != nil {
-				// busted JSON
-				handleError(e, conn)
-				tc.removeSub(sub)
+			return
+		}
+	}
+}
 
-				return
-			}
+// Chat message sent by subscribers.
+type chatMessage struct {
+	Author string `json:"author"`
+	Body   string `json:"body"`
+}
 
-			e = writeTimeout(ctx, timeout, conn, bytes)
+// Read chat messages from a subscriber, persist them, and forward them to every
+// subscriber on the channel. Calls cancel and closes the connection when
+// reading fails or the subscriber sends a malformed message.
+func (ts *TelemetryServer) receive(ctx context.Context, cancel context.CancelFunc, tc *telemetryChannel, sub *client) {
+	defer cancel()
 
-			if e != nil {
-				handleError(e, conn)
-				tc.removeSub(sub)
+	for {
+		// subscribers are not expected to send anything so don't time them out
+		mtype, bytes, e := sub.conn.Read(ctx)
 
-				return
-			}
-		case <-ctx.Done():
-			// something went wrong
-			handleError(ctx.Err(), conn)
-			tc.removeSub(sub)
+		if e != nil {
+			// connection closed or broken
+			handleError(e, sub.conn)
+
+			return
+		}
+
+		if mtype != websocket.MessageText {
+			handleError(wsPolicyViolation("Binary data is not supported"), sub.conn)
+
+			return
+		}
+
+		cm := chatMessage{}
+		e = json.Unmarshal(bytes, &cm)
+
+		if e != nil || len(cm.Body) == 0 {
+			handleError(wsBadMsg("Expected chat message with a body"), sub.conn)
+
+			return
+		}
+
+		// timestamp the message against the telemetry sequence and persist it
+		msg := are_hub.NewMessage(tc.ID, cm.Author, cm.Body, tc.sequence())
+		e = ts.messages.Insert(ctx, msg)
+
+		if e != nil {
+			handleError(e, sub.conn)
+
+			return
+		}
+
+		e = tc.chat(msg)
+
+		if e != nil {
+			handleError(e, sub.conn)
 
 			return
 		}
@@ -303,7 +595,7 @@ func (ts *TelemetryServer) procedure(id string, conn *websocket.Conn) (*telemetr
 		return nil, e
 	}
 
-	// channel found, ask for the password
+	// channel found, ask for the password (or a session token)
 	bytes, e := passwordChallenge(context.TODO(), conn)
 
 	if e != nil {
@@ -311,7 +603,7 @@ func (ts *TelemetryServer) procedure(id string, conn *websocket.Conn) (*telemetr
 	}
 
 	// compare the received password with the known password
-	match, e := hash.CmpPassword(channel.PasswordStr(), string(bytes))
+	match, e := ts.admit(context.TODO(), id, channel.PasswordStr(), string(bytes), are_hub.ROLE_SUBSCRIBER)
 
 	if e != nil {
 		// something went wrong with comparison
@@ -322,22 +614,246 @@ func (ts *TelemetryServer) procedure(id string, conn *websocket.Conn) (*telemetr
 		return nil, wsUnauthorised("Incorrect password")
 	}
 
-	// check if the telemetry channel already exists in the map
-	tc, ok := ts.channels[id]
+	// get the live telemetry channel or create one out of the found channel
+	return ts.channels.getOrCreate(context.TODO(), id, func(ctx context.Context) (*telemetryChannel, error) {
+		return ts.newChannel(ctx, channel)
+	})
+}
+
+// Broadcast a frame published in slot on tc and persist the lap it completed
+// (if any) and the alerts it fired. Invalid frames are rejected with a 400 Bad
+// Request error and frames from drivers not holding the slot with a 409 Conflict.
+func (ts *TelemetryServer) publish(ctx context.Context, tc *telemetryChannel, slot Slot, frame []byte) error {
+	s, prev, e := tc.claim(ctx, slot)
+
+	if e != nil {
+		return e
+	}
+
+	if len(prev) > 0 && prev != slot.Driver {
+		info := streamInfo{Car: slot.Car, Driver: slot.Driver, Previous: prev}
+		e = tc.swap(s, prev, slot.Driver)
+
+		if e != nil {
+			return e
+		}
+
+		ts.events.Emit(are_hub.NewEvent(are_hub.EVENT_DRIVER_SWAPPED, tc.ID, info))
+	}
+
+	d, e := tc.broadcast(ctx, s, slot.Driver, frame)
+
+	if _, ok := e.(uf.HttpError); e != nil && !ok {
+		// the frame was rejected
+		return uf.BadRequest(e.Error())
+	}
+
+	if e != nil {
+		return e
+	}
+
+	metrics.FramesPublished.WithLabelValues(tc.ID).Inc()
+	metrics.BytesIn.WithLabelValues(tc.ID).Add(float64(len(frame)))
+
+	tc.active(s, func() {
+		info := streamInfo{Car: slot.Car, Driver: slot.Driver, Seq: tc.sequence()}
+		ts.events.Emit(are_hub.NewEvent(are_hub.EVENT_PUBLISHER_OFFLINE, tc.ID, info))
+	})
+
+	if d == nil {
+		return nil
+	}
+
+	if len(d.session) > 0 {
+		info := streamInfo{Car: slot.Car, Driver: slot.Driver, Session: d.session}
+		ts.events.Emit(are_hub.NewEvent(are_hub.EVENT_SESSION_STARTED, tc.ID, info))
+	}
+
+	if d.lap != nil {
+		e = ts.laps.Insert(ctx, d.lap)
+
+		if e != nil {
+			return e
+		}
+	}
+
+	for _, a := range d.alerts {
+		e = ts.alerts.Insert(ctx, a)
+
+		if e != nil {
+			return e
+		}
+
+		ts.events.Emit(are_hub.NewEvent(are_hub.EVENT_ALERT_FIRED, tc.ID, a))
+	}
+
+	if d.metadata != nil {
+		// the frame was broadcast so don't fail publishing over metadata
+		_, e = ts.repo.FillID(ctx, tc.ID, *d.metadata)
+
+		if e != nil && !are_hub.IsNoObjectsFound(e) {
+			log.Printf("Failed to fill the metadata of %s: %v\n", tc.ID, e)
+		}
+	}
+
+	return nil
+}
+
+// Describes a stream of a channel in subscriber messages and events.
+type streamInfo struct {
+	Car     string `json:"car,omitempty"`
+	Driver  string `json:"driver,omitempty"`
+	Session string `json:"session,omitempty"`
+
+	// Driver that was replaced in a driver swap.
+	Previous string `json:"previous,omitempty"`
+
+	// Sequence number of the last frame broadcast.
+	Seq uint64 `json:"seq,omitempty"`
+}
+
+// Apply c to its telemetry channel if it is live. The subscriber capacity,
+// buffering, name, and password are replaced without going offline. Channels
+// whose schema or cars changed are closed instead so that clients reconnect
+// to the new configuration.
+func (ts *TelemetryServer) refresh(c *are_hub.Channel) {
+	tc, ok := ts.channels.get(c.ID)
+
+	if !ok {
+		return
+	}
+
+	if c.Schema != tc.Schema || !sameCars(c.Cars, tc.Cars) {
+		ts.closeChannel(c.ID, "Channel changed")
+
+		return
+	}
+
+	tc.setLimits(c.Limits.Or(ts.limits()))
+	tc.update(c)
+
+	if !c.Spectatable() {
+		tc.dropSpectators("Channel is private")
+	}
+}
+
+// Check whether a and b are the same cars in the same order.
+func sameCars(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Reload the alert rules of the channel matching id after they were changed.
+// The channel is touched so that the other servers watching channels reload
+// them too.
+func (ts *TelemetryServer) changeRules(ctx context.Context, id string) error {
+	e := ts.repo.TouchID(ctx, id)
+
+	if e != nil && !are_hub.IsNoObjectsFound(e) {
+		return e
+	}
+
+	return ts.reloadRules(ctx, id)
+}
+
+// Reload the alert rules of the channel matching id if it is live.
+func (ts *TelemetryServer) reloadRules(ctx context.Context, id string) error {
+	tc, ok := ts.channels.get(id)
 
 	if !ok {
-		// create a new telemetry channel out of the found one and add it to the map
-		tc = newTelemetryChannel(channel)
-		ts.addChannel(tc)
+		// rules are loaded when the channel goes live
+		return nil
+	}
+
+	rules, e := ts.rules.FindChannelID(ctx, id)
+
+	if e != nil {
+		return e
+	}
+
+	tc.setRules(rules)
+
+	return nil
+}
+
+// Get the telemetry channel matching id (finding it in the repository if it
+// is not live) and compare plaintext with the channel's password or the
+// sessions permitting role.
+func (ts *TelemetryServer) authenticate(ctx context.Context, id, plaintext, role string) (*telemetryChannel, error) {
+	tc, e := ts.channels.getOrCreate(ctx, id, func(ctx context.Context) (*telemetryChannel, error) {
+		// channel not live, find it in the DB
+		c, e := ts.repo.FindID(ctx, id)
+
+		if e != nil {
+			if are_hub.IsNoObjectsFound(e) {
+				return nil, uf.NotFound(e.Error())
+			}
+
+			return nil, e
+		}
+
+		return ts.newChannel(ctx, c)
+	})
+
+	if e != nil {
+		return nil, e
+	}
+
+	// compare the recieved password with the known password
+	match, e := ts.admit(ctx, id, tc.password(), plaintext, role)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if !match {
+		return nil, uf.Unauthorized("Incorrect credentials.")
 	}
 
 	return tc, nil
 }
 
-func (ts *TelemetryServer) addChannel(channel *telemetryChannel) {
+// Create a telemetry channel out of c with its alert rules. The channel is made
+// live by the registry.
+func (ts *TelemetryServer) newChannel(ctx context.Context, c *are_hub.Channel) (*telemetryChannel, error) {
+	rules, e := ts.rules.FindChannelID(ctx, c.ID)
+
+	if e != nil {
+		return nil, e
+	}
+
+	tc := newTelemetryChannel(c, rules)
+	tc.node = ts.node
+
 	ts.mtx.Lock()
-	ts.channels[channel.ID] = channel
+	tc.limits = c.Limits.Or(ts.defaults)
+	tc.specLimits = ts.spectators
+	tc.bp = ts.backplane
 	ts.mtx.Unlock()
+
+	return tc, nil
+}
+
+// Make channel live, replacing any live channel with the same ID.
+func (ts *TelemetryServer) addChannel(channel *telemetryChannel) {
+	ts.channels.add(channel)
+}
+
+// Get the default subscriber capacity and buffering.
+func (ts *TelemetryServer) limits() are_hub.Limits {
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+
+	return ts.defaults
 }
 
 func passwordChallenge(ctx context.Context, conn *websocket.Conn) ([]byte, error) {
@@ -384,10 +900,59 @@ func readTimeout(ctx context.Context, timeout time.Duration, conn *websocket.Con
 	return bytes, nil
 }
 
-func handleError(e error, conn *websocket.Conn) {
+// Close conn with the status of e. Returns the status.
+func handleError(e error, conn *websocket.Conn) websocket.StatusCode {
 	if er, ok := e.(*errorResponse); ok {
 		conn.Close(er.Status, er.Message)
-	} else {
-		conn.Close(websocket.StatusInternalError, e.Error())
+
+		return er.Status
 	}
+
+	conn.Close(websocket.StatusInternalError, e.Error())
+
+	return websocket.StatusInternalError
+}
+
+// Close conn of a client that failed to complete the connection procedure
+// and count the failure.
+func handshakeFailed(e error, conn *websocket.Conn) {
+	code := handleError(e, conn)
+	metrics.HandshakeFailures.WithLabelValues(strconv.Itoa(int(code))).Inc()
+}
+
+// Check whether plaintext is either the password (hashed) of the channel
+// matching id or the token of a session of the channel permitting role.
+func (ts *TelemetryServer) admit(ctx context.Context, id, hashed, plaintext, role string) (bool, error) {
+	if strings.HasPrefix(plaintext, are_hub.SESSION_PREFIX) {
+		s, e := ts.sessions.FindToken(ctx, hash.Token(plaintext))
+
+		if e == nil {
+			return s.ChannelID == id && are_hub.Permits(s.Role, role), nil
+		}
+
+		if !are_hub.IsNoObjectsFound(e) {
+			return false, e
+		}
+
+		// not a session so may be the password
+	}
+
+	return verify(hashed, plaintext)
+}
+
+// Compare plaintext with a hashed password, recording how long it took.
+func verify(hashed, plaintext string) (bool, error) {
+	start := time.Now()
+	match, e := hash.CmpPassword(hashed, plaintext)
+	result := "match"
+
+	if e != nil {
+		result = "error"
+	} else if !match {
+		result = "mismatch"
+	}
+
+	metrics.PasswordVerify.WithLabelValues(result).Observe(time.Since(start).Seconds())
+
+	return match, e
 }
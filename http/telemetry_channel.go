@@ -1,45 +1,306 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/acc"
+	"github.com/blacksfk/are_hub/alert"
+	"github.com/blacksfk/are_hub/frame"
+	"github.com/blacksfk/are_hub/lap"
+	"github.com/blacksfk/are_hub/metrics"
+	"github.com/blacksfk/are_hub/strategy"
+	uf "github.com/blacksfk/microframework"
 	"nhooyr.io/websocket"
 )
 
 const (
-	// Maximum number of clients listening.
+	// Maximum number of clients listening unless configured otherwise. See
+	// are_hub.Limits.
 	MAX_SUBS = 10
+
+	// Minimum time between strategy estimates sent to subscribers. Estimates are
+	// always sent when a lap is completed.
+	STRATEGY_INTERVAL = time.Second
+
+	// Prefix of alert rule fields which refer to the strategy estimate rather
+	// than frames. Eg. "strategy.lapsRemaining".
+	STRATEGY_PREFIX = "strategy."
 )
 
-// Wraps are_hub.Channel with a publisher client, a map of subscriber clients,
+// Subscriber capacity and buffering of channels unless configured otherwise.
+var defaultLimits = are_hub.Limits{
+	MaxSubscribers: MAX_SUBS,
+	BufferLength:   BUF_LEN,
+	SlowPolicy:     are_hub.POLICY_DISCONNECT,
+}
+
+// Frame validation functions by schema name. See are_hub.Channel.Schema.
+var schemas = map[string]func([]byte) error{
+	acc.SCHEMA: acc.Validate,
+}
+
+// Fields used to segment frames into laps by schema name.
+var lapFields = map[string]lap.Fields{
+	acc.SCHEMA: acc.LapFields,
+}
+
+// Fields used to estimate strategy by schema name.
+var strategyFields = map[string]strategy.Fields{
+	acc.SCHEMA: acc.StrategyFields,
+}
+
+// Fields used to fill the empty metadata of channels by schema name.
+var metadataFields = map[string]are_hub.MetadataFields{
+	acc.SCHEMA: acc.MetadataFields,
+}
+
+// Wraps are_hub.Channel with a stream per car, a map of subscriber clients,
 // and mutual exclusion locks.
 type telemetryChannel struct {
+	// The channel's name, password, visibility, and metadata are guarded by
+	// infoMtx as they are replaced when the channel is updated. See update.
 	*are_hub.Channel
+	infoMtx sync.Mutex
 
+	// Guards the publisher slots of streams.
 	pubMtx sync.Mutex
 	pub    *websocket.Conn
 
 	subMtx sync.Mutex
 	subs   map[string]*client
+
+	// Sequence number of the last telemetry frame broadcast on any stream.
+	// Guarded by subMtx.
+	seq uint64
+
+	// Held while numbering a frame until subMtx is locked so that frames are
+	// broadcast in the order they were numbered without holding subMtx while
+	// waiting for the backplane. Locked before subMtx.
+	seqMtx sync.Mutex
+
+	// The last frames broadcast (up to the buffer length, oldest first) so that
+	// server-sent event clients can resume. Guarded by subMtx.
+	recent []*envelope
+
+	// Subscriber capacity and buffering with the server's defaults applied.
+	// Guarded by subMtx.
+	limits are_hub.Limits
+
+	// Subscribers dropped for being too slow. Guarded by subMtx.
+	dropped uint64
+
+	// Spectators of unlisted and public channels and their capacity and
+	// downsampling. Separate from the subscribers. Guarded by subMtx.
+	specs      map[string]*client
+	specLimits SpectatorLimits
+
+	// Streams by car number. Channels without cars have a single stream keyed
+	// by the empty string. The map is not modified after creation.
+	streams map[string]*stream
+
+	// Where the channel is in its lifecycle. See channelState.
+	state atomic.Int32
+
+	// Relays messages to and from the other nodes serving the channel and
+	// coordinates its publisher slots.
+	bp   are_hub.Backplane
+	node string
+
+	// Stops relaying messages from the other nodes. Nil unless the channel has
+	// subscribers. Guarded by subMtx.
+	unsubscribe func()
+}
+
+// Lifecycle of a telemetry channel. Channels only move forward through the
+// states: opening, live, closing, and closed.
+type channelState int32
+
+const (
+	// Created but not yet live. Publishers and subscribers are rejected.
+	stateOpening channelState = iota
+
+	// Accepting publishers and subscribers.
+	stateLive
+
+	// Closed by an administrator or because the channel was changed or
+	// deleted. Publishers and subscribers are rejected while the connected
+	// subscribers are disconnected.
+	stateClosing
+
+	// Every subscriber has been disconnected.
+	stateClosed
+)
+
+// Names of the states reported by the admin API.
+var stateNames = map[channelState]string{
+	stateOpening: "opening",
+	stateLive:    "live",
+	stateClosing: "closing",
+	stateClosed:  "closed",
 }
 
-func newTelemetryChannel(c *are_hub.Channel) *telemetryChannel {
-	subs := make(map[string]*client, MAX_SUBS)
+// Returned when publishing to a channel that is not live.
+var errClosed = uf.HttpError{Code: http.StatusGone, Message: "Channel closed."}
+
+// Frames published by a single car (or the only publisher of channels without
+// cars) and the state derived from them. Driver swaps hand the publisher slot
+// over to a new driver without resetting the derived state.
+type stream struct {
+	car string
+
+	// Driver currently publishing. Guarded by pubMtx.
+	driver string
+
+	// Driver taking over the slot with their first frame. Guarded by pubMtx.
+	pending string
+
+	// Fires when no frames have been broadcast for publisherTimeout. The slot
+	// can be claimed by any driver once offline. Guarded by pubMtx.
+	idle    *time.Timer
+	offline bool
+
+	// When the last frame was published. Guarded by pubMtx.
+	last time.Time
+
+	// When the backplane last granted the slot to driver. The claim is only
+	// renewed once half of publisherTimeout has passed. Guarded by pubMtx.
+	claimed time.Time
+
+	// Frames broadcast. Guarded by subMtx.
+	frames uint64
 
-	return &telemetryChannel{c, sync.Mutex{}, nil, sync.Mutex{}, subs}
+	// Detects completed laps. Nil if the channel's schema does not define lap
+	// fields. Guarded by subMtx so that frames are segmented in order.
+	segmenter *lap.Segmenter
+
+	// Estimates strategy. Nil if the channel's schema does not define strategy
+	// fields. Guarded by subMtx.
+	calculator *strategy.Calculator
+
+	// When the last strategy estimate was sent. Guarded by subMtx.
+	estimated time.Time
+
+	// When the last frame was sent to spectators. Guarded by subMtx.
+	spectated time.Time
+
+	// Evaluates the channel's alert rules. Guarded by subMtx.
+	rules *alert.Engine
+}
+
+// Results derived from a frame which must be persisted.
+type derived struct {
+	// Lap completed by the frame or nil.
+	lap *are_hub.Lap
+
+	// Identifier of the session started by the frame. Empty if the frame did
+	// not start a session.
+	session string
+
+	// Alerts fired by the frame.
+	alerts []*are_hub.Alert
+
+	// Empty metadata of the channel filled from the frame or nil.
+	metadata *are_hub.Metadata
+}
+
+// A frame broadcast on a team channel tagged with the car and driver that
+// published it.
+type teamFrame struct {
+	Car    string          `json:"car"`
+	Driver string          `json:"driver"`
+	Frame  json.RawMessage `json:"frame"`
+}
+
+func newTelemetryChannel(c *are_hub.Channel, rules []are_hub.AlertRule) *telemetryChannel {
+	limits := c.Limits.Or(defaultLimits)
+	subs := make(map[string]*client, limits.MaxSubscribers)
+	tc := &telemetryChannel{
+		Channel:    c,
+		subs:       subs,
+		limits:     limits,
+		specs:      make(map[string]*client),
+		specLimits: defaultSpectatorLimits,
+		streams:    make(map[string]*stream),
+	}
+	cars := c.Cars
+
+	if len(cars) == 0 {
+		cars = []string{""}
+	}
+
+	for _, car := range cars {
+		s := &stream{car: car, rules: alert.NewEngine(rules)}
+
+		if f, ok := lapFields[c.Schema]; ok {
+			s.segmenter = lap.NewSegmenter(c.ID, f)
+		}
+
+		if f, ok := strategyFields[c.Schema]; ok {
+			s.calculator = strategy.NewCalculator(f)
+		}
+
+		tc.streams[car] = s
+	}
+
+	return tc
+}
+
+// Get where the channel is in its lifecycle.
+func (c *telemetryChannel) lifecycle() channelState {
+	return channelState(c.state.Load())
+}
+
+// Move the channel from one state to another. Returns false if the channel
+// is not in from.
+func (c *telemetryChannel) transition(from, to channelState) bool {
+	return c.state.CompareAndSwap(int32(from), int32(to))
+}
+
+// Make an opening channel live. Returns false if the channel is not opening.
+func (c *telemetryChannel) open() bool {
+	return c.transition(stateOpening, stateLive)
+}
+
+// Check whether the channel is a team channel with a stream per car.
+func (c *telemetryChannel) team() bool {
+	return len(c.Cars) > 0
+}
+
+// Get the stream of car. Team channels require a car and other channels
+// must not be given one.
+func (c *telemetryChannel) stream(car string) (*stream, error) {
+	if len(car) == 0 && c.team() {
+		return nil, uf.BadRequest("Car required.")
+	}
+
+	s, ok := c.streams[car]
+
+	if !ok {
+		return nil, uf.NotFound("No car matching: " + car)
+	}
+
+	return s, nil
 }
 
 // Add a publisher.
 func (c *telemetryChannel) setPub(pub *websocket.Conn) error {
+	c.pubMtx.Lock()
+	defer c.pubMtx.Unlock()
+
 	if c.pub != nil {
 		return wsChannelFull()
 	}
 
-	c.pubMtx.Lock()
-	defer c.pubMtx.Unlock()
-
 	c.pub = pub
 
 	return nil
@@ -53,16 +314,182 @@ func (c *telemetryChannel) removePub() {
 	c.pub = nil
 }
 
-// Add a subscriber to the telemetryChannel.
-func (c *telemetryChannel) addSub(sub *client) error {
-	if len(c.subs) == MAX_SUBS {
-		return wsChannelFull()
+// Claim the publisher slot of slot.Car for slot.Driver. The slot is granted if
+// it is vacant, already held by the driver, its publisher has gone offline, or
+// it was handed over to the driver. The channel's backplane decides as the slot
+// may be held on another node, but is only asked again once the driver's claim
+// is close to expiring. Returns the stream and the driver that
+// previously held the slot.
+func (c *telemetryChannel) claim(ctx context.Context, slot Slot) (*stream, string, error) {
+	if c.lifecycle() != stateLive {
+		return nil, "", errClosed
+	}
+
+	s, e := c.stream(slot.Car)
+
+	if e != nil {
+		return nil, "", e
+	}
+
+	var held string
+
+	c.pubMtx.Lock()
+	renew := s.driver != slot.Driver || time.Since(s.claimed) >= publisherTimeout/2
+	c.pubMtx.Unlock()
+
+	if renew {
+		// the slot may be held or handed over on another node so the
+		// backplane decides rather than this node
+		var ok bool
+		held, ok, e = c.bp.Claim(ctx, c.ID, slot.Car, slot.Driver, publisherTimeout)
+
+		if e != nil {
+			return nil, "", e
+		}
+
+		if !ok {
+			return nil, "", conflict(slot.Car, held)
+		}
+	}
+
+	c.pubMtx.Lock()
+	defer c.pubMtx.Unlock()
+
+	if renew {
+		s.claimed = time.Now()
+	}
+
+	prev := s.driver
+	handed := len(s.pending) > 0 && s.pending == slot.Driver
+
+	if len(held) > 0 {
+		prev = held
+	}
+
+	if handed {
+		s.pending = ""
+	}
+
+	s.driver = slot.Driver
+
+	return s, prev, nil
+}
+
+// Create the error returned when car is being published by another driver.
+func conflict(car, driver string) error {
+	msg := fmt.Sprintf("Car %s is being published by %s.", car, driver)
+
+	return uf.HttpError{Code: http.StatusConflict, Message: msg}
+}
+
+// Hand the publisher slot of car over to driver. The driver takes over with
+// their first frame.
+func (c *telemetryChannel) handoff(ctx context.Context, car, driver string) error {
+	s, e := c.stream(car)
+
+	if e != nil {
+		return e
+	}
+
+	// the driver may take over on another node
+	e = c.bp.Handoff(ctx, c.ID, car, driver)
+
+	if e != nil {
+		return e
+	}
+
+	c.pubMtx.Lock()
+	defer c.pubMtx.Unlock()
+
+	s.pending = driver
+
+	// the current driver must claim the slot again to find out it was handed
+	// over
+	s.claimed = time.Time{}
+
+	return nil
+}
+
+// Restart the idle timer of s's publisher. offline is called once no frames
+// have been broadcast on s for publisherTimeout.
+func (c *telemetryChannel) active(s *stream, offline func()) {
+	c.pubMtx.Lock()
+	defer c.pubMtx.Unlock()
+
+	if c.lifecycle() != stateLive {
+		// don't restart timers stopped when closing
+		return
+	}
+
+	s.offline = false
+	s.last = time.Now().UTC()
+
+	if s.idle != nil {
+		s.idle.Reset(publisherTimeout)
+
+		return
 	}
 
+	s.idle = time.AfterFunc(publisherTimeout, func() {
+		c.pubMtx.Lock()
+		s.offline = true
+		c.pubMtx.Unlock()
+
+		offline()
+	})
+}
+
+// Add a subscriber to the telemetryChannel.
+func (c *telemetryChannel) addSub(sub *client) error {
 	// lock the mutex to prevent changes to the map
 	c.subMtx.Lock()
 	defer c.subMtx.Unlock()
 
+	return c.add(sub)
+}
+
+// Add a subscriber to the telemetryChannel and queue the recently broadcast
+// frames with a sequence number greater than last. Frames older than the recent
+// history are lost.
+func (c *telemetryChannel) resumeSub(sub *client, last uint64) error {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	e := c.add(sub)
+
+	if e != nil {
+		return e
+	}
+
+	// queue while holding the lock so that no frames are missed or duplicated
+	// between the replayed frames and newly broadcast frames
+	for _, env := range c.recent {
+		if env.seq <= last || !sub.follows(env) {
+			continue
+		}
+
+		select {
+		case sub.buffer <- env:
+		default:
+			// the buffer length was lowered after the client was created;
+			// frames older than its buffer are lost
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Add a subscriber. subMtx must be held.
+func (c *telemetryChannel) add(sub *client) error {
+	if c.lifecycle() != stateLive {
+		return wsChannelClosed()
+	}
+
+	if len(c.subs) >= c.limits.MaxSubscribers {
+		return wsChannelFull()
+	}
+
 	// check if the subscriber ID already exists
 	_, ok := c.subs[sub.id]
 
@@ -73,30 +500,621 @@ func (c *telemetryChannel) addSub(sub *client) error {
 
 	// ID doesn't exist; add the subscriber
 	c.subs[sub.id] = sub
+	c.listen()
 
 	return nil
 }
 
+// Receive the messages broadcast on other nodes if not already. subMtx must be
+// held.
+func (c *telemetryChannel) listen() {
+	if c.unsubscribe == nil {
+		c.unsubscribe = c.bp.Subscribe(c.ID, c.node, c.relay)
+	}
+}
+
 func (c *telemetryChannel) removeSub(sub *client) {
 	c.subMtx.Lock()
 	defer c.subMtx.Unlock()
 
-	delete(c.subs, sub.id)
+	c.remove(sub.id)
+}
+
+// Remove the subscriber matching id. Messages broadcast on other nodes are no
+// longer received once no subscribers or spectators remain. subMtx must be
+// held.
+func (c *telemetryChannel) remove(id string) {
+	delete(c.subs, id)
+	c.ignore()
+}
+
+// Stop receiving the messages broadcast on other nodes if no subscribers or
+// spectators remain. subMtx must be held.
+func (c *telemetryChannel) ignore() {
+	if len(c.subs) == 0 && len(c.specs) == 0 && c.unsubscribe != nil {
+		c.unsubscribe()
+		c.unsubscribe = nil
+	}
+}
+
+// Get the length of the message buffer subscribers should be created with.
+func (c *telemetryChannel) bufferLength() int {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	return c.limits.BufferLength
+}
+
+// Replace the subscriber capacity and buffering. Connected subscribers keep
+// their buffers and are not dropped if the capacity is lowered below the
+// number connected.
+func (c *telemetryChannel) setLimits(limits are_hub.Limits) {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	c.limits = limits
+	c.trim()
+}
+
+// Replace the channel's name, password, visibility, and metadata with those of
+// ch.
+func (c *telemetryChannel) update(ch *are_hub.Channel) {
+	c.infoMtx.Lock()
+	defer c.infoMtx.Unlock()
+
+	c.Channel.Name = ch.Name
+	c.Channel.Password = ch.Password
+	c.Channel.Visibility = ch.Visibility
+	c.Channel.Metadata = ch.Metadata
+}
+
+// Check whether the channel may currently be spectated.
+func (c *telemetryChannel) spectatable() bool {
+	c.infoMtx.Lock()
+	defer c.infoMtx.Unlock()
+
+	return c.Channel.Spectatable()
+}
+
+// Fill the empty sim, car model, and track of the channel from f. Returns the
+// fields filled or nil if none were.
+func (c *telemetryChannel) describe(fields are_hub.MetadataFields, f frame.Frame) *are_hub.Metadata {
+	c.infoMtx.Lock()
+	defer c.infoMtx.Unlock()
+
+	m := &are_hub.Metadata{}
+	filled := false
+	fill := func(current, into *string, v string) {
+		if len(*current) == 0 && len(v) > 0 {
+			*current, *into = v, v
+			filled = true
+		}
+	}
+
+	fill(&c.Channel.Sim, &m.Sim, fields.Sim)
+
+	if v, ok := f.String(fields.CarModel); ok {
+		fill(&c.Channel.CarModel, &m.CarModel, v)
+	}
+
+	if v, ok := f.String(fields.Track); ok {
+		fill(&c.Channel.Track, &m.Track, v)
+	}
+
+	if !filled {
+		return nil
+	}
+
+	return m
+}
+
+// Get the channel's name.
+func (c *telemetryChannel) name() string {
+	c.infoMtx.Lock()
+	defer c.infoMtx.Unlock()
+
+	return c.Channel.Name
+}
+
+// Get the channel's password hash.
+func (c *telemetryChannel) password() string {
+	c.infoMtx.Lock()
+	defer c.infoMtx.Unlock()
+
+	return c.PasswordStr()
+}
+
+// Discard the oldest recent frames beyond the buffer length. subMtx must be held.
+func (c *telemetryChannel) trim() {
+	if n := len(c.recent) - c.limits.BufferLength; n > 0 {
+		c.recent = append(c.recent[:0], c.recent[n:]...)
+	}
+}
+
+// Get the sequence number of the last telemetry frame broadcast.
+func (c *telemetryChannel) sequence() uint64 {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	return c.seq
 }
 
-// Send a message to all subscribers on this channel.
-func (c *telemetryChannel) broadcast(bytes []byte) {
+// Send a telemetry frame (JSON encoded) published by driver on s to all
+// subscribers on this channel. The frame is assigned the next sequence number.
+// Laps completed and alerts fired by the frame are sent to all subscribers
+// after the frame and returned.
+func (c *telemetryChannel) broadcast(ctx context.Context, s *stream, driver string, raw []byte) (*derived, error) {
+	// validate outside of the lock as it may be expensive. Frames are checked
+	// before they are numbered so that rejected frames don't leave gaps
+	if !json.Valid(raw) {
+		return nil, errors.New("Frame is not valid JSON.")
+	}
+
+	if fn, ok := schemas[c.Schema]; ok {
+		e := fn(raw)
+
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	var data interface{} = json.RawMessage(raw)
+
+	if c.team() {
+		data = teamFrame{s.car, driver, json.RawMessage(raw)}
+	}
+
+	// number the frame before locking the subscribers so that they aren't held
+	// up by the backplane
+	c.seqMtx.Lock()
+	seq, e := c.next(ctx)
+
+	if e != nil {
+		c.seqMtx.Unlock()
+
+		return nil, e
+	}
+
 	// prevent modification to the current subscribers while sending to the buffered channels
+	c.subMtx.Lock()
+	c.seqMtx.Unlock()
+	defer c.subMtx.Unlock()
+
+	// encode the frame once rather than per subscriber
+	env, e := newEnvelope(dataResponse(seq, data))
+
+	if e != nil {
+		return nil, e
+	}
+
+	c.seq = seq
+	s.frames++
+	env.car = s.car
+
+	// remember the frame for resuming clients
+	c.recent = append(c.recent, env)
+	c.trim()
+	c.fanout(env)
+
+	_, describes := metadataFields[c.Schema]
+	spectate := c.spectating(s)
+	derives := s.segmenter != nil || s.calculator != nil || s.rules.Len() > 0 || describes
+
+	if !spectate && !derives {
+		return nil, nil
+	}
+
+	// decode the frame once for both the spectators and the derived results
+	f, e := frame.Decode(raw)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if spectate {
+		c.spectate(s, driver, seq, f)
+	}
+
+	if !derives {
+		// nothing to derive from the frame
+		return nil, nil
+	}
+
+	return c.derive(s, driver, f)
+}
+
+// Segment the frame most recently broadcast on s into laps, update the strategy
+// estimate, and evaluate the alert rules, sending the results to all
+// subscribers. subMtx must be held.
+func (c *telemetryChannel) derive(s *stream, driver string, f frame.Frame) (*derived, error) {
+	d := &derived{}
+
+	if s.segmenter != nil {
+		prev := s.segmenter.Session()
+		d.lap = s.segmenter.Frame(f, c.seq)
+
+		if session := s.segmenter.Session(); session != prev {
+			d.session = session
+		}
+	}
+
+	if d.lap != nil {
+		d.lap.Car = s.car
+		d.lap.Driver = driver
+		env, e := newEnvelope(lapResponse(d.lap))
+
+		if e != nil {
+			return nil, e
+		}
+
+		env.car = s.car
+		c.fanout(env)
+	}
+
+	if s.calculator != nil {
+		e := c.estimate(s, f, d.lap)
+
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	d.alerts = s.rules.Evaluate(c.lookup(s, f), time.Now())
+
+	for _, a := range d.alerts {
+		a.ChannelID = c.ID
+		a.Car = s.car
+		a.Seq = c.seq
+		env, e := newEnvelope(alertResponse(a))
+
+		if e != nil {
+			return nil, e
+		}
+
+		env.car = s.car
+		c.fanout(env)
+	}
+
+	if fields, ok := metadataFields[c.Schema]; ok {
+		d.metadata = c.describe(fields, f)
+	}
+
+	return d, nil
+}
+
+// Update the strategy estimate of s with the frame most recently broadcast and
+// the lap it completed (if any). The estimate is sent to all subscribers when a
+// lap is completed or at most every STRATEGY_INTERVAL. subMtx must be held.
+func (c *telemetryChannel) estimate(s *stream, f frame.Frame, completed *are_hub.Lap) error {
+	s.calculator.Frame(f)
+
+	if completed != nil {
+		s.calculator.Lap(completed)
+	} else if time.Since(s.estimated) < STRATEGY_INTERVAL {
+		// don't flood subscribers with estimates between laps
+		return nil
+	}
+
+	s.estimated = time.Now()
+	est := s.calculator.Estimate()
+	est.Car = s.car
+	env, e := newEnvelope(strategyResponse(c.seq, est))
+
+	if e != nil {
+		return e
+	}
+
+	env.car = s.car
+	c.fanout(env)
+
+	return nil
+}
+
+// Create a function that finds alert rule fields in f or in the strategy
+// estimate of s. subMtx must be held while calling the function.
+func (c *telemetryChannel) lookup(s *stream, f frame.Frame) alert.Lookup {
+	var est *strategy.Estimate
+
+	return func(path string) (float64, bool) {
+		name := strings.TrimPrefix(path, STRATEGY_PREFIX)
+
+		if name == path {
+			return f.Number(path)
+		}
+
+		if s.calculator == nil {
+			return 0, false
+		}
+
+		if est == nil {
+			// only estimate once per frame
+			e := s.calculator.Estimate()
+			est = &e
+		}
+
+		return est.Value(name)
+	}
+}
+
+// Replace the alert rules evaluated against frames.
+func (c *telemetryChannel) setRules(rules []are_hub.AlertRule) {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	for _, s := range c.streams {
+		s.rules.SetRules(rules)
+	}
+}
+
+// Get the current strategy estimate of s. Returns false if the channel's schema
+// does not define strategy fields.
+func (c *telemetryChannel) strategy(s *stream) (strategy.Estimate, bool) {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	if s.calculator == nil {
+		return strategy.Estimate{}, false
+	}
+
+	est := s.calculator.Estimate()
+	est.Car = s.car
+
+	return est, true
+}
+
+// Send a chat message to all subscribers on this channel.
+func (c *telemetryChannel) chat(msg *are_hub.Message) error {
+	env, e := newEnvelope(chatResponse(msg))
+
+	if e != nil {
+		return e
+	}
+
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	c.fanout(env)
+
+	return nil
+}
+
+// Tell the subscribers following s that driver has taken over from prev.
+func (c *telemetryChannel) swap(s *stream, prev, driver string) error {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+
+	env, e := newEnvelope(swapResponse(c.seq, streamInfo{Car: s.car, Driver: driver, Previous: prev}))
+
+	if e != nil {
+		return e
+	}
+
+	env.car = s.car
+	c.fanout(env)
+
+	return nil
+}
+
+// Get the sequence number of the next frame from the backplane so that frames
+// broadcast on any node are numbered in a single sequence. seqMtx must be held.
+func (c *telemetryChannel) next(ctx context.Context) (uint64, error) {
+	seq, e := c.bp.Sequence(ctx, c.ID)
+
+	if e != nil {
+		return 0, uf.HttpError{Code: http.StatusServiceUnavailable, Message: "Sequence unavailable: " + e.Error()}
+	}
+
+	return seq, nil
+}
+
+// Send env to the subscribers on this node and relay it to the other nodes
+// serving the channel. subMtx must be held.
+func (c *telemetryChannel) fanout(env *envelope) {
+	c.send(env)
+	c.bp.Publish(are_hub.Broadcast{
+		ChannelID: c.ID,
+		Node:      c.node,
+		Status:    int(env.status),
+		Seq:       env.seq,
+		Car:       env.car,
+		Data:      env.data,
+	})
+}
+
+// Send a message broadcast on another node to the subscribers on this node.
+// Frames are remembered for resuming clients.
+func (c *telemetryChannel) relay(b are_hub.Broadcast) {
+	env, e := newEnvelope(response{Status: websocket.StatusCode(b.Status), Seq: b.Seq, Data: b.Data})
+
+	if e != nil {
+		log.Printf("Failed to relay message on %s: %v\n", c.ID, e)
+
+		return
+	}
+
+	env.car = b.Car
+
+	if s, ok := c.streams[env.car]; ok && env.status == WS_SWAP {
+		// the slot was taken over on another node so the driver publishing
+		// on this node must claim it again
+		c.pubMtx.Lock()
+		s.claimed = time.Time{}
+		c.pubMtx.Unlock()
+	}
+
 	c.subMtx.Lock()
 	defer c.subMtx.Unlock()
 
+	if env.status == WS_OK {
+		if env.seq > c.seq {
+			c.seq = env.seq
+		}
+
+		s, ok := c.streams[env.car]
+
+		if ok {
+			s.frames++
+		}
+
+		c.recent = append(c.recent, env)
+		c.trim()
+
+		if ok {
+			c.spectateRelayed(s, b)
+		}
+	}
+
+	c.send(env)
+}
+
+// Send an encoded message to all subscribers on this channel following the
+// message's car. Subscribers with full buffers are handled according to their
+// slow subscriber policy. subMtx must be held.
+func (c *telemetryChannel) send(env *envelope) {
+	// subscribers with the block policy share a single deadline so that the
+	// publisher is blocked for at most blockDeadline
+	var deadline time.Time
+
 	for id, sub := range c.subs {
-		select {
-		case sub.buffer <- bytes:
+		if !sub.follows(env) {
+			continue
+		}
+
+		if c.enqueue(sub, env) {
+			continue
+		}
+
+		// subscriber's buffer is full
+		policy := sub.policy
+
+		if len(policy) == 0 {
+			policy = c.limits.SlowPolicy
+		}
+
+		switch policy {
+		case are_hub.POLICY_DROP_NEWEST:
+			c.miss(sub, env)
+		case are_hub.POLICY_DROP_OLDEST:
+			c.discard(sub)
+
+			if !c.enqueue(sub, env) {
+				c.miss(sub, env)
+			}
+		case are_hub.POLICY_CONFLATE:
+			for c.discard(sub) {
+			}
+
+			if !c.enqueue(sub, env) {
+				c.miss(sub, env)
+			}
+		case are_hub.POLICY_BLOCK:
+			if deadline.IsZero() {
+				deadline = time.Now().Add(blockDeadline)
+			}
+
+			if !c.wait(sub, env, deadline) {
+				c.disconnect(id, sub)
+			}
 		default:
-			// subscriber's buffer is full; drop them like a 10 tonne hammer
-			go sub.drop(WS_ERROR_TIMEOUT, "Message buffer full")
-			delete(c.subs, id)
+			c.disconnect(id, sub)
 		}
 	}
 }
+
+// Queue env for sub without blocking. The subscriber is told how many frames it
+// missed once there is room. Returns false if sub's buffer is full. subMtx
+// must be held.
+func (c *telemetryChannel) enqueue(sub *client, env *envelope) bool {
+	select {
+	case sub.buffer <- env:
+		c.notify(sub)
+
+		return true
+	default:
+		return false
+	}
+}
+
+// Queue env for sub, blocking until there is room or deadline passes. Returns
+// false if the deadline passed. subMtx must be held.
+func (c *telemetryChannel) wait(sub *client, env *envelope, deadline time.Time) bool {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case sub.buffer <- env:
+		c.notify(sub)
+
+		return true
+	case <-sub.done:
+		return false
+	case <-timer.C:
+		return false
+	}
+}
+
+// Tell sub how many frames it missed if there is room in its buffer.
+// subMtx must be held.
+func (c *telemetryChannel) notify(sub *client) {
+	if sub.missed == 0 {
+		return
+	}
+
+	env, e := newEnvelope(missedResponse(c.seq, sub.missed))
+
+	if e != nil {
+		return
+	}
+
+	env.missed = sub.missed
+
+	select {
+	case sub.buffer <- env:
+		sub.missed = 0
+	default:
+		// try again with the next message
+	}
+}
+
+// Count env as missed by sub if it is a telemetry frame. Other messages (eg.
+// chat) are not counted so that the missed count matches the gap in sequence
+// numbers. subMtx must be held.
+func (c *telemetryChannel) miss(sub *client, env *envelope) {
+	if env.status != WS_OK {
+		return
+	}
+
+	sub.missed++
+	sub.lost++
+
+	metrics.FramesDropped.WithLabelValues(c.ID).Inc()
+}
+
+// Discard the oldest message queued for sub. Discarded notices of missed
+// frames are carried over to the next notice rather than counted as missed.
+// Returns false if the buffer is empty. subMtx must be held.
+func (c *telemetryChannel) discard(sub *client) bool {
+	select {
+	case env := <-sub.buffer:
+		if env.status == WS_MISSED {
+			sub.missed += env.missed
+		} else {
+			c.miss(sub, env)
+		}
+
+		return true
+	default:
+		return false
+	}
+}
+
+// Drop sub for being too slow. subMtx must be held.
+func (c *telemetryChannel) disconnect(id string, sub *client) {
+	// drop them like a 10 tonne hammer
+	go sub.drop(WS_ERROR_TIMEOUT, "Message buffer full")
+	c.remove(id)
+	c.dropped++
+
+	metrics.FramesDropped.WithLabelValues(c.ID).Inc()
+	metrics.SubscribersDropped.WithLabelValues(c.ID).Inc()
+}
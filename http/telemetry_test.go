@@ -0,0 +1,1282 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/acc"
+	"github.com/blacksfk/are_hub/backplane"
+	"github.com/blacksfk/are_hub/hash"
+	"github.com/blacksfk/are_hub/mock"
+	"github.com/blacksfk/are_hub/strategy"
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+	"nhooyr.io/websocket"
+)
+
+const (
+	// Telemetry channel test data.
+	testChannelID       = "60f6b5f3c2a1e4d9b8a7c6d5"
+	testChannelPassword = "abc123"
+)
+
+// Hashing is expensive so only do it once.
+var testHash = func() string {
+	h, e := hash.Password(testChannelPassword)
+
+	if e != nil {
+		panic(e)
+	}
+
+	return h
+}()
+
+// Test hub with mocked repositories.
+type testHub struct {
+	*httptest.Server
+	ts *TelemetryServer
+
+	mtx      sync.Mutex
+	messages []are_hub.Message
+	laps     []are_hub.Lap
+	alerts   []are_hub.Alert
+	emitted  []are_hub.Event
+	filled   []are_hub.Metadata
+
+	// schema and cars of the channel when it is found
+	schema string
+	cars   []string
+
+	// alert rules of the channel when it goes live
+	rules []are_hub.AlertRule
+
+	// subscriber capacity and buffering of the channel when it is found
+	limits are_hub.Limits
+
+	// visibility of the channel when it is found
+	visibility string
+
+	// sessions redeemed from invitations by token hash
+	sessions map[string]are_hub.Session
+}
+
+// Create a test hub serving the telemetry routes with a single channel.
+func newTestHub(t *testing.T) *testHub {
+	hub := &testHub{sessions: make(map[string]are_hub.Session)}
+	channels := &mock.ChannelRepo{
+		FindIDFunc: func(_ context.Context, id string) (*are_hub.Channel, error) {
+			if id != testChannelID {
+				return nil, are_hub.NewNoObjectsFound("channels", "id == "+id)
+			}
+
+			c := are_hub.NewChannel("Audi Sport Team WRT", testHash)
+			c.ID = id
+			c.Schema = hub.schema
+			c.Cars = hub.cars
+			c.Limits = hub.limits
+			c.Visibility = hub.visibility
+
+			return c, nil
+		},
+		FillIDFunc: func(_ context.Context, id string, m are_hub.Metadata) (*are_hub.Channel, error) {
+			hub.mtx.Lock()
+			defer hub.mtx.Unlock()
+
+			hub.filled = append(hub.filled, m)
+
+			return &are_hub.Channel{Metadata: m}, nil
+		},
+	}
+
+	messages := &mock.MessageRepo{
+		InsertFunc: func(_ context.Context, a are_hub.Archetype) error {
+			hub.mtx.Lock()
+			defer hub.mtx.Unlock()
+
+			hub.messages = append(hub.messages, *a.(*are_hub.Message))
+
+			return nil
+		},
+	}
+
+	laps := &mock.LapRepo{
+		InsertFunc: func(_ context.Context, a are_hub.Archetype) error {
+			hub.mtx.Lock()
+			defer hub.mtx.Unlock()
+
+			hub.laps = append(hub.laps, *a.(*are_hub.Lap))
+
+			return nil
+		},
+	}
+
+	rules := &mock.AlertRuleRepo{
+		FindChannelIDFunc: func(context.Context, string) ([]are_hub.AlertRule, error) {
+			return hub.rules, nil
+		},
+	}
+
+	alerts := &mock.AlertRepo{
+		InsertFunc: func(_ context.Context, a are_hub.Archetype) error {
+			hub.mtx.Lock()
+			defer hub.mtx.Unlock()
+
+			hub.alerts = append(hub.alerts, *a.(*are_hub.Alert))
+
+			return nil
+		},
+	}
+
+	events := &mock.Emitter{
+		EmitFunc: func(ev are_hub.Event) {
+			hub.mtx.Lock()
+			defer hub.mtx.Unlock()
+
+			hub.emitted = append(hub.emitted, ev)
+		},
+	}
+
+	sessions := &mock.SessionRepo{
+		FindTokenFunc: func(_ context.Context, hash string) (*are_hub.Session, error) {
+			hub.mtx.Lock()
+			defer hub.mtx.Unlock()
+
+			s, ok := hub.sessions[hash]
+
+			if !ok {
+				return nil, are_hub.NewNoObjectsFound("sessions", "unexpired token")
+			}
+
+			return &s, nil
+		},
+	}
+
+	opts := &websocket.AcceptOptions{CompressionMode: websocket.CompressionDisabled}
+	hub.ts = NewTelemetryServer(opts, TelemetryRepos{
+		Channels: channels,
+		Messages: messages,
+		Laps:     laps,
+		Rules:    rules,
+		Alerts:   alerts,
+		Sessions: sessions,
+	}, events)
+
+	router := httprouter.New()
+	router.Handler(http.MethodGet, "/subscribe/:id", uf.NewHttpTestHandler(hub.ts.Subscribe))
+	router.Handler(http.MethodGet, "/subscribe/:id/events", uf.NewHttpTestHandler(hub.ts.Events))
+	router.Handler(http.MethodGet, "/spectate/:id", uf.NewHttpTestHandler(hub.ts.Spectate))
+	router.Handler(http.MethodPost, "/publish/:id", uf.NewHttpTestHandler(hub.ts.Publish))
+	router.Handler(http.MethodPost, "/publish/:id/swap", uf.NewHttpTestHandler(hub.ts.Swap))
+
+	hub.Server = httptest.NewServer(router)
+	t.Cleanup(hub.Close)
+
+	return hub
+}
+
+// Create an emitter which discards events.
+func discardEvents() *mock.Emitter {
+	return &mock.Emitter{EmitFunc: func(are_hub.Event) {}}
+}
+
+// Create an audit repository discarding every event.
+func discardAudits() *mock.AuditRepo {
+	return &mock.AuditRepo{InsertFunc: func(context.Context, are_hub.Archetype) error {
+		return nil
+	}}
+}
+
+// Create a session repository discarding every deletion.
+func discardSessions() *mock.SessionRepo {
+	return &mock.SessionRepo{
+		DeleteInviteIDFunc:  func(context.Context, string) error { return nil },
+		DeleteChannelIDFunc: func(context.Context, string) error { return nil },
+	}
+}
+
+// Get the live test channel.
+func (hub *testHub) channel(t *testing.T) *telemetryChannel {
+	tc, ok := hub.ts.channels.get(testChannelID)
+
+	if !ok {
+		t.Fatal("Channel is not live")
+	}
+
+	return tc
+}
+
+// Create a telemetry server without any live channels.
+func testTelemetry() *TelemetryServer {
+	return NewTelemetryServer(nil, TelemetryRepos{}, discardEvents())
+}
+
+// Connect a subscriber and complete the password challenge.
+func (hub *testHub) subscribe(t *testing.T, id, pw string) *websocket.Conn {
+	return hub.subscribeCar(t, id, pw, "")
+}
+
+// Connect a subscriber following car (all cars if empty) and complete the
+// password challenge.
+func (hub *testHub) subscribeCar(t *testing.T, id, pw, car string) *websocket.Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	url := "ws" + strings.TrimPrefix(hub.URL, "http") + "/subscribe/" + id + "?car=" + car
+	conn, _, e := websocket.Dial(ctx, url, nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	t.Cleanup(func() { conn.Close(websocket.StatusNormalClosure, "") })
+
+	res := readResponse(t, conn)
+
+	if res.Status != WS_CHALLENGE_PASSWORD {
+		t.Fatalf("Expected: %d. Actual: %d.", WS_CHALLENGE_PASSWORD, res.Status)
+	}
+
+	e = conn.Write(ctx, websocket.MessageText, []byte(pw))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	res = readResponse(t, conn)
+
+	if res.Status != WS_CHALLENGE_SUCCESS {
+		t.Fatalf("Expected: %d. Actual: %d.", WS_CHALLENGE_SUCCESS, res.Status)
+	}
+
+	return conn
+}
+
+// Publish a frame over HTTP.
+func (hub *testHub) publish(t *testing.T, id, pw, frame string) *http.Response {
+	return hub.publishAs(t, id, pw, Slot{}, frame)
+}
+
+// Publish a frame over HTTP in slot.
+func (hub *testHub) publishAs(t *testing.T, id, pw string, slot Slot, frame string) *http.Response {
+	req, e := http.NewRequest(http.MethodPost, hub.URL+"/publish/"+id, bytes.NewReader([]byte(frame)))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Channel-Password", pw)
+	req.Header.Set("Channel-Car", slot.Car)
+	req.Header.Set("Channel-Driver", slot.Driver)
+
+	res, e := http.DefaultClient.Do(req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	res.Body.Close()
+
+	return res
+}
+
+// Hand the publisher slot of slot.Car over to slot.Driver.
+func (hub *testHub) swap(t *testing.T, slot Slot) *http.Response {
+	body, e := json.Marshal(slot)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	req, e := http.NewRequest(http.MethodPost, hub.URL+"/publish/"+testChannelID+"/swap", bytes.NewReader(body))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Channel-Password", testChannelPassword)
+	res, e := http.DefaultClient.Do(req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	res.Body.Close()
+
+	return res
+}
+
+// Received websocket message.
+type testResponse struct {
+	Status websocket.StatusCode `json:"status"`
+	Seq    uint64               `json:"seq"`
+	Data   json.RawMessage      `json:"data"`
+}
+
+func readResponse(t *testing.T, conn *websocket.Conn) testResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, bytes, e := conn.Read(ctx)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	res := testResponse{}
+	e = json.Unmarshal(bytes, &res)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return res
+}
+
+// Read the next message without the status skip.
+func readSkip(t *testing.T, conn *websocket.Conn, skip websocket.StatusCode) testResponse {
+	for {
+		if res := readResponse(t, conn); res.Status != skip {
+			return res
+		}
+	}
+}
+
+// Are frames forwarded to subscribers with sequence numbers?
+// Are non-JSON frames rejected?
+func TestTelemetryPublish(t *testing.T) {
+	hub := newTestHub(t)
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+
+	for i := uint64(1); i <= 3; i++ {
+		res := hub.publish(t, testChannelID, testChannelPassword, `{"speedKmh":212.5}`)
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+		}
+
+		msg := readResponse(t, conn)
+
+		if msg.Status != WS_OK || msg.Seq != i {
+			t.Fatalf("Expected: status %d, seq %d. Actual: %+v.", WS_OK, i, msg)
+		}
+
+		if string(msg.Data) != `{"speedKmh":212.5}` {
+			t.Fatalf("Expected: %s. Actual: %s.", `{"speedKmh":212.5}`, msg.Data)
+		}
+	}
+
+	res := hub.publish(t, testChannelID, testChannelPassword, `{"speedKmh":`)
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusBadRequest, res.StatusCode)
+	}
+
+	res = hub.publish(t, testChannelID, "lol123", `{}`)
+
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusUnauthorized, res.StatusCode)
+	}
+}
+
+// Are frames validated against the channel's schema?
+func TestTelemetryPublishSchema(t *testing.T) {
+	hub := newTestHub(t)
+	hub.schema = acc.SCHEMA
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+
+	res := hub.publish(t, testChannelID, testChannelPassword, `{"version":1,"physics":{"gas":1.5}}`)
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusBadRequest, res.StatusCode)
+	}
+
+	res = hub.publish(t, testChannelID, testChannelPassword, `{"version":1,"physics":{"gas":0.5}}`)
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+
+	// only the valid frame should have been broadcast
+	if msg := readResponse(t, conn); msg.Seq != 1 {
+		t.Fatalf("Expected: seq 1. Actual: %+v.", msg)
+	}
+}
+
+// Is the empty metadata of the channel filled from the telemetry once?
+func TestTelemetryMetadata(t *testing.T) {
+	hub := newTestHub(t)
+	hub.schema = acc.SCHEMA
+
+	for _, f := range []string{
+		`{"version":1,"physics":{"gas":0.5}}`,
+		`{"version":1,"static":{"carModel":"amr_v8_vantage_gt3","track":"spa"}}`,
+		`{"version":1,"static":{"carModel":"bentley_continental_gt3","track":"monza"}}`,
+	} {
+		if res := hub.publish(t, testChannelID, testChannelPassword, f); res.StatusCode != http.StatusOK {
+			t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+		}
+	}
+
+	hub.mtx.Lock()
+	defer hub.mtx.Unlock()
+
+	expected := []are_hub.Metadata{
+		{Sim: acc.MetadataFields.Sim},
+		{CarModel: "amr_v8_vantage_gt3", Track: "spa"},
+	}
+
+	if !reflect.DeepEqual(hub.filled, expected) {
+		t.Fatalf("Expected: %+v. Actual: %+v.", expected, hub.filled)
+	}
+}
+
+// Are completed laps sent to subscribers after the frame that completed them
+// and persisted?
+func TestTelemetryPublishLap(t *testing.T) {
+	hub := newTestHub(t)
+	hub.schema = acc.SCHEMA
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+
+	frames := []string{
+		`{"version":1,"physics":{"fuel":60},"graphics":{"completedLaps":4,"currentSectorIndex":0,"isValidLap":1}}`,
+		`{"version":1,"physics":{"fuel":57.5},"graphics":{"completedLaps":4,"currentSectorIndex":1,"lastSectorTime":34000,"isValidLap":1}}`,
+		`{"version":1,"physics":{"fuel":57},"graphics":{"completedLaps":5,"currentSectorIndex":0,"iLastTime":104230,"isValidLap":1}}`,
+	}
+
+	for _, f := range frames {
+		hub.publish(t, testChannelID, testChannelPassword, f)
+	}
+
+	for i := range frames {
+		if msg := readSkip(t, conn, WS_STRATEGY); msg.Status != WS_OK {
+			t.Fatalf("Expected: frame %d. Actual: %+v.", i+1, msg)
+		}
+	}
+
+	msg := readSkip(t, conn, WS_STRATEGY)
+
+	if msg.Status != WS_LAP || msg.Seq != 3 {
+		t.Fatalf("Expected: status %d, seq 3. Actual: %+v.", WS_LAP, msg)
+	}
+
+	lap := are_hub.Lap{}
+	e := json.Unmarshal(msg.Data, &lap)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if lap.Number != 5 || lap.Time != 104230 || lap.FuelUsed != 3 || !lap.Valid {
+		t.Fatalf("Expected: lap 5, 104230ms, 3 fuel used, valid. Actual: %+v.", lap)
+	}
+
+	// the lap is followed by a strategy estimate including it
+	msg = readResponse(t, conn)
+
+	if msg.Status != WS_STRATEGY {
+		t.Fatalf("Expected: status %d. Actual: %+v.", WS_STRATEGY, msg)
+	}
+
+	hub.mtx.Lock()
+	defer hub.mtx.Unlock()
+
+	if l := len(hub.laps); l != 1 {
+		t.Fatalf("Expected: 1 persisted lap. Actual: %d.", l)
+	}
+}
+
+// Are alerts sent to subscribers when a rule fires and persisted? Does the rule
+// only fire again once the value has moved back past the hysteresis?
+func TestTelemetryAlert(t *testing.T) {
+	hub := newTestHub(t)
+	hub.rules = []are_hub.AlertRule{{
+		Name:       "FL pressure",
+		Field:      "physics.wheelsPressure.0",
+		Operator:   ">",
+		Threshold:  28,
+		Unit:       "psi",
+		Hysteresis: 0.5,
+		Common:     are_hub.Common{ID: "rule1"},
+	}}
+
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+
+	// fires, stays active, doesn't clear within the hysteresis, clears, fires again
+	pressures := []string{"27.5", "28.2", "28.4", "27.8", "27.4", "28.1"}
+	fired := map[uint64]bool{2: true, 6: true}
+
+	for _, p := range pressures {
+		hub.publish(t, testChannelID, testChannelPassword, `{"physics":{"wheelsPressure":[`+p+`,27,26,26]}}`)
+	}
+
+	for i := range pressures {
+		seq := uint64(i + 1)
+
+		if msg := readResponse(t, conn); msg.Status != WS_OK || msg.Seq != seq {
+			t.Fatalf("Expected: frame %d. Actual: %+v.", seq, msg)
+		}
+
+		if !fired[seq] {
+			continue
+		}
+
+		msg := readResponse(t, conn)
+
+		if msg.Status != WS_ALERT || msg.Seq != seq {
+			t.Fatalf("Expected: status %d, seq %d. Actual: %+v.", WS_ALERT, seq, msg)
+		}
+
+		alert := are_hub.Alert{}
+		e := json.Unmarshal(msg.Data, &alert)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if alert.RuleID != "rule1" || alert.ChannelID != testChannelID || alert.Value != 28.2 && alert.Value != 28.1 {
+			t.Fatalf("Expected: rule1 on %s above 28. Actual: %+v.", testChannelID, alert)
+		}
+	}
+
+	hub.mtx.Lock()
+	defer hub.mtx.Unlock()
+
+	if l := len(hub.alerts); l != 2 {
+		t.Fatalf("Expected: 2 persisted alerts. Actual: %d.", l)
+	}
+}
+
+// Are sessions starting, alerts firing, and publishers going offline emitted?
+func TestTelemetryEvents(t *testing.T) {
+	defer func(d time.Duration) { publisherTimeout = d }(publisherTimeout)
+	publisherTimeout = time.Millisecond * 250
+
+	hub := newTestHub(t)
+	hub.schema = acc.SCHEMA
+	hub.rules = []are_hub.AlertRule{{Field: "physics.fuel", Operator: "<", Threshold: 5}}
+
+	hub.publish(t, testChannelID, testChannelPassword, `{"version":1,"physics":{"fuel":4},"graphics":{"completedLaps":0}}`)
+	hub.publish(t, testChannelID, testChannelPassword, `{"version":1,"physics":{"fuel":3.9},"graphics":{"completedLaps":0}}`)
+
+	time.Sleep(publisherTimeout * 3)
+
+	hub.mtx.Lock()
+	defer hub.mtx.Unlock()
+
+	expected := []string{are_hub.EVENT_SESSION_STARTED, are_hub.EVENT_ALERT_FIRED, are_hub.EVENT_PUBLISHER_OFFLINE}
+
+	if len(hub.emitted) != len(expected) {
+		t.Fatalf("Expected: %v. Actual: %+v.", expected, hub.emitted)
+	}
+
+	for i, ev := range hub.emitted {
+		if ev.Type != expected[i] || ev.ChannelID != testChannelID {
+			t.Fatalf("Expected: %s on %s. Actual: %+v.", expected[i], testChannelID, ev)
+		}
+	}
+}
+
+// Are frames on team channels tagged by car and driver and only sent to the
+// subscribers following the car? Are drivers not holding a car's slot rejected
+// until the slot is handed over to them? Does the stream continue after a swap?
+func TestTelemetryTeam(t *testing.T) {
+	hub := newTestHub(t)
+	hub.cars = []string{"7", "32"}
+
+	all := hub.subscribe(t, testChannelID, testChannelPassword)
+	seven := hub.subscribeCar(t, testChannelID, testChannelPassword, "7")
+
+	vanthoor := Slot{Car: "7", Driver: "Vanthoor"}
+	vervisch := Slot{Car: "7", Driver: "Vervisch"}
+	weerts := Slot{Car: "32", Driver: "Weerts"}
+
+	codes := []struct {
+		slot Slot
+		code int
+	}{
+		{vanthoor, http.StatusOK},
+		{weerts, http.StatusOK},
+		// car 7 is being published by Vanthoor
+		{vervisch, http.StatusConflict},
+		// car required
+		{Slot{Driver: "Vanthoor"}, http.StatusBadRequest},
+		{Slot{Car: "99"}, http.StatusNotFound},
+	}
+
+	for _, c := range codes {
+		res := hub.publishAs(t, testChannelID, testChannelPassword, c.slot, `{"speedKmh":212.5}`)
+
+		if res.StatusCode != c.code {
+			t.Fatalf("%+v. Expected: %d. Actual: %d.", c.slot, c.code, res.StatusCode)
+		}
+	}
+
+	// hand car 7 over to Vervisch
+	if res := hub.swap(t, vervisch); res.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusAccepted, res.StatusCode)
+	}
+
+	if res := hub.publishAs(t, testChannelID, testChannelPassword, vervisch, `{}`); res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+
+	// the previous driver can no longer publish
+	if res := hub.publishAs(t, testChannelID, testChannelPassword, vanthoor, `{}`); res.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusConflict, res.StatusCode)
+	}
+
+	readFrame := func(conn *websocket.Conn, seq uint64, car, driver string) {
+		msg := readResponse(t, conn)
+		f := teamFrame{}
+		e := json.Unmarshal(msg.Data, &f)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if msg.Status != WS_OK || msg.Seq != seq || f.Car != car || f.Driver != driver {
+			t.Fatalf("Expected: frame %d from %s in car %s. Actual: %+v, %+v.", seq, driver, car, msg, f)
+		}
+	}
+
+	readSwap := func(conn *websocket.Conn) {
+		msg := readResponse(t, conn)
+		info := streamInfo{}
+		e := json.Unmarshal(msg.Data, &info)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if msg.Status != WS_SWAP || info.Car != "7" || info.Driver != "Vervisch" || info.Previous != "Vanthoor" {
+			t.Fatalf("Expected: swap from Vanthoor to Vervisch. Actual: %+v, %+v.", msg, info)
+		}
+	}
+
+	readFrame(all, 1, "7", "Vanthoor")
+	readFrame(all, 2, "32", "Weerts")
+	readSwap(all)
+	readFrame(all, 3, "7", "Vervisch")
+
+	// car 32's frame is not sent to subscribers following car 7
+	readFrame(seven, 1, "7", "Vanthoor")
+	readSwap(seven)
+	readFrame(seven, 3, "7", "Vervisch")
+
+	hub.mtx.Lock()
+	defer hub.mtx.Unlock()
+
+	if l := len(hub.emitted); l != 1 || hub.emitted[0].Type != are_hub.EVENT_DRIVER_SWAPPED {
+		t.Fatalf("Expected: %s. Actual: %+v.", are_hub.EVENT_DRIVER_SWAPPED, hub.emitted)
+	}
+}
+
+// Does it return the live channel's strategy estimate?
+// Does it return a 404 Not Found error for channels that aren't live?
+func TestTelemetryStrategy(t *testing.T) {
+	hub := newTestHub(t)
+	hub.schema = acc.SCHEMA
+	p := httprouter.Param{Key: "id", Value: testChannelID}
+
+	test404(t, http.MethodGet, "/channel/"+p.Value+"/strategy", nil, hub.ts.Strategy, p)
+
+	frames := []string{
+		`{"version":1,"physics":{"fuel":60},"graphics":{"completedLaps":4,"sessionTimeLeft":600000},"static":{"maxFuel":120}}`,
+		`{"version":1,"physics":{"fuel":57},"graphics":{"completedLaps":5,"iLastTime":100000,"sessionTimeLeft":500000}}`,
+	}
+
+	for _, f := range frames {
+		hub.publish(t, testChannelID, testChannelPassword, f)
+	}
+
+	req, e := http.NewRequest(http.MethodGet, "/channel/"+p.Value+"/strategy", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	uf.EmbedParams(req, p)
+
+	w := httptest.NewRecorder()
+	e = hub.ts.Strategy(w, req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	res := w.Result()
+
+	checkCT(res, t)
+
+	est := strategy.Estimate{}
+	e = json.NewDecoder(res.Body).Decode(&est)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if est.FuelPerLap != 3 || est.LapsToFinish != 5 || est.Fuel != 57 {
+		t.Fatalf("Expected: 3 fuel per lap, 5 laps to finish, 57 fuel. Actual: %+v.", est)
+	}
+}
+
+// Are chat messages persisted, timestamped against the telemetry sequence,
+// and forwarded to every subscriber?
+func TestTelemetryChat(t *testing.T) {
+	hub := newTestHub(t)
+	engineer := hub.subscribe(t, testChannelID, testChannelPassword)
+	strategist := hub.subscribe(t, testChannelID, testChannelPassword)
+
+	// publish a frame so the chat message has a non-zero sequence number
+	hub.publish(t, testChannelID, testChannelPassword, `{"lap":23}`)
+	readResponse(t, engineer)
+	readResponse(t, strategist)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	e := engineer.Write(ctx, websocket.MessageText, []byte(`{"author":"Race engineer","body":"lap 23: lockup T1"}`))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for _, conn := range []*websocket.Conn{engineer, strategist} {
+		res := readResponse(t, conn)
+
+		if res.Status != WS_CHAT || res.Seq != 1 {
+			t.Fatalf("Expected: status %d, seq 1. Actual: %+v.", WS_CHAT, res)
+		}
+
+		msg := are_hub.Message{}
+		e = json.Unmarshal(res.Data, &msg)
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if msg.Body != "lap 23: lockup T1" || msg.ChannelID != testChannelID {
+			t.Fatalf("Expected: lap 23: lockup T1 on %s. Actual: %+v.", testChannelID, msg)
+		}
+	}
+
+	hub.mtx.Lock()
+	defer hub.mtx.Unlock()
+
+	if l := len(hub.messages); l != 1 {
+		t.Fatalf("Expected: 1 persisted message. Actual: %d.", l)
+	}
+}
+
+// Are subscribers sending malformed chat messages disconnected?
+func TestTelemetryChatMalformed(t *testing.T) {
+	hub := newTestHub(t)
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	e := conn.Write(ctx, websocket.MessageText, []byte(`{"author":"Race engineer"}`))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	_, _, e = conn.Read(ctx)
+
+	if status := websocket.CloseStatus(e); status != WS_ERROR_BAD_MSG {
+		t.Fatalf("Expected: %d. Actual: %d (%v).", WS_ERROR_BAD_MSG, status, e)
+	}
+}
+
+// Server-sent event received by the test client.
+type testEvent struct {
+	id, event, data string
+}
+
+// Open an event stream. headers are set as alternating key and value pairs.
+func (hub *testHub) events(t *testing.T, id string, headers ...string) (*http.Response, *bufio.Reader) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, e := http.NewRequestWithContext(ctx, http.MethodGet, hub.URL+"/subscribe/"+id+"/events", nil)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	res, e := http.DefaultClient.Do(req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	t.Cleanup(func() { res.Body.Close() })
+
+	return res, bufio.NewReader(res.Body)
+}
+
+// Read a single event from the stream.
+func readEvent(t *testing.T, r *bufio.Reader) testEvent {
+	ev := testEvent{}
+	done := make(chan error, 1)
+
+	go func() {
+		for {
+			line, e := r.ReadString('\n')
+
+			if e != nil {
+				done <- e
+
+				return
+			}
+
+			line = strings.TrimSuffix(line, "\n")
+
+			switch {
+			case line == "":
+				done <- nil
+
+				return
+			case strings.HasPrefix(line, "id: "):
+				ev.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+
+	select {
+	case e := <-done:
+		if e != nil {
+			t.Fatal(e)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for an event")
+	}
+
+	return ev
+}
+
+// Are requests without the correct password rejected?
+func TestEventsUnauthorised(t *testing.T) {
+	hub := newTestHub(t)
+
+	res, _ := hub.events(t, testChannelID)
+
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusUnauthorized, res.StatusCode)
+	}
+
+	res, _ = hub.events(t, testChannelID, "Authorization", "Bearer lol123")
+
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusUnauthorized, res.StatusCode)
+	}
+}
+
+// Are frames streamed as data events with their sequence number as the ID?
+// Can clients resume with Last-Event-ID?
+func TestEventsStream(t *testing.T) {
+	hub := newTestHub(t)
+	res, r := hub.events(t, testChannelID, "Authorization", "Bearer "+testChannelPassword)
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected: text/event-stream. Actual: %s.", ct)
+	}
+
+	frames := []string{`{"lap":1}`, `{"lap":2}`, `{"lap":3}`}
+
+	for _, f := range frames {
+		hub.publish(t, testChannelID, testChannelPassword, f)
+	}
+
+	for i, f := range frames {
+		ev := readEvent(t, r)
+		expected := testEvent{id: string(rune('1' + i)), event: "data", data: f}
+
+		if ev != expected {
+			t.Fatalf("Expected: %+v. Actual: %+v.", expected, ev)
+		}
+	}
+
+	// reconnect having missed the last frame
+	_, r = hub.events(t, testChannelID, "Channel-Password", testChannelPassword, "Last-Event-ID", "2")
+	ev := readEvent(t, r)
+
+	if ev.id != "3" || ev.data != frames[2] {
+		t.Fatalf("Expected: id 3 with %s. Actual: %+v.", frames[2], ev)
+	}
+
+	res, _ = hub.events(t, testChannelID, "Channel-Password", testChannelPassword, "Last-Event-ID", "abc")
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusBadRequest, res.StatusCode)
+	}
+}
+
+// Are subscribers beyond the channel's capacity rejected? Are subscribers
+// created with the channel's buffer length? Are updated limits applied to the
+// live channel?
+func TestTelemetryLimits(t *testing.T) {
+	hub := newTestHub(t)
+	hub.limits = are_hub.Limits{MaxSubscribers: 1, BufferLength: 4}
+	auth := []string{"Authorization", "Bearer " + testChannelPassword}
+
+	hub.subscribe(t, testChannelID, testChannelPassword)
+
+	res, _ := hub.events(t, testChannelID, auth...)
+
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusServiceUnavailable, res.StatusCode)
+	}
+
+	tc := hub.channel(t)
+
+	if size := tc.status().Subscribers[0].BufferSize; size != 4 {
+		t.Fatalf("Expected: buffer of 4. Actual: %d.", size)
+	}
+
+	// the server's defaults apply to limits the channel doesn't define
+	hub.ts.SetDefaults(are_hub.Limits{BufferLength: 8})
+
+	c := are_hub.NewChannel("Audi Sport Team WRT", testHash)
+	c.ID = testChannelID
+	c.Limits = are_hub.Limits{MaxSubscribers: 2}
+	hub.ts.refresh(c)
+
+	res, r := hub.events(t, testChannelID, auth...)
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+
+	hub.publish(t, testChannelID, testChannelPassword, `{"speedKmh":212.5}`)
+	readEvent(t, r)
+
+	cs := tc.status()
+
+	if len(cs.Subscribers) != 2 || cs.Subscribers[1].BufferSize != 8 {
+		t.Fatalf("Expected: 2 subscribers with the newest buffering 8. Actual: %+v.", cs.Subscribers)
+	}
+}
+
+// Read the messages queued for sub until none arrive for a short while.
+// Returns the sequence numbers of frames and the number of missed messages
+// subscribers were told about.
+func drainSubscription(t *testing.T, sub *Subscription) ([]uint64, uint64, error) {
+	var seqs []uint64
+	var missed uint64
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		ev, e := sub.Next(ctx)
+		cancel()
+
+		if e == context.DeadlineExceeded {
+			return seqs, missed, nil
+		}
+
+		if e != nil {
+			return seqs, missed, e
+		}
+
+		switch ev.Status {
+		case WS_OK:
+			seqs = append(seqs, ev.Seq)
+		case WS_MISSED:
+			info := missedInfo{}
+
+			if e = json.Unmarshal(ev.Data, &info); e != nil {
+				t.Fatal(e)
+			}
+
+			missed += info.Missed
+		}
+	}
+}
+
+// Are frames discarded according to the subscriber's policy and the subscriber
+// told how many it missed?
+func TestTelemetrySlowPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+
+		// frames and missed messages read after 4 frames and after 1 more
+		first, second    []uint64
+		missed1, missed2 uint64
+	}{
+		{are_hub.POLICY_DROP_NEWEST, []uint64{1, 2}, []uint64{5}, 0, 2},
+		{are_hub.POLICY_DROP_OLDEST, []uint64{3, 4}, []uint64{5}, 0, 2},
+		{are_hub.POLICY_CONFLATE, []uint64{4}, []uint64{5}, 3, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			hub := newTestHub(t)
+			hub.limits = are_hub.Limits{BufferLength: 2}
+
+			o := SubscribeOptions{Policy: test.policy}
+			sub, e := hub.ts.Subscription(context.Background(), testChannelID, testChannelPassword, o)
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			defer sub.Close()
+
+			for i := 0; i < 4; i++ {
+				hub.publish(t, testChannelID, testChannelPassword, `{"speedKmh":212.5}`)
+			}
+
+			seqs, missed, e := drainSubscription(t, sub)
+
+			if e != nil || !reflect.DeepEqual(seqs, test.first) || missed != test.missed1 {
+				t.Fatalf("Expected: %v with %d missed. Actual: %v with %d missed (%v).", test.first, test.missed1, seqs, missed, e)
+			}
+
+			hub.publish(t, testChannelID, testChannelPassword, `{"speedKmh":212.5}`)
+			seqs, missed, e = drainSubscription(t, sub)
+
+			if e != nil || !reflect.DeepEqual(seqs, test.second) || missed != test.missed2 {
+				t.Fatalf("Expected: %v with %d missed. Actual: %v with %d missed (%v).", test.second, test.missed2, seqs, missed, e)
+			}
+
+			// every frame not received was missed
+			expected := uint64(5 - len(test.first) - len(test.second))
+
+			if lost := hub.channel(t).status().Subscribers[0].Missed; lost != expected {
+				t.Errorf("Expected: %d missed in total. Actual: %d.", expected, lost)
+			}
+		})
+	}
+}
+
+// Are discarded chat messages left out of the missed frames?
+func TestTelemetrySlowPolicyChat(t *testing.T) {
+	for _, policy := range []string{are_hub.POLICY_DROP_OLDEST, are_hub.POLICY_CONFLATE} {
+		t.Run(policy, func(t *testing.T) {
+			hub := newTestHub(t)
+			hub.limits = are_hub.Limits{BufferLength: 2}
+
+			o := SubscribeOptions{Policy: policy}
+			sub, e := hub.ts.Subscription(context.Background(), testChannelID, testChannelPassword, o)
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			defer sub.Close()
+			tc := hub.channel(t)
+
+			for i := 0; i < 2; i++ {
+				if e = tc.chat(are_hub.NewMessage(testChannelID, "Vanthoor", "Box box", 0)); e != nil {
+					t.Fatal(e)
+				}
+			}
+
+			// the chat messages are discarded to make room for the frame
+			hub.publish(t, testChannelID, testChannelPassword, `{"speedKmh":212.5}`)
+			seqs, missed, e := drainSubscription(t, sub)
+
+			if e != nil || !reflect.DeepEqual(seqs, []uint64{1}) || missed != 0 {
+				t.Fatalf("Expected: [1] with 0 missed. Actual: %v with %d missed (%v).", seqs, missed, e)
+			}
+
+			if lost := tc.status().Subscribers[0].Missed; lost != 0 {
+				t.Errorf("Expected: 0 missed in total. Actual: %d.", lost)
+			}
+		})
+	}
+}
+
+// Are subscribers with the disconnect policy dropped? Is the publisher blocked
+// until subscribers with the block policy make room, and are they dropped if
+// they don't by the deadline?
+func TestTelemetryDisconnectPolicy(t *testing.T) {
+	hub := newTestHub(t)
+	hub.limits = are_hub.Limits{BufferLength: 2, SlowPolicy: are_hub.POLICY_BLOCK}
+
+	dropped, e := hub.ts.Subscription(context.Background(), testChannelID, testChannelPassword, SubscribeOptions{Policy: are_hub.POLICY_DISCONNECT})
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	defer dropped.Close()
+
+	// follows the channel's policy
+	blocked, e := hub.ts.Subscription(context.Background(), testChannelID, testChannelPassword, SubscribeOptions{})
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	defer blocked.Close()
+
+	for i := 0; i < 2; i++ {
+		hub.publish(t, testChannelID, testChannelPassword, `{"speedKmh":212.5}`)
+	}
+
+	// make room for the blocked frame shortly after it is published
+	go func() {
+		time.Sleep(blockDeadline / 4)
+		blocked.Next(context.Background())
+	}()
+
+	hub.publish(t, testChannelID, testChannelPassword, `{"speedKmh":212.5}`)
+
+	if _, _, e = drainSubscription(t, dropped); e != ErrDropped {
+		t.Fatalf("Expected: %v. Actual: %v.", ErrDropped, e)
+	}
+
+	seqs, missed, e := drainSubscription(t, blocked)
+
+	if e != nil || !reflect.DeepEqual(seqs, []uint64{2, 3}) || missed != 0 {
+		t.Fatalf("Expected: [2 3]. Actual: %v with %d missed (%v).", seqs, missed, e)
+	}
+
+	// nothing reads the frames this time
+	for i := 0; i < 3; i++ {
+		hub.publish(t, testChannelID, testChannelPassword, `{"speedKmh":212.5}`)
+	}
+
+	if _, _, e = drainSubscription(t, blocked); e != ErrDropped {
+		t.Fatalf("Expected: %v. Actual: %v.", ErrDropped, e)
+	}
+}
+
+// Are frames published on one node relayed to the subscribers of another in a
+// single sequence? Are publisher slots held on one node rejected on another
+// until handed over?
+func TestTelemetryBackplane(t *testing.T) {
+	bp := backplane.NewLocal()
+	nodes := []*testHub{newTestHub(t), newTestHub(t)}
+	var conns []*websocket.Conn
+
+	for _, hub := range nodes {
+		hub.cars = []string{"31"}
+		hub.ts.SetBackplane(bp)
+		conns = append(conns, hub.subscribe(t, testChannelID, testChannelPassword))
+	}
+
+	a, b := nodes[0], nodes[1]
+	vanthoor := Slot{"31", "Vanthoor"}
+	weerts := Slot{"31", "Weerts"}
+
+	readFrames := func(seq uint64, driver string) {
+		t.Helper()
+
+		for i, conn := range conns {
+			msg := readSkip(t, conn, WS_SWAP)
+			f := teamFrame{}
+
+			if e := json.Unmarshal(msg.Data, &f); e != nil {
+				t.Fatal(e)
+			}
+
+			if msg.Status != WS_OK || msg.Seq != seq || f.Driver != driver {
+				t.Errorf("Node %d. Expected: frame %d by %s. Actual: %d, %+v.", i, seq, driver, msg.Seq, f)
+			}
+		}
+	}
+
+	if res := a.publishAs(t, testChannelID, testChannelPassword, vanthoor, `{}`); res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+
+	readFrames(1, "Vanthoor")
+
+	// the same driver may publish on either node
+	if res := b.publishAs(t, testChannelID, testChannelPassword, vanthoor, `{}`); res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+
+	readFrames(2, "Vanthoor")
+
+	// car 31 is held by Vanthoor across nodes
+	if res := b.publishAs(t, testChannelID, testChannelPassword, weerts, `{}`); res.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusConflict, res.StatusCode)
+	}
+
+	// hand over on one node and take over on the other
+	if res := a.swap(t, weerts); res.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusAccepted, res.StatusCode)
+	}
+
+	if res := b.publishAs(t, testChannelID, testChannelPassword, weerts, `{}`); res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+
+	readFrames(3, "Weerts")
+
+	if res := a.publishAs(t, testChannelID, testChannelPassword, vanthoor, `{}`); res.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusConflict, res.StatusCode)
+	}
+
+	if seq := a.channel(t).sequence(); seq != 3 {
+		t.Errorf("Expected: node a at seq 3. Actual: %d.", seq)
+	}
+}
+
+// Counts the claims of publisher slots made to a backplane.
+type countingBackplane struct {
+	are_hub.Backplane
+	claims atomic.Int32
+}
+
+func (bp *countingBackplane) Claim(ctx context.Context, id, car, driver string, ttl time.Duration) (string, bool, error) {
+	bp.claims.Add(1)
+
+	return bp.Backplane.Claim(ctx, id, car, driver, ttl)
+}
+
+// Are claims only renewed with the backplane once close to expiring or after a
+// handover?
+func TestTelemetryClaimRenewal(t *testing.T) {
+	hub := newTestHub(t)
+	hub.cars = []string{"31"}
+	bp := &countingBackplane{Backplane: backplane.NewLocal()}
+	hub.ts.SetBackplane(bp)
+
+	for i := 0; i < 3; i++ {
+		hub.publishAs(t, testChannelID, testChannelPassword, Slot{"31", "Vanthoor"}, `{}`)
+	}
+
+	if n := bp.claims.Load(); n != 1 {
+		t.Fatalf("Expected: 1 claim. Actual: %d.", n)
+	}
+
+	if res := hub.swap(t, Slot{"31", "Weerts"}); res.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected: %d. Actual: %d.", http.StatusAccepted, res.StatusCode)
+	}
+
+	hub.publishAs(t, testChannelID, testChannelPassword, Slot{"31", "Vanthoor"}, `{}`)
+
+	if n := bp.claims.Load(); n != 2 {
+		t.Fatalf("Expected: 2 claims. Actual: %d.", n)
+	}
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Delay before watching channels again after watching failed.
+var watchBackoff time.Duration = time.Second * 5
+
+// Keep the live channels in sync with the changes made to channels by any
+// instance of the server until ctx is cancelled. Watching is restarted after
+// a delay when it fails. Live channels are found in the repository again before
+// restarting so that changes made in the meantime are not missed.
+func (ts *TelemetryServer) Watch(ctx context.Context, w are_hub.ChannelWatcher) {
+	for {
+		e := w.Watch(ctx, ts.apply)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Watching channels failed: %v\n", e)
+
+		t := time.NewTimer(watchBackoff)
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+
+			return
+		}
+
+		ts.resync(ctx)
+	}
+}
+
+// Apply a change made to a channel to its telemetry channel if it is live.
+// Subscribers of deleted channels are disconnected.
+func (ts *TelemetryServer) apply(change are_hub.ChannelChange) {
+	switch change.Type {
+	case are_hub.CHANGE_UPDATED:
+		ts.refresh(change.Channel)
+
+		// channels are touched when their alert rules change on any server
+		e := ts.reloadRules(context.Background(), change.ID)
+
+		if e != nil {
+			log.Printf("Failed to reload the alert rules of %s: %v\n", change.ID, e)
+		}
+	case are_hub.CHANGE_DELETED:
+		ts.closeChannel(change.ID, "Channel deleted")
+	}
+}
+
+// Find every live channel in the repository and apply it to its telemetry
+// channel.
+func (ts *TelemetryServer) resync(ctx context.Context) {
+	for _, tc := range ts.channels.all() {
+		c, e := ts.repo.FindID(ctx, tc.ID)
+
+		if e == nil {
+			ts.apply(are_hub.ChannelChange{Type: are_hub.CHANGE_UPDATED, ID: c.ID, Channel: c})
+		} else if are_hub.IsNoObjectsFound(e) {
+			ts.apply(are_hub.ChannelChange{Type: are_hub.CHANGE_DELETED, ID: tc.ID})
+		} else {
+			log.Printf("Failed to find channel %s: %v\n", tc.ID, e)
+		}
+	}
+}
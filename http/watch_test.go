@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/acc"
+	"github.com/blacksfk/are_hub/mock"
+	"nhooyr.io/websocket"
+)
+
+// Watch hub's telemetry server for the changes sent through the returned
+// function, which returns once the change has been applied.
+func watch(t *testing.T, hub *testHub) func(are_hub.ChannelChange) {
+	changes := make(chan are_hub.ChannelChange)
+	applied := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t.Cleanup(cancel)
+
+	go hub.ts.Watch(ctx, &mock.ChannelRepo{
+		WatchFunc: func(ctx context.Context, fn func(are_hub.ChannelChange)) error {
+			for {
+				select {
+				case change := <-changes:
+					fn(change)
+					applied <- struct{}{}
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		},
+	})
+
+	return func(change are_hub.ChannelChange) {
+		changes <- change
+		<-applied
+	}
+}
+
+// Read from conn until it is closed and check its close status.
+func expectClosed(t *testing.T, conn *websocket.Conn, code websocket.StatusCode) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for {
+		_, _, e := conn.Read(ctx)
+
+		if e != nil {
+			if websocket.CloseStatus(e) != code {
+				t.Fatalf("Expected: %d. Actual: %v.", code, e)
+			}
+
+			return
+		}
+	}
+}
+
+// Are live channels updated in place unless their schema or cars changed?
+func TestWatchUpdate(t *testing.T) {
+	hub := newTestHub(t)
+	change := watch(t, hub)
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+	tc := hub.channel(t)
+
+	c := are_hub.NewChannel("Team WRT", "replaced")
+	c.ID = testChannelID
+	c.Limits = are_hub.Limits{MaxSubscribers: 3}
+
+	change(are_hub.ChannelChange{Type: are_hub.CHANGE_UPDATED, ID: c.ID, Channel: c})
+
+	cs := tc.status()
+
+	if cs.State != stateNames[stateLive] || cs.Name != "Team WRT" || cs.Limits.MaxSubscribers != 3 {
+		t.Errorf("Expected: live channel Team WRT of 3 subscribers. Actual: %+v.", cs)
+	}
+
+	if pw := tc.password(); pw != "replaced" {
+		t.Errorf("Expected: replaced. Actual: %s.", pw)
+	}
+
+	c.Schema = acc.SCHEMA
+	change(are_hub.ChannelChange{Type: are_hub.CHANGE_UPDATED, ID: c.ID, Channel: c})
+	expectClosed(t, conn, WS_ERROR_DISCONNECTED)
+
+	if _, ok := hub.ts.channels.get(testChannelID); ok {
+		t.Error("Expected the changed channel to be closed")
+	}
+}
+
+// Are the alert rules of live channels reloaded when the channel is touched by
+// another server?
+func TestWatchRules(t *testing.T) {
+	hub := newTestHub(t)
+	change := watch(t, hub)
+	hub.subscribe(t, testChannelID, testChannelPassword)
+	tc := hub.channel(t)
+
+	if n := tc.streams[""].rules.Len(); n != 0 {
+		t.Fatalf("Expected: no rules. Actual: %d.", n)
+	}
+
+	hub.rules = []are_hub.AlertRule{alertRules[0]}
+	c := are_hub.NewChannel("Audi Sport Team WRT", testHash)
+	c.ID = testChannelID
+	change(are_hub.ChannelChange{Type: are_hub.CHANGE_UPDATED, ID: c.ID, Channel: c})
+
+	tc.subMtx.Lock()
+	defer tc.subMtx.Unlock()
+
+	if n := tc.streams[""].rules.Len(); n != 1 {
+		t.Errorf("Expected: 1 rule. Actual: %d.", n)
+	}
+}
+
+// Are subscribers of deleted channels disconnected?
+func TestWatchDelete(t *testing.T) {
+	hub := newTestHub(t)
+	change := watch(t, hub)
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+	tc := hub.channel(t)
+
+	// channels that aren't live are ignored
+	change(are_hub.ChannelChange{Type: are_hub.CHANGE_DELETED, ID: "nope"})
+	change(are_hub.ChannelChange{Type: are_hub.CHANGE_DELETED, ID: testChannelID})
+	expectClosed(t, conn, WS_ERROR_DISCONNECTED)
+
+	if s := tc.lifecycle(); s != stateClosed {
+		t.Errorf("Expected: %s. Actual: %s.", stateNames[stateClosed], stateNames[s])
+	}
+}
+
+// Are channels deleted while not watching closed once watching again?
+func TestWatchResync(t *testing.T) {
+	hub := newTestHub(t)
+	conn := hub.subscribe(t, testChannelID, testChannelPassword)
+
+	hub.ts.repo.(*mock.ChannelRepo).FindIDFunc = func(_ context.Context, id string) (*are_hub.Channel, error) {
+		return nil, are_hub.NewNoObjectsFound("channels", "id == "+id)
+	}
+
+	hub.ts.resync(context.Background())
+	expectClosed(t, conn, WS_ERROR_DISCONNECTED)
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/blacksfk/are_hub"
+	uf "github.com/blacksfk/microframework"
+)
+
+// CRUD controller that manipulates webhooks and retrieves their delivery logs.
+// Webhooks are scoped to the channel in the URL; routes without a channel
+// manipulate webhooks receiving the events of every channel.
+type Webhook struct {
+	webhooks   are_hub.WebhookRepo
+	deliveries are_hub.DeliveryRepo
+}
+
+// Create a new webhook controller.
+func NewWebhook(webhooks are_hub.WebhookRepo, deliveries are_hub.DeliveryRepo) Webhook {
+	return Webhook{webhooks, deliveries}
+}
+
+// Get all webhooks receiving the events of the channel in the URL.
+func (wh Webhook) Index(w http.ResponseWriter, r *http.Request) error {
+	webhooks, e := wh.webhooks.FindChannelID(r.Context(), uf.GetParam(r, "id"))
+
+	if e != nil {
+		return e
+	}
+
+	if webhooks == nil {
+		// send an empty array rather than null
+		webhooks = []are_hub.Webhook{}
+	}
+
+	return uf.SendJSON(w, webhooks)
+}
+
+// Create a new webhook.
+func (wh Webhook) Store(w http.ResponseWriter, r *http.Request) error {
+	// get the webhook from the request's context
+	webhook, e := are_hub.WebhookFromCtx(r.Context())
+
+	if e != nil {
+		return e
+	}
+
+	webhook.ChannelID = uf.GetParam(r, "id")
+	e = wh.webhooks.Insert(r.Context(), webhook)
+
+	if e != nil {
+		return e
+	}
+
+	// return the created webhook with the ID and timestamps
+	return uf.SendJSON(w, webhook)
+}
+
+// Find a specific webhook by its ID.
+func (wh Webhook) Show(w http.ResponseWriter, r *http.Request) error {
+	webhook, e := wh.find(r)
+
+	if e != nil {
+		return e
+	}
+
+	return uf.SendJSON(w, webhook)
+}
+
+// Update a specific webhook by its ID.
+func (wh Webhook) Update(w http.ResponseWriter, r *http.Request) error {
+	// get the webhook embedded in the request's context
+	webhook, e := are_hub.WebhookFromCtx(r.Context())
+
+	if e != nil {
+		return e
+	}
+
+	// the webhook must belong to the channel in the URL
+	stored, e := wh.find(r)
+
+	if e != nil {
+		return e
+	}
+
+	// the body replaces the webhook but not when it was created
+	webhook.ChannelID = uf.GetParam(r, "id")
+	webhook.CreatedAt = stored.CreatedAt
+	e = wh.webhooks.UpdateID(r.Context(), uf.GetParam(r, "webhook"), webhook)
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound(e.Error())
+		}
+
+		return e
+	}
+
+	// return the updated webhook
+	return uf.SendJSON(w, webhook)
+}
+
+// Delete a specific webhook by its ID.
+func (wh Webhook) Delete(w http.ResponseWriter, r *http.Request) error {
+	// the webhook must belong to the channel in the URL
+	_, e := wh.find(r)
+
+	if e != nil {
+		return e
+	}
+
+	webhook, e := wh.webhooks.DeleteID(r.Context(), uf.GetParam(r, "webhook"))
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return uf.NotFound(e.Error())
+		}
+
+		return e
+	}
+
+	// return the deleted webhook
+	return uf.SendJSON(w, webhook)
+}
+
+// Get the delivery log of a specific webhook by its ID.
+func (wh Webhook) Deliveries(w http.ResponseWriter, r *http.Request) error {
+	webhook, e := wh.find(r)
+
+	if e != nil {
+		return e
+	}
+
+	deliveries, e := wh.deliveries.FindWebhookID(r.Context(), webhook.ID)
+
+	if e != nil {
+		return e
+	}
+
+	if deliveries == nil {
+		// send an empty array rather than null
+		deliveries = []are_hub.Delivery{}
+	}
+
+	return uf.SendJSON(w, deliveries)
+}
+
+// Find the webhook in the URL, ensuring it belongs to the channel in the URL.
+func (wh Webhook) find(r *http.Request) (*are_hub.Webhook, error) {
+	webhook, e := wh.webhooks.FindID(r.Context(), uf.GetParam(r, "webhook"))
+
+	if e != nil {
+		if are_hub.IsNoObjectsFound(e) {
+			return nil, uf.NotFound(e.Error())
+		}
+
+		return nil, e
+	}
+
+	if webhook.ChannelID != uf.GetParam(r, "id") {
+		return nil, uf.NotFound("No webhook matching: " + uf.GetParam(r, "webhook"))
+	}
+
+	return webhook, nil
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/mock"
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Is the creation time of the stored webhook kept when it is replaced?
+func TestWebhookUpdateCreatedAt(t *testing.T) {
+	created := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	var updated time.Time
+
+	repo := &mock.WebhookRepo{
+		FindIDFunc: func(context.Context, string) (*are_hub.Webhook, error) {
+			webhook := are_hub.NewWebhook("https://example.com/hook", "hunter2", nil)
+			webhook.ChannelID = "1"
+			webhook.CreatedAt = created
+
+			return webhook, nil
+		},
+		UpdateIDFunc: func(_ context.Context, _ string, a are_hub.Archetype) error {
+			updated = a.(*are_hub.Webhook).CreatedAt
+
+			return nil
+		},
+	}
+
+	controller := NewWebhook(repo, &mock.DeliveryRepo{})
+	webhook := are_hub.NewWebhook("https://example.com/laps", "hunter2", []string{are_hub.EVENT_SESSION_STARTED})
+	req := httptest.NewRequest(http.MethodPut, "/channel/1/webhooks/1", nil)
+	req = req.WithContext(webhook.ToCtx(req.Context()))
+	uf.EmbedParams(req, httprouter.Param{Key: "id", Value: "1"}, httprouter.Param{Key: "webhook", Value: "1"})
+	w := httptest.NewRecorder()
+
+	if e := controller.Update(w, req); e != nil {
+		t.Fatal(e)
+	}
+
+	received := are_hub.Webhook{}
+
+	if e := json.NewDecoder(w.Result().Body).Decode(&received); e != nil {
+		t.Fatal(e)
+	}
+
+	if !updated.Equal(created) || !received.CreatedAt.Equal(created) {
+		t.Errorf("Expected: created at %v. Actual: %v, sent %v.", created, updated, received.CreatedAt)
+	}
+}
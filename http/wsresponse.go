@@ -1,8 +1,13 @@
 package http
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"strconv"
 
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/strategy"
 	"nhooyr.io/websocket"
 )
 
@@ -22,6 +27,24 @@ const (
 const (
 	// No error occurred in the last message.
 	WS_OK = iota + 4200
+
+	// Chat message or annotation posted by a subscriber.
+	WS_CHAT
+
+	// Lap completed by the publisher.
+	WS_LAP
+
+	// Strategy estimated from the laps and frames broadcast.
+	WS_STRATEGY
+
+	// Alert rule fired by a frame broadcast.
+	WS_ALERT
+
+	// Driver swap on a car of a team channel.
+	WS_SWAP
+
+	// Messages were discarded because the subscriber's buffer was full.
+	WS_MISSED
 )
 
 // Error codes
@@ -44,14 +67,88 @@ const (
 	// Publisher/subscriber channel is full and the client cannot be added
 	// until a publisher/subscriber disconnects.
 	WS_ERROR_CHANNEL_FULL
+
+	// Disconnected by an administrator.
+	WS_ERROR_DISCONNECTED
 )
 
 // Encapsulates data and challenge messages.
 type response struct {
 	Status websocket.StatusCode `json:"status"`
+	Seq    uint64               `json:"seq,omitempty"`
 	Data   interface{}          `json:"data"`
 }
 
+// A response encoded once and queued for delivery to subscribers.
+type envelope struct {
+	status websocket.StatusCode
+	seq    uint64
+
+	// Car the message relates to on team channels. Empty for messages relating
+	// to the entire channel.
+	car string
+
+	// The complete response for websocket clients.
+	ws []byte
+
+	// Only the response's data for server-sent event clients.
+	data []byte
+
+	// Number of frames a WS_MISSED notice tells the subscriber about.
+	missed uint64
+}
+
+// Encode res as an envelope.
+func newEnvelope(res response) (*envelope, error) {
+	data, e := json.Marshal(res.Data)
+
+	if e != nil {
+		return nil, e
+	}
+
+	// re-use the encoded data rather than encoding it twice
+	res.Data = json.RawMessage(data)
+	ws, e := json.Marshal(res)
+
+	if e != nil {
+		return nil, e
+	}
+
+	return &envelope{status: res.Status, seq: res.Seq, ws: ws, data: data}, nil
+}
+
+// Server-sent event names by status.
+var eventNames = map[websocket.StatusCode]string{
+	WS_OK:       "data",
+	WS_CHAT:     "chat",
+	WS_LAP:      "lap",
+	WS_STRATEGY: "strategy",
+	WS_ALERT:    "alert",
+	WS_SWAP:     "swap",
+	WS_MISSED:   "missed",
+}
+
+// Format the envelope as a server-sent event. The sequence number is used
+// as the event ID so that clients can resume with the Last-Event-ID header.
+func (env *envelope) event() []byte {
+	b := bytes.Buffer{}
+
+	if env.status == WS_OK {
+		// only frames can be resumed from
+		b.WriteString("id: ")
+		b.WriteString(strconv.FormatUint(env.seq, 10))
+		b.WriteByte('\n')
+	}
+
+	b.WriteString("event: ")
+	b.WriteString(eventNames[env.status])
+	b.WriteString("\ndata: ")
+	b.Write(env.data)
+	b.WriteString("\n\n")
+
+	return b.Bytes()
+}
+
 func challengeSucceededResponse() response {
 	return response{Status: WS_CHALLENGE_SUCCESS}
 }
@@ -60,8 +157,37 @@ func passwordChallengeResponse() response {
 	return response{Status: WS_CHALLENGE_PASSWORD}
 }
 
-func dataResponse(data interface{}) response {
-	return response{Status: WS_OK, Data: data}
+func dataResponse(seq uint64, data interface{}) response {
+	return response{Status: WS_OK, Seq: seq, Data: data}
+}
+
+func chatResponse(msg *are_hub.Message) response {
+	return response{Status: WS_CHAT, Seq: msg.Seq, Data: msg}
+}
+
+func lapResponse(lap *are_hub.Lap) response {
+	return response{Status: WS_LAP, Seq: lap.Seq, Data: lap}
+}
+
+func strategyResponse(seq uint64, est strategy.Estimate) response {
+	return response{Status: WS_STRATEGY, Seq: seq, Data: est}
+}
+
+func alertResponse(alert *are_hub.Alert) response {
+	return response{Status: WS_ALERT, Seq: alert.Seq, Data: alert}
+}
+
+func swapResponse(seq uint64, info streamInfo) response {
+	return response{Status: WS_SWAP, Seq: seq, Data: info}
+}
+
+// Number of messages discarded for a slow subscriber since it was last told.
+type missedInfo struct {
+	Missed uint64 `json:"missed"`
+}
+
+func missedResponse(seq, missed uint64) response {
+	return response{Status: WS_MISSED, Seq: seq, Data: missedInfo{missed}}
 }
 
 // Encapsulates error code messages.
@@ -98,3 +224,7 @@ func wsTimeout() *errorResponse {
 func wsChannelFull() *errorResponse {
 	return &errorResponse{Status: WS_ERROR_CHANNEL_FULL}
 }
+
+func wsChannelClosed() *errorResponse {
+	return &errorResponse{WS_ERROR_DISCONNECTED, "Channel closed"}
+}
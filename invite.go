@@ -0,0 +1,127 @@
+package are_hub
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// What invitations and the sessions redeemed from them permit.
+const (
+	// Subscribe to the channel.
+	ROLE_SUBSCRIBER = "subscriber"
+
+	// Publish to the channel as well as subscribe to it.
+	ROLE_PUBLISHER = "publisher"
+)
+
+// Prefixes distinguishing invitation and session tokens from passwords.
+const (
+	INVITE_PREFIX  = "inv_"
+	SESSION_PREFIX = "ses_"
+)
+
+// Check whether r is one of the ROLE_ constants.
+func IsRole(r string) bool {
+	return r == ROLE_SUBSCRIBER || r == ROLE_PUBLISHER
+}
+
+// Check whether role permits acting as required (one of the ROLE_ constants).
+func Permits(role, required string) bool {
+	return role == required || role == ROLE_PUBLISHER
+}
+
+// Invitation to a channel redeemed for a session in place of the channel's
+// password.
+type Invite struct {
+	ChannelID string `json:"channelId"`
+
+	// What sessions redeemed from the invitation permit (see ROLE_*).
+	Role string `json:"role"`
+
+	// When the invitation can no longer be redeemed.
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// Number of times the invitation can be redeemed and has been.
+	MaxUses int `json:"maxUses"`
+	Uses    int `json:"uses"`
+
+	// Sent once when the invitation is created. Only its hash is stored.
+	Token     string `json:"token,omitempty" bson:"-"`
+	TokenHash string `json:"-"`
+
+	Common `bson:",inline"`
+}
+
+// Create a new invitation.
+func NewInvite(role string, expiresAt time.Time, maxUses int) *Invite {
+	return &Invite{Role: role, ExpiresAt: expiresAt, MaxUses: maxUses}
+}
+
+// Get an invitation from a context.
+func InviteFromCtx(ctx context.Context) (*Invite, error) {
+	v := ctx.Value(keyInvite)
+	i, ok := v.(*Invite)
+
+	if !ok {
+		return nil, fmt.Errorf("Could not assert %v as *Invite\n", v)
+	}
+
+	return i, nil
+}
+
+// Insert an invitation into context.
+func (i *Invite) ToCtx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyInvite, i)
+}
+
+// Access to a channel granted by redeeming an invitation. The token is sent
+// in place of the channel's password.
+type Session struct {
+	ChannelID string `json:"channelId"`
+	InviteID  string `json:"inviteId"`
+	Role      string `json:"role"`
+
+	// When the token is no longer accepted.
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// Sent once when the invitation is redeemed. Only its hash is stored.
+	Token     string `json:"token,omitempty" bson:"-"`
+	TokenHash string `json:"-"`
+
+	Common `bson:",inline"`
+}
+
+type InviteRepo interface {
+	// Get the invitations of a channel by the channel's ID that can still be
+	// redeemed.
+	FindChannelID(context.Context, string) ([]Invite, error)
+
+	// Create a new invitation.
+	Insert(context.Context, Archetype) error
+
+	// Find an invitation by its ID.
+	FindID(context.Context, string) (*Invite, error)
+
+	// Find and delete an invitation by its ID.
+	DeleteID(context.Context, string) (*Invite, error)
+
+	// Count a use of the invitation matching a token hash. Returns
+	// NoObjectsFound if no invitation matches or it has expired or been used
+	// up.
+	Redeem(context.Context, string) (*Invite, error)
+}
+
+type SessionRepo interface {
+	// Create a new session.
+	Insert(context.Context, Archetype) error
+
+	// Find an unexpired session by its token hash.
+	FindToken(context.Context, string) (*Session, error)
+
+	// Delete the sessions redeemed from an invitation by the invitation's ID.
+	DeleteInviteID(context.Context, string) error
+
+	// Delete the sessions of a channel by the channel's ID.
+	DeleteChannelID(context.Context, string) error
+}
@@ -0,0 +1,41 @@
+package are_hub
+
+import "context"
+
+// A lap completed by the publisher of a channel. Times are in milliseconds.
+type Lap struct {
+	ChannelID string `json:"channelId"`
+
+	// Identifies the session the lap was driven in. A new session begins when
+	// the completed lap counter is reset.
+	Session string `json:"session"`
+
+	// Car and driver that completed the lap on team channels.
+	Car    string `json:"car,omitempty"`
+	Driver string `json:"driver,omitempty"`
+
+	// Number of laps completed in the session including this lap.
+	Number int64 `json:"number"`
+
+	Time     int64   `json:"time"`
+	Sectors  []int64 `json:"sectors"`
+	FuelUsed float64 `json:"fuelUsed"`
+	Valid    bool    `json:"valid"`
+
+	// Sequence number of the telemetry frame the lap was completed on.
+	Seq uint64 `json:"seq"`
+
+	Common `bson:",inline"`
+}
+
+type LapRepo interface {
+	// Get all laps completed on a channel (by the channel's ID) in order of completion.
+	// Only laps in the session are returned if the session is not empty.
+	FindChannelID(context.Context, string, string) ([]Lap, error)
+
+	// Find a lap by its ID.
+	FindID(context.Context, string) (*Lap, error)
+
+	// Create a new lap.
+	Insert(context.Context, Archetype) error
+}
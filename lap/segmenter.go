@@ -0,0 +1,170 @@
+// Package lap segments a stream of telemetry frames into completed laps.
+package lap
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/frame"
+)
+
+// Paths (dot separated, eg. "graphics.completedLaps") of the fields in telemetry
+// frames used to segment laps. Only CompletedLaps is required; the remaining
+// fields are left as zero values on laps if empty or missing from frames.
+type Fields struct {
+	// Number of laps completed in the session.
+	CompletedLaps string
+
+	// Time of the last completed lap in milliseconds.
+	LastLapTime string
+
+	// Index of the sector the car is currently in (starting at 0).
+	SectorIndex string
+
+	// Time of the last completed sector in milliseconds.
+	LastSectorTime string
+
+	// Fuel remaining.
+	Fuel string
+
+	// Whether the current lap is valid (non-zero or true).
+	Valid string
+}
+
+// Watches the frames broadcast on a channel and detects lap completions.
+// A Segmenter is not safe for concurrent use.
+type Segmenter struct {
+	channelID string
+	fields    Fields
+	session   string
+
+	// state of the lap in progress
+	started   bool
+	laps      int64
+	sector    int64
+	sectors   []int64
+	startFuel float64
+	fuel      float64
+	valid     bool
+}
+
+// Create a new segmenter for the channel matching channelID.
+func NewSegmenter(channelID string, f Fields) *Segmenter {
+	return &Segmenter{channelID: channelID, fields: f}
+}
+
+// Process a frame with sequence number seq. Returns the lap that was completed
+// by the frame or nil.
+func (s *Segmenter) Frame(f frame.Frame, seq uint64) *are_hub.Lap {
+	if fuel, ok := f.Number(s.fields.Fuel); ok {
+		s.fuel = fuel
+	}
+
+	laps, ok := f.Number(s.fields.CompletedLaps)
+
+	if !ok || (s.started && int64(laps) == s.laps) {
+		// partial frame without the lap counter or the lap is in progress
+		s.progress(f)
+
+		return nil
+	}
+
+	if !s.started || int64(laps) < s.laps {
+		// first lap counter seen or the counter was reset; new session
+		s.reset(int64(laps), f)
+		s.progress(f)
+
+		return nil
+	}
+
+	// lap completed
+	lap := &are_hub.Lap{
+		ChannelID: s.channelID,
+		Session:   s.session,
+		Number:    int64(laps),
+		Valid:     s.valid,
+		Seq:       seq,
+	}
+
+	if t, ok := f.Number(s.fields.LastLapTime); ok {
+		lap.Time = int64(t)
+	}
+
+	if len(s.fields.Fuel) > 0 {
+		lap.FuelUsed = s.startFuel - s.fuel
+	}
+
+	lap.Sectors = s.sectors
+
+	if lap.Time > 0 && len(s.sectors) > 0 {
+		// the final sector is whatever remains of the lap time
+		var sum int64
+
+		for _, t := range s.sectors {
+			sum += t
+		}
+
+		lap.Sectors = append(lap.Sectors, lap.Time-sum)
+	}
+
+	s.begin(int64(laps), f)
+	s.progress(f)
+
+	return lap
+}
+
+// Get the current session's identifier.
+func (s *Segmenter) Session() string {
+	return s.session
+}
+
+// Start a new session.
+func (s *Segmenter) reset(laps int64, f frame.Frame) {
+	bytes := make([]byte, 6)
+
+	// reading can only fail if the system's entropy source is unavailable
+	rand.Read(bytes)
+
+	s.session = hex.EncodeToString(bytes)
+	s.started = true
+	s.begin(laps, f)
+}
+
+// Update the lap in progress.
+func (s *Segmenter) progress(f frame.Frame) {
+	if valid, ok := f.Bool(s.fields.Valid); ok && !valid {
+		// a single invalid frame invalidates the entire lap
+		s.valid = false
+	}
+
+	s.sectorChange(f)
+}
+
+// Start a new lap.
+func (s *Segmenter) begin(laps int64, f frame.Frame) {
+	s.laps = laps
+	s.sectors = nil
+	s.sector = 0
+	s.startFuel = s.fuel
+	s.valid = true
+
+	if sector, ok := f.Number(s.fields.SectorIndex); ok {
+		s.sector = int64(sector)
+	}
+}
+
+// Record the last sector time if the car has moved into the next sector.
+func (s *Segmenter) sectorChange(f frame.Frame) {
+	sector, ok := f.Number(s.fields.SectorIndex)
+
+	if !ok || int64(sector) <= s.sector {
+		return
+	}
+
+	s.sector = int64(sector)
+
+	if t, ok := f.Number(s.fields.LastSectorTime); ok {
+		s.sectors = append(s.sectors, int64(t))
+	}
+}
@@ -0,0 +1,135 @@
+package lap
+
+import (
+	"testing"
+
+	"github.com/blacksfk/are_hub/frame"
+)
+
+// Fields used by the test frames.
+var fields = Fields{
+	CompletedLaps:  "graphics.completedLaps",
+	LastLapTime:    "graphics.iLastTime",
+	SectorIndex:    "graphics.currentSectorIndex",
+	LastSectorTime: "graphics.lastSectorTime",
+	Fuel:           "physics.fuel",
+	Valid:          "graphics.isValidLap",
+}
+
+// Decode a JSON encoded frame.
+func decode(t *testing.T, str string) frame.Frame {
+	f, e := frame.Decode([]byte(str))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return f
+}
+
+// Feed frames to s and return the indices of the frames that completed laps.
+func feed(t *testing.T, s *Segmenter, frames ...string) []int {
+	var completed []int
+
+	for i, f := range frames {
+		if lap := s.Frame(decode(t, f), uint64(i+1)); lap != nil {
+			completed = append(completed, i)
+		}
+	}
+
+	return completed
+}
+
+// Are laps detected along with their sectors, fuel used, and validity?
+func TestSegmenterLap(t *testing.T) {
+	s := NewSegmenter("1", fields)
+
+	lap := s.Frame(decode(t, `{"physics":{"fuel":60},"graphics":{"completedLaps":0,"currentSectorIndex":0,"isValidLap":1}}`), 1)
+
+	if lap != nil {
+		t.Fatalf("Expected: no lap on the first frame. Actual: %+v.", lap)
+	}
+
+	frames := []string{
+		// physics only frame
+		`{"physics":{"fuel":59}}`,
+		`{"graphics":{"completedLaps":0,"currentSectorIndex":1,"lastSectorTime":35000,"isValidLap":1}}`,
+		`{"graphics":{"completedLaps":0,"currentSectorIndex":2,"lastSectorTime":41000,"isValidLap":1}}`,
+		`{"physics":{"fuel":57.5},"graphics":{"completedLaps":1,"currentSectorIndex":0,"iLastTime":104000,"isValidLap":0}}`,
+	}
+
+	for i, f := range frames {
+		lap = s.Frame(decode(t, f), uint64(i+2))
+	}
+
+	if lap == nil {
+		t.Fatal("Expected: a lap to be completed on the last frame.")
+	}
+
+	if lap.Number != 1 || lap.Time != 104000 || lap.FuelUsed != 2.5 || lap.Seq != 5 || lap.ChannelID != "1" {
+		t.Fatalf("Expected: lap 1, 104000ms, 2.5 fuel used, seq 5. Actual: %+v.", lap)
+	}
+
+	// the completing frame's validity belongs to the next lap
+	if !lap.Valid {
+		t.Fatal("Expected: valid lap.")
+	}
+
+	expected := []int64{35000, 41000, 28000}
+
+	if len(lap.Sectors) != len(expected) {
+		t.Fatalf("Expected: %v. Actual: %v.", expected, lap.Sectors)
+	}
+
+	for i := range expected {
+		if lap.Sectors[i] != expected[i] {
+			t.Fatalf("Expected: %v. Actual: %v.", expected, lap.Sectors)
+		}
+	}
+
+	// the next lap started invalid
+	lap = s.Frame(decode(t, `{"graphics":{"completedLaps":2,"iLastTime":110000,"isValidLap":1}}`), 6)
+
+	if lap == nil || lap.Valid {
+		t.Fatalf("Expected: invalid lap. Actual: %+v.", lap)
+	}
+}
+
+// Is a new session started when the lap counter is reset?
+func TestSegmenterSession(t *testing.T) {
+	s := NewSegmenter("1", fields)
+	completed := feed(t, s,
+		`{"graphics":{"completedLaps":10}}`,
+		`{"graphics":{"completedLaps":11}}`,
+	)
+
+	if len(completed) != 1 {
+		t.Fatalf("Expected: 1 lap. Actual: %d.", len(completed))
+	}
+
+	session := s.Session()
+
+	// counter reset; shouldn't count as a lap
+	completed = feed(t, s,
+		`{"graphics":{"completedLaps":0}}`,
+		`{"graphics":{"completedLaps":0}}`,
+	)
+
+	if len(completed) != 0 {
+		t.Fatalf("Expected: 0 laps. Actual: %d.", len(completed))
+	}
+
+	if s.Session() == session {
+		t.Fatal("Expected: a new session.")
+	}
+}
+
+// Are frames without the lap counter field ignored?
+func TestSegmenterMissingFields(t *testing.T) {
+	s := NewSegmenter("1", Fields{CompletedLaps: "laps"})
+	completed := feed(t, s, `{"speed":200}`, `{"laps":"3"}`, `{"laps":1}`, `{"laps":2}`)
+
+	if len(completed) != 1 || completed[0] != 3 {
+		t.Fatalf("Expected: lap completed on the 4th frame. Actual: %v.", completed)
+	}
+}
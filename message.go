@@ -0,0 +1,29 @@
+package are_hub
+
+import "context"
+
+// A chat message or annotation posted to a channel by one of its subscribers.
+// Seq is the sequence number of the last telemetry frame broadcast on the
+// channel when the message was posted so that messages can be interleaved with
+// recorded telemetry when a session is replayed.
+type Message struct {
+	ChannelID string `json:"channelId"`
+	Author    string `json:"author"`
+	Body      string `json:"body"`
+	Seq       uint64 `json:"seq"`
+	Common    `bson:",inline"`
+}
+
+// Create a new message.
+func NewMessage(channelID, author, body string, seq uint64) *Message {
+	return &Message{ChannelID: channelID, Author: author, Body: body, Seq: seq}
+}
+
+type MessageRepo interface {
+	// Get all messages posted to a channel (by the channel's ID) ordered by
+	// sequence number.
+	FindChannelID(context.Context, string) ([]Message, error)
+
+	// Create a new message.
+	Insert(context.Context, Archetype) error
+}
@@ -0,0 +1,102 @@
+// Package metrics defines the Prometheus metrics exposed by the hub.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const NAMESPACE = "are_hub"
+
+// Registry the hub's metrics are registered in. Collectors outside of this
+// package (eg. the telemetry server's live channel gauges) are registered when
+// the services are initialised.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Telemetry frames published by channel.
+	FramesPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "frames_published_total",
+		Help:      "Telemetry frames published.",
+	}, []string{"channel"})
+
+	// Bytes of telemetry frames received from publishers by channel.
+	BytesIn = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "received_bytes_total",
+		Help:      "Bytes of telemetry frames received from publishers.",
+	}, []string{"channel"})
+
+	// Bytes written to subscribers by channel.
+	BytesOut = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "sent_bytes_total",
+		Help:      "Bytes of messages written to subscribers.",
+	}, []string{"channel"})
+
+	// Messages not delivered to subscribers because their buffer was full.
+	FramesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "frames_dropped_total",
+		Help:      "Messages not delivered to subscribers because their buffer was full.",
+	}, []string{"channel"})
+
+	// Subscribers disconnected because their buffer was full.
+	SubscribersDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "subscribers_dropped_total",
+		Help:      "Subscribers disconnected because their buffer was full.",
+	}, []string{"channel"})
+
+	// Duration of argon2 password verifications by result (match, mismatch, or error).
+	PasswordVerify = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "password_verify_duration_seconds",
+		Help:      "Duration of argon2 password verifications.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 10),
+	}, []string{"result"})
+
+	// Websocket connections closed before the subscriber was added to a
+	// channel by close code.
+	HandshakeFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "websocket_handshake_failures_total",
+		Help:      "Websocket connections closed before completing the connection procedure.",
+	}, []string{"code"})
+
+	// Duration of MongoDB repository calls by collection and method.
+	RepoCall = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "repository_call_duration_seconds",
+		Help:      "Duration of MongoDB repository calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"collection", "method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		FramesPublished,
+		BytesIn,
+		BytesOut,
+		FramesDropped,
+		SubscribersDropped,
+		PasswordVerify,
+		HandshakeFailures,
+		RepoCall,
+	)
+}
+
+// Start timing a call. The returned function observes the time elapsed since
+// Since was called in o. Eg. defer metrics.Since(h)()
+func Since(o prometheus.Observer) func() {
+	start := time.Now()
+
+	return func() {
+		o.Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,28 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Implements are_hub.AlertRepo.
+type AlertRepo struct {
+	FindChannelIDFunc   func(context.Context, string) ([]are_hub.Alert, error)
+	FindChannelIDCalled bool
+
+	InsertFunc   func(context.Context, are_hub.Archetype) error
+	InsertCalled bool
+}
+
+func (r *AlertRepo) FindChannelID(ctx context.Context, id string) ([]are_hub.Alert, error) {
+	r.FindChannelIDCalled = true
+
+	return r.FindChannelIDFunc(ctx, id)
+}
+
+func (r *AlertRepo) Insert(ctx context.Context, archetype are_hub.Archetype) error {
+	r.InsertCalled = true
+
+	return r.InsertFunc(ctx, archetype)
+}
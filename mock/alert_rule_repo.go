@@ -0,0 +1,55 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Implements are_hub.AlertRuleRepo.
+type AlertRuleRepo struct {
+	FindChannelIDFunc   func(context.Context, string) ([]are_hub.AlertRule, error)
+	FindChannelIDCalled bool
+
+	InsertFunc   func(context.Context, are_hub.Archetype) error
+	InsertCalled bool
+
+	FindIDFunc   func(context.Context, string) (*are_hub.AlertRule, error)
+	FindIDCalled bool
+
+	UpdateIDFunc   func(context.Context, string, are_hub.Archetype) error
+	UpdateIDCalled bool
+
+	DeleteIDFunc   func(context.Context, string) (*are_hub.AlertRule, error)
+	DeleteIDCalled bool
+}
+
+func (r *AlertRuleRepo) FindChannelID(ctx context.Context, id string) ([]are_hub.AlertRule, error) {
+	r.FindChannelIDCalled = true
+
+	return r.FindChannelIDFunc(ctx, id)
+}
+
+func (r *AlertRuleRepo) Insert(ctx context.Context, archetype are_hub.Archetype) error {
+	r.InsertCalled = true
+
+	return r.InsertFunc(ctx, archetype)
+}
+
+func (r *AlertRuleRepo) FindID(ctx context.Context, id string) (*are_hub.AlertRule, error) {
+	r.FindIDCalled = true
+
+	return r.FindIDFunc(ctx, id)
+}
+
+func (r *AlertRuleRepo) UpdateID(ctx context.Context, id string, rule are_hub.Archetype) error {
+	r.UpdateIDCalled = true
+
+	return r.UpdateIDFunc(ctx, id, rule)
+}
+
+func (r *AlertRuleRepo) DeleteID(ctx context.Context, id string) (*are_hub.AlertRule, error) {
+	r.DeleteIDCalled = true
+
+	return r.DeleteIDFunc(ctx, id)
+}
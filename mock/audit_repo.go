@@ -0,0 +1,28 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Implements are_hub.AuditRepo.
+type AuditRepo struct {
+	QueryFunc   func(context.Context, are_hub.AuditQuery) ([]are_hub.AuditEvent, error)
+	QueryCalled bool
+
+	InsertFunc   func(context.Context, are_hub.Archetype) error
+	InsertCalled bool
+}
+
+func (r *AuditRepo) Query(ctx context.Context, q are_hub.AuditQuery) ([]are_hub.AuditEvent, error) {
+	r.QueryCalled = true
+
+	return r.QueryFunc(ctx, q)
+}
+
+func (r *AuditRepo) Insert(ctx context.Context, archetype are_hub.Archetype) error {
+	r.InsertCalled = true
+
+	return r.InsertFunc(ctx, archetype)
+}
@@ -2,29 +2,54 @@ package mock
 
 import (
 	"context"
+	"time"
 
 	"github.com/blacksfk/are_hub"
 )
 
-// Implements are_hub.ChannelRepo.
+// Implements are_hub.ChannelRepo and are_hub.ChannelWatcher.
 type ChannelRepo struct {
 	AllFunc   func(context.Context) ([]are_hub.Channel, error)
 	AllCalled bool
 
+	QueryFunc   func(context.Context, are_hub.ChannelQuery) (*are_hub.ChannelPage, error)
+	QueryCalled bool
+
 	InsertFunc   func(context.Context, are_hub.Archetype) error
 	InsertCalled bool
 
 	FindIDFunc   func(context.Context, string) (*are_hub.Channel, error)
 	FindIDCalled bool
 
-	UpdateIDFunc   func(context.Context, string, are_hub.Archetype) error
+	UpdateIDFunc   func(context.Context, string, int64, are_hub.Versioned) error
 	UpdateIDCalled bool
 
+	PatchIDFunc   func(context.Context, string, int64, are_hub.ChannelPatch) (*are_hub.Channel, error)
+	PatchIDCalled bool
+
+	FillIDFunc   func(context.Context, string, are_hub.Metadata) (*are_hub.Channel, error)
+	FillIDCalled bool
+
+	TouchIDFunc   func(context.Context, string) error
+	TouchIDCalled bool
+
 	DeleteIDFunc   func(context.Context, string) (*are_hub.Channel, error)
 	DeleteIDCalled bool
 
+	TrashFunc   func(context.Context) ([]are_hub.Channel, error)
+	TrashCalled bool
+
+	RestoreIDFunc   func(context.Context, string) (*are_hub.Channel, error)
+	RestoreIDCalled bool
+
+	PurgeFunc   func(context.Context, time.Time) (int64, error)
+	PurgeCalled bool
+
 	CountFunc   func(context.Context) (int64, error)
 	CountCalled bool
+
+	WatchFunc   func(context.Context, func(are_hub.ChannelChange)) error
+	WatchCalled bool
 }
 
 func (r *ChannelRepo) All(ctx context.Context) ([]are_hub.Channel, error) {
@@ -33,6 +58,12 @@ func (r *ChannelRepo) All(ctx context.Context) ([]are_hub.Channel, error) {
 	return r.AllFunc(ctx)
 }
 
+func (r *ChannelRepo) Query(ctx context.Context, q are_hub.ChannelQuery) (*are_hub.ChannelPage, error) {
+	r.QueryCalled = true
+
+	return r.QueryFunc(ctx, q)
+}
+
 func (r *ChannelRepo) Insert(ctx context.Context, archetype are_hub.Archetype) error {
 	r.InsertCalled = true
 
@@ -45,10 +76,28 @@ func (r *ChannelRepo) FindID(ctx context.Context, id string) (*are_hub.Channel,
 	return r.FindIDFunc(ctx, id)
 }
 
-func (r *ChannelRepo) UpdateID(ctx context.Context, id string, channel are_hub.Archetype) error {
+func (r *ChannelRepo) UpdateID(ctx context.Context, id string, version int64, channel are_hub.Versioned) error {
 	r.UpdateIDCalled = true
 
-	return r.UpdateIDFunc(ctx, id, channel)
+	return r.UpdateIDFunc(ctx, id, version, channel)
+}
+
+func (r *ChannelRepo) PatchID(ctx context.Context, id string, version int64, patch are_hub.ChannelPatch) (*are_hub.Channel, error) {
+	r.PatchIDCalled = true
+
+	return r.PatchIDFunc(ctx, id, version, patch)
+}
+
+func (r *ChannelRepo) FillID(ctx context.Context, id string, m are_hub.Metadata) (*are_hub.Channel, error) {
+	r.FillIDCalled = true
+
+	return r.FillIDFunc(ctx, id, m)
+}
+
+func (r *ChannelRepo) TouchID(ctx context.Context, id string) error {
+	r.TouchIDCalled = true
+
+	return r.TouchIDFunc(ctx, id)
 }
 
 func (r *ChannelRepo) DeleteID(ctx context.Context, id string) (*are_hub.Channel, error) {
@@ -57,6 +106,30 @@ func (r *ChannelRepo) DeleteID(ctx context.Context, id string) (*are_hub.Channel
 	return r.DeleteIDFunc(ctx, id)
 }
 
+func (r *ChannelRepo) Trash(ctx context.Context) ([]are_hub.Channel, error) {
+	r.TrashCalled = true
+
+	return r.TrashFunc(ctx)
+}
+
+func (r *ChannelRepo) RestoreID(ctx context.Context, id string) (*are_hub.Channel, error) {
+	r.RestoreIDCalled = true
+
+	return r.RestoreIDFunc(ctx, id)
+}
+
+func (r *ChannelRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	r.PurgeCalled = true
+
+	return r.PurgeFunc(ctx, before)
+}
+
 func (r *ChannelRepo) Count(ctx context.Context) (int64, error) {
 	return 0, nil
 }
+
+func (r *ChannelRepo) Watch(ctx context.Context, fn func(are_hub.ChannelChange)) error {
+	r.WatchCalled = true
+
+	return r.WatchFunc(ctx, fn)
+}
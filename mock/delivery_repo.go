@@ -0,0 +1,28 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Implements are_hub.DeliveryRepo.
+type DeliveryRepo struct {
+	FindWebhookIDFunc   func(context.Context, string) ([]are_hub.Delivery, error)
+	FindWebhookIDCalled bool
+
+	InsertFunc   func(context.Context, are_hub.Archetype) error
+	InsertCalled bool
+}
+
+func (r *DeliveryRepo) FindWebhookID(ctx context.Context, id string) ([]are_hub.Delivery, error) {
+	r.FindWebhookIDCalled = true
+
+	return r.FindWebhookIDFunc(ctx, id)
+}
+
+func (r *DeliveryRepo) Insert(ctx context.Context, archetype are_hub.Archetype) error {
+	r.InsertCalled = true
+
+	return r.InsertFunc(ctx, archetype)
+}
@@ -0,0 +1,24 @@
+package mock
+
+import (
+	"sync"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Implements are_hub.Emitter. Events are emitted concurrently so EmitCalled is
+// guarded by a mutex.
+type Emitter struct {
+	EmitFunc   func(are_hub.Event)
+	EmitCalled bool
+
+	mtx sync.Mutex
+}
+
+func (em *Emitter) Emit(ev are_hub.Event) {
+	em.mtx.Lock()
+	em.EmitCalled = true
+	em.mtx.Unlock()
+
+	em.EmitFunc(ev)
+}
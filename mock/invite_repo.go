@@ -0,0 +1,55 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Implements are_hub.InviteRepo.
+type InviteRepo struct {
+	FindChannelIDFunc   func(context.Context, string) ([]are_hub.Invite, error)
+	FindChannelIDCalled bool
+
+	InsertFunc   func(context.Context, are_hub.Archetype) error
+	InsertCalled bool
+
+	FindIDFunc   func(context.Context, string) (*are_hub.Invite, error)
+	FindIDCalled bool
+
+	DeleteIDFunc   func(context.Context, string) (*are_hub.Invite, error)
+	DeleteIDCalled bool
+
+	RedeemFunc   func(context.Context, string) (*are_hub.Invite, error)
+	RedeemCalled bool
+}
+
+func (r *InviteRepo) FindChannelID(ctx context.Context, id string) ([]are_hub.Invite, error) {
+	r.FindChannelIDCalled = true
+
+	return r.FindChannelIDFunc(ctx, id)
+}
+
+func (r *InviteRepo) Insert(ctx context.Context, archetype are_hub.Archetype) error {
+	r.InsertCalled = true
+
+	return r.InsertFunc(ctx, archetype)
+}
+
+func (r *InviteRepo) FindID(ctx context.Context, id string) (*are_hub.Invite, error) {
+	r.FindIDCalled = true
+
+	return r.FindIDFunc(ctx, id)
+}
+
+func (r *InviteRepo) DeleteID(ctx context.Context, id string) (*are_hub.Invite, error) {
+	r.DeleteIDCalled = true
+
+	return r.DeleteIDFunc(ctx, id)
+}
+
+func (r *InviteRepo) Redeem(ctx context.Context, hash string) (*are_hub.Invite, error) {
+	r.RedeemCalled = true
+
+	return r.RedeemFunc(ctx, hash)
+}
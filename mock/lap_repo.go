@@ -0,0 +1,37 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Implements are_hub.LapRepo.
+type LapRepo struct {
+	FindChannelIDFunc   func(context.Context, string, string) ([]are_hub.Lap, error)
+	FindChannelIDCalled bool
+
+	FindIDFunc   func(context.Context, string) (*are_hub.Lap, error)
+	FindIDCalled bool
+
+	InsertFunc   func(context.Context, are_hub.Archetype) error
+	InsertCalled bool
+}
+
+func (r *LapRepo) FindChannelID(ctx context.Context, id, session string) ([]are_hub.Lap, error) {
+	r.FindChannelIDCalled = true
+
+	return r.FindChannelIDFunc(ctx, id, session)
+}
+
+func (r *LapRepo) FindID(ctx context.Context, id string) (*are_hub.Lap, error) {
+	r.FindIDCalled = true
+
+	return r.FindIDFunc(ctx, id)
+}
+
+func (r *LapRepo) Insert(ctx context.Context, archetype are_hub.Archetype) error {
+	r.InsertCalled = true
+
+	return r.InsertFunc(ctx, archetype)
+}
@@ -0,0 +1,28 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Implements are_hub.MessageRepo.
+type MessageRepo struct {
+	FindChannelIDFunc   func(context.Context, string) ([]are_hub.Message, error)
+	FindChannelIDCalled bool
+
+	InsertFunc   func(context.Context, are_hub.Archetype) error
+	InsertCalled bool
+}
+
+func (r *MessageRepo) FindChannelID(ctx context.Context, id string) ([]are_hub.Message, error) {
+	r.FindChannelIDCalled = true
+
+	return r.FindChannelIDFunc(ctx, id)
+}
+
+func (r *MessageRepo) Insert(ctx context.Context, archetype are_hub.Archetype) error {
+	r.InsertCalled = true
+
+	return r.InsertFunc(ctx, archetype)
+}
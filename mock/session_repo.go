@@ -0,0 +1,46 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Implements are_hub.SessionRepo.
+type SessionRepo struct {
+	InsertFunc   func(context.Context, are_hub.Archetype) error
+	InsertCalled bool
+
+	FindTokenFunc   func(context.Context, string) (*are_hub.Session, error)
+	FindTokenCalled bool
+
+	DeleteInviteIDFunc   func(context.Context, string) error
+	DeleteInviteIDCalled bool
+
+	DeleteChannelIDFunc   func(context.Context, string) error
+	DeleteChannelIDCalled bool
+}
+
+func (r *SessionRepo) Insert(ctx context.Context, archetype are_hub.Archetype) error {
+	r.InsertCalled = true
+
+	return r.InsertFunc(ctx, archetype)
+}
+
+func (r *SessionRepo) FindToken(ctx context.Context, hash string) (*are_hub.Session, error) {
+	r.FindTokenCalled = true
+
+	return r.FindTokenFunc(ctx, hash)
+}
+
+func (r *SessionRepo) DeleteInviteID(ctx context.Context, id string) error {
+	r.DeleteInviteIDCalled = true
+
+	return r.DeleteInviteIDFunc(ctx, id)
+}
+
+func (r *SessionRepo) DeleteChannelID(ctx context.Context, id string) error {
+	r.DeleteChannelIDCalled = true
+
+	return r.DeleteChannelIDFunc(ctx, id)
+}
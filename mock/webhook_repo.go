@@ -0,0 +1,55 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+)
+
+// Implements are_hub.WebhookRepo.
+type WebhookRepo struct {
+	FindChannelIDFunc   func(context.Context, string) ([]are_hub.Webhook, error)
+	FindChannelIDCalled bool
+
+	InsertFunc   func(context.Context, are_hub.Archetype) error
+	InsertCalled bool
+
+	FindIDFunc   func(context.Context, string) (*are_hub.Webhook, error)
+	FindIDCalled bool
+
+	UpdateIDFunc   func(context.Context, string, are_hub.Archetype) error
+	UpdateIDCalled bool
+
+	DeleteIDFunc   func(context.Context, string) (*are_hub.Webhook, error)
+	DeleteIDCalled bool
+}
+
+func (r *WebhookRepo) FindChannelID(ctx context.Context, id string) ([]are_hub.Webhook, error) {
+	r.FindChannelIDCalled = true
+
+	return r.FindChannelIDFunc(ctx, id)
+}
+
+func (r *WebhookRepo) Insert(ctx context.Context, archetype are_hub.Archetype) error {
+	r.InsertCalled = true
+
+	return r.InsertFunc(ctx, archetype)
+}
+
+func (r *WebhookRepo) FindID(ctx context.Context, id string) (*are_hub.Webhook, error) {
+	r.FindIDCalled = true
+
+	return r.FindIDFunc(ctx, id)
+}
+
+func (r *WebhookRepo) UpdateID(ctx context.Context, id string, webhook are_hub.Archetype) error {
+	r.UpdateIDCalled = true
+
+	return r.UpdateIDFunc(ctx, id, webhook)
+}
+
+func (r *WebhookRepo) DeleteID(ctx context.Context, id string) (*are_hub.Webhook, error) {
+	r.DeleteIDCalled = true
+
+	return r.DeleteIDFunc(ctx, id)
+}
@@ -0,0 +1,30 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Implements are_hub.AlertRepo.
+type Alert struct {
+	collection
+}
+
+// Create an alerts collection in db.
+func NewAlertCollection(client *mongo.Client, db string) Alert {
+	return Alert{collection{client, db, "alerts"}}
+}
+
+func (a Alert) FindChannelID(ctx context.Context, id string) ([]are_hub.Alert, error) {
+	var alerts []are_hub.Alert
+
+	// newest first
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "createdat", Value: -1}})
+
+	return alerts, a.find(ctx, bson.M{"channelid": id}, &alerts, opts)
+}
@@ -0,0 +1,37 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Implements are_hub.AlertRuleRepo.
+type AlertRule struct {
+	collection
+}
+
+// Create an alert rules collection in db.
+func NewAlertRuleCollection(client *mongo.Client, db string) AlertRule {
+	return AlertRule{collection{client, db, "alertRules"}}
+}
+
+func (a AlertRule) FindChannelID(ctx context.Context, id string) ([]are_hub.AlertRule, error) {
+	var rules []are_hub.AlertRule
+
+	return rules, a.find(ctx, bson.M{"channelid": id}, &rules)
+}
+
+func (a AlertRule) FindID(ctx context.Context, id string) (*are_hub.AlertRule, error) {
+	rule := &are_hub.AlertRule{}
+
+	return rule, a.findID(ctx, id, rule)
+}
+
+func (a AlertRule) DeleteID(ctx context.Context, id string) (*are_hub.AlertRule, error) {
+	rule := &are_hub.AlertRule{}
+
+	return rule, a.deleteID(ctx, id, rule)
+}
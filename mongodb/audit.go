@@ -0,0 +1,45 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Implements are_hub.AuditRepo.
+type Audit struct {
+	collection
+}
+
+// Create an audit events collection in db.
+func NewAuditCollection(client *mongo.Client, db string) Audit {
+	return Audit{collection{client, db, "auditEvents"}}
+}
+
+func (a Audit) Query(ctx context.Context, q are_hub.AuditQuery) ([]are_hub.AuditEvent, error) {
+	var events []are_hub.AuditEvent
+	filter := bson.M{}
+
+	for key, v := range map[string]string{"actor": q.Actor, "action": q.Action, "targetid": q.TargetID} {
+		if len(v) > 0 {
+			filter[key] = v
+		}
+	}
+
+	if created := between(q.After, q.Before); created != nil {
+		filter["createdat"] = created
+	}
+
+	// newest first
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "createdat", Value: -1}, {Key: "_id", Value: -1}})
+
+	if q.Limit > 0 {
+		opts.SetLimit(q.Limit)
+	}
+
+	return events, a.find(ctx, filter, &events, opts)
+}
@@ -2,16 +2,51 @@ package mongodb
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
 
 	"github.com/blacksfk/are_hub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// Implements are_hub.ChannelRepo.
+const (
+	// How often channels are polled for changes on servers without change
+	// streams.
+	POLL_INTERVAL = time.Second * 5
+
+	// Error code of change streams opened on standalone servers.
+	codeNoChangeStreams = 40573
+)
+
+// Implements are_hub.ChannelRepo and are_hub.ChannelWatcher.
 type Channel struct {
 	collection
 }
 
+// Document fields by the fields channels can be sorted by.
+var channelSorts = map[string]string{
+	are_hub.SORT_NAME:    "name",
+	are_hub.SORT_CREATED: "createdat",
+	are_hub.SORT_UPDATED: "updatedat",
+}
+
+// Change stream event fields used to report changes.
+type channelEvent struct {
+	OperationType string `bson:"operationType"`
+
+	DocumentKey struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+
+	// Looked up for updates. Nil if the channel was deleted since.
+	FullDocument *are_hub.Channel `bson:"fullDocument"`
+}
+
 // Create a channels collection in db.
 func NewChannelCollection(client *mongo.Client, db string) Channel {
 	return Channel{collection{client, db, "channels"}}
@@ -23,14 +58,391 @@ func (c Channel) All(ctx context.Context) ([]are_hub.Channel, error) {
 	return channels, c.all(ctx, &channels)
 }
 
+func (c Channel) Query(ctx context.Context, q are_hub.ChannelQuery) (*are_hub.ChannelPage, error) {
+	filter := bson.M{}
+
+	for field, v := range map[string]string{
+		"name":     q.Name,
+		"sim":      q.Sim,
+		"carmodel": q.CarModel,
+		"track":    q.Track,
+		"event":    q.Event,
+		"team":     q.Team,
+	} {
+		if len(v) > 0 {
+			filter[field] = primitive.Regex{Pattern: regexp.QuoteMeta(v), Options: "i"}
+		}
+	}
+
+	if len(q.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": q.Tags}
+	}
+
+	switch q.Visibility {
+	case "":
+	case are_hub.VISIBILITY_PRIVATE:
+		// channels without a visibility are private
+		filter["visibility"] = bson.M{"$in": bson.A{q.Visibility, "", nil}}
+	default:
+		filter["visibility"] = q.Visibility
+	}
+
+	if created := between(q.CreatedAfter, q.CreatedBefore); created != nil {
+		filter["createdat"] = created
+	}
+
+	if updated := between(q.UpdatedAfter, q.UpdatedBefore); updated != nil {
+		filter["updatedat"] = updated
+	}
+
+	field, ok := channelSorts[q.Sort]
+
+	if !ok {
+		field = "createdat"
+	}
+
+	order := 1
+
+	if q.Descending {
+		order = -1
+	}
+
+	// break ties by ID so that channels don't move between pages
+	sort := bson.D{{Key: field, Value: order}, {Key: "_id", Value: order}}
+	page := &are_hub.ChannelPage{}
+	total, e := c.page(ctx, filter, &page.Channels, sort, q.Offset, q.Limit)
+	page.Total = total
+
+	return page, e
+}
+
 func (c Channel) FindID(ctx context.Context, id string) (*are_hub.Channel, error) {
 	channel := &are_hub.Channel{}
 
 	return channel, c.findID(ctx, id, channel)
 }
 
+func (c Channel) UpdateID(ctx context.Context, id string, version int64, channel are_hub.Versioned) error {
+	return c.updateIDVersion(ctx, id, version, channel)
+}
+
+func (c Channel) PatchID(ctx context.Context, id string, version int64, p are_hub.ChannelPatch) (*are_hub.Channel, error) {
+	fields := bson.M{}
+
+	if p.Name != nil {
+		fields["name"] = *p.Name
+	}
+
+	if p.Schema != nil {
+		fields["schema"] = *p.Schema
+	}
+
+	if p.Cars != nil {
+		fields["cars"] = *p.Cars
+	}
+
+	if p.MaxSubscribers != nil {
+		fields["maxsubscribers"] = *p.MaxSubscribers
+	}
+
+	if p.BufferLength != nil {
+		fields["bufferlength"] = *p.BufferLength
+	}
+
+	if p.SlowPolicy != nil {
+		fields["slowpolicy"] = *p.SlowPolicy
+	}
+
+	if p.Visibility != nil {
+		fields["visibility"] = *p.Visibility
+	}
+
+	for field, v := range map[string]*string{
+		"sim":      p.Sim,
+		"carmodel": p.CarModel,
+		"track":    p.Track,
+		"event":    p.Event,
+		"team":     p.Team,
+	} {
+		if v != nil {
+			fields[field] = *v
+		}
+	}
+
+	if p.Tags != nil {
+		fields["tags"] = *p.Tags
+	}
+
+	if p.Password != nil {
+		fields["password"] = *p.Password
+	}
+
+	channel := &are_hub.Channel{}
+
+	return channel, c.patchIDVersion(ctx, id, version, fields, channel)
+}
+
+func (c Channel) TouchID(ctx context.Context, hex string) error {
+	defer c.observe("TouchID")()
+
+	id, e := primitive.ObjectIDFromHex(hex)
+
+	if e != nil {
+		return e
+	}
+
+	update := bson.M{"$set": bson.M{"updatedat": time.Now().UTC()}}
+	res, e := c.get().UpdateOne(ctx, live(bson.M{"_id": id}), update)
+
+	if e != nil {
+		return e
+	}
+
+	if res.MatchedCount == 0 {
+		return are_hub.NewNoObjectsFound(c.name, "id: "+hex)
+	}
+
+	return nil
+}
+
+func (c Channel) FillID(ctx context.Context, hex string, m are_hub.Metadata) (*are_hub.Channel, error) {
+	defer c.observe("FillID")()
+
+	id, e := primitive.ObjectIDFromHex(hex)
+
+	if e != nil {
+		return nil, e
+	}
+
+	// the version is left as is so that filling the metadata doesn't fail the
+	// next update of clients holding the current ETag
+	empty := bson.A{}
+	set := bson.M{"updatedat": time.Now().UTC()}
+
+	for field, v := range map[string]string{
+		"sim":      m.Sim,
+		"carmodel": m.CarModel,
+		"track":    m.Track,
+		"event":    m.Event,
+		"team":     m.Team,
+	} {
+		if len(v) == 0 {
+			continue
+		}
+
+		// missing, null, or empty
+		unset := bson.M{"$in": bson.A{bson.M{"$ifNull": bson.A{"$" + field, ""}}, bson.A{""}}}
+		set[field] = bson.M{"$cond": bson.A{unset, v, "$" + field}}
+		empty = append(empty, bson.M{field: bson.M{"$in": bson.A{"", nil}}})
+	}
+
+	if len(empty) == 0 {
+		return nil, are_hub.NewNoObjectsFound(c.name, "empty metadata of id: "+hex)
+	}
+
+	// only update channels with at least one of the fields empty
+	filter := live(bson.M{"_id": id, "$or": empty})
+	opts := options.FindOneAndUpdate()
+	opts.SetReturnDocument(options.After)
+
+	channel := &are_hub.Channel{}
+	e = c.get().FindOneAndUpdate(ctx, filter, mongo.Pipeline{{{Key: "$set", Value: set}}}, opts).Decode(channel)
+
+	if e == mongo.ErrNoDocuments {
+		return nil, are_hub.NewNoObjectsFound(c.name, "empty metadata of id: "+hex)
+	}
+
+	if e != nil {
+		return nil, e
+	}
+
+	return channel, nil
+}
+
 func (c Channel) DeleteID(ctx context.Context, id string) (*are_hub.Channel, error) {
 	channel := &are_hub.Channel{}
 
-	return channel, c.deleteID(ctx, id, channel)
+	return channel, c.trashID(ctx, id, channel)
+}
+
+func (c Channel) Trash(ctx context.Context) ([]are_hub.Channel, error) {
+	var channels []are_hub.Channel
+
+	return channels, c.trash(ctx, &channels)
+}
+
+func (c Channel) RestoreID(ctx context.Context, id string) (*are_hub.Channel, error) {
+	channel := &are_hub.Channel{}
+
+	// another channel may have taken the name while it was in the trash
+	return channel, c.duplicate(c.restoreID(ctx, id, channel))
+}
+
+func (c Channel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	defer c.observe("Purge")()
+
+	filter := bson.M{"deletedat": bson.M{"$ne": nil, "$lte": before}}
+	cursor, e := c.get().Find(ctx, filter)
+
+	if e != nil {
+		return 0, e
+	}
+
+	var channels []are_hub.Channel
+	e = cursor.All(ctx, &channels)
+
+	if e != nil {
+		return 0, e
+	}
+
+	var n int64
+
+	for _, channel := range channels {
+		e = c.purge(ctx, channel.ID)
+
+		if e != nil {
+			return n, e
+		}
+
+		n++
+	}
+
+	return n, nil
+}
+
+// Permanently delete the channel matching id and the documents of every
+// collection belonging to it. The channel is deleted last so that it stays in
+// the trash to be purged again if deleting any documents fails.
+func (c Channel) purge(ctx context.Context, id string) error {
+	dependents := []collection{
+		NewMessageCollection(c.client, c.db).collection,
+		NewLapCollection(c.client, c.db).collection,
+		NewAlertRuleCollection(c.client, c.db).collection,
+		NewAlertCollection(c.client, c.db).collection,
+		NewWebhookCollection(c.client, c.db).collection,
+		NewDeliveryCollection(c.client, c.db).collection,
+		NewInviteCollection(c.client, c.db).collection,
+		NewSessionCollection(c.client, c.db).collection,
+	}
+
+	for _, d := range dependents {
+		_, e := d.get().DeleteMany(ctx, bson.M{"channelid": id})
+
+		if e != nil {
+			return fmt.Errorf("%s: %w", d.name, e)
+		}
+	}
+
+	oid, e := primitive.ObjectIDFromHex(id)
+
+	if e != nil {
+		return e
+	}
+
+	_, e = c.get().DeleteOne(ctx, bson.M{"_id": oid})
+
+	return e
+}
+
+// Report changes through the collection's change stream. Standalone servers
+// (without a replica set) don't support change streams so the collection is
+// polled every POLL_INTERVAL instead.
+func (c Channel) Watch(ctx context.Context, fn func(are_hub.ChannelChange)) error {
+	e := c.stream(ctx, fn)
+
+	if se, ok := e.(mongo.ServerError); ok && se.HasErrorCode(codeNoChangeStreams) {
+		return c.poll(ctx, fn)
+	}
+
+	return e
+}
+
+// Report the changes received through the collection's change stream.
+func (c Channel) stream(ctx context.Context, fn func(are_hub.ChannelChange)) error {
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	cs, e := c.get().Watch(ctx, mongo.Pipeline{}, opts)
+
+	if e != nil {
+		return e
+	}
+
+	defer cs.Close(context.Background())
+
+	for cs.Next(ctx) {
+		event := channelEvent{}
+		e = cs.Decode(&event)
+
+		if e != nil {
+			return e
+		}
+
+		change := are_hub.ChannelChange{Type: are_hub.CHANGE_DELETED, ID: event.DocumentKey.ID.Hex()}
+
+		switch event.OperationType {
+		case "insert", "update", "replace":
+			// channels moved to the trash are reported as deleted
+			if event.FullDocument != nil && event.FullDocument.DeletedAt == nil {
+				change.Type = are_hub.CHANGE_UPDATED
+				change.Channel = event.FullDocument
+			}
+		case "delete":
+		default:
+			// the collection was dropped or renamed; the stream is invalidated
+			continue
+		}
+
+		fn(change)
+	}
+
+	e = cs.Err()
+
+	if e == nil {
+		e = errors.New("Change stream closed.")
+	}
+
+	return e
+}
+
+// Report the channels created, updated, or deleted between polls. Channels
+// are compared by when they were last updated.
+func (c Channel) poll(ctx context.Context, fn func(are_hub.ChannelChange)) error {
+	var seen map[string]time.Time
+
+	ticker := time.NewTicker(POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		channels, e := c.All(ctx)
+
+		if e != nil {
+			return e
+		}
+
+		current := make(map[string]time.Time, len(channels))
+
+		for i := range channels {
+			channel := &channels[i]
+			current[channel.ID] = channel.UpdatedAt
+			updated, ok := seen[channel.ID]
+
+			// nothing to compare against on the first poll
+			if seen != nil && (!ok || !updated.Equal(channel.UpdatedAt)) {
+				fn(are_hub.ChannelChange{Type: are_hub.CHANGE_UPDATED, ID: channel.ID, Channel: channel})
+			}
+		}
+
+		for id := range seen {
+			if _, ok := current[id]; !ok {
+				fn(are_hub.ChannelChange{Type: are_hub.CHANGE_DELETED, ID: id})
+			}
+		}
+
+		seen = current
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
@@ -3,14 +3,27 @@ package mongodb
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"time"
 
 	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/metrics"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Extracts the field of a duplicate key error message.
+var dupKey = regexp.MustCompile(`dup key: \{ ?(\w+):`)
+
+// Codes of the command errors returned when dropping an index that doesn't
+// exist.
+const (
+	namespaceNotFound = 26
+	indexNotFound     = 27
+)
+
 // Wrapper around mongo.Client and mongo.Collection. To be used in struct
 // composition with collections.
 type collection struct {
@@ -29,14 +42,36 @@ func (c collection) get() *mongo.Collection {
 	return c.client.Database(c.db).Collection(c.name)
 }
 
+// Start timing a call to method. The returned function records the duration.
+func (c collection) observe(method string) func() {
+	return metrics.Since(metrics.RepoCall.WithLabelValues(c.name, method))
+}
+
 // Get a count of the number of documents in a collection.
 func (c collection) Count(ctx context.Context) (int64, error) {
-	return c.get().EstimatedDocumentCount(ctx)
+	defer c.observe("Count")()
+
+	return c.get().CountDocuments(ctx, live(bson.M{}))
 }
 
 // Get all documents.
 func (c collection) all(ctx context.Context, slice interface{}) error {
-	cursor, e := c.get().Find(ctx, bson.D{})
+	defer c.observe("all")()
+
+	cursor, e := c.get().Find(ctx, live(bson.M{}))
+
+	if e != nil {
+		return e
+	}
+
+	return cursor.All(ctx, slice)
+}
+
+// Get all documents matching filter.
+func (c collection) find(ctx context.Context, filter bson.M, slice interface{}, opts ...*options.FindOptions) error {
+	defer c.observe("find")()
+
+	cursor, e := c.get().Find(ctx, live(filter), opts...)
 
 	if e != nil {
 		return e
@@ -45,8 +80,89 @@ func (c collection) all(ctx context.Context, slice interface{}) error {
 	return cursor.All(ctx, slice)
 }
 
+// Get the documents matching filter in the order of sort, skipping the first
+// offset and returning up to limit (every document if zero). Returns the number
+// of documents matching filter.
+func (c collection) page(ctx context.Context, filter bson.M, slice interface{}, sort bson.D, offset, limit int64) (int64, error) {
+	defer c.observe("page")()
+
+	filter = live(filter)
+	coll := c.get()
+	total, e := coll.CountDocuments(ctx, filter)
+
+	if e != nil {
+		return 0, e
+	}
+
+	opts := options.Find()
+	opts.SetSort(sort)
+	opts.SetSkip(offset)
+
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, e := coll.Find(ctx, filter, opts)
+
+	if e != nil {
+		return 0, e
+	}
+
+	return total, cursor.All(ctx, slice)
+}
+
+// Create indexes. Indexes which already exist are left as they are.
+func (c collection) createIndexes(ctx context.Context, models ...mongo.IndexModel) error {
+	defer c.observe("createIndexes")()
+
+	_, e := c.get().Indexes().CreateMany(ctx, models)
+
+	return e
+}
+
+// Drop the indexes matching names. Indexes which don't exist are ignored.
+func (c collection) dropIndexes(ctx context.Context, names ...string) error {
+	defer c.observe("dropIndexes")()
+
+	for _, name := range names {
+		_, e := c.get().Indexes().DropOne(ctx, name)
+
+		if ce, ok := e.(mongo.CommandError); ok && (ce.Code == indexNotFound || ce.Code == namespaceNotFound) {
+			continue
+		}
+
+		if e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// Get a filter matching times between after and before (inclusive). Either
+// end is unbounded if zero. Returns nil if both are zero.
+func between(after, before time.Time) bson.M {
+	var filter bson.M
+
+	if !after.IsZero() {
+		filter = bson.M{"$gte": after}
+	}
+
+	if !before.IsZero() {
+		if filter == nil {
+			filter = bson.M{}
+		}
+
+		filter["$lte"] = before
+	}
+
+	return filter
+}
+
 // Get a document matching the hexadecimal-encoded ID.
 func (c collection) findID(ctx context.Context, hex string, ptr are_hub.Archetype) error {
+	defer c.observe("findID")()
+
 	id, e := primitive.ObjectIDFromHex(hex)
 
 	if e != nil {
@@ -54,7 +170,7 @@ func (c collection) findID(ctx context.Context, hex string, ptr are_hub.Archetyp
 	}
 
 	coll := c.get()
-	result := coll.FindOne(ctx, bson.M{"_id": id})
+	result := coll.FindOne(ctx, live(bson.M{"_id": id}))
 
 	if e := result.Err(); e != nil {
 		if e == mongo.ErrNoDocuments {
@@ -64,17 +180,19 @@ func (c collection) findID(ctx context.Context, hex string, ptr are_hub.Archetyp
 		return e
 	}
 
-	return c.get().FindOne(ctx, bson.M{"_id": id}).Decode(ptr)
+	return result.Decode(ptr)
 }
 
 // Insert a document.
 func (c collection) Insert(ctx context.Context, ptr are_hub.Archetype) error {
+	defer c.observe("Insert")()
+
 	ptr.Created()
 
 	result, e := c.get().InsertOne(ctx, ptr)
 
 	if e != nil {
-		return e
+		return c.duplicate(e)
 	}
 
 	id, ok := result.InsertedID.(primitive.ObjectID)
@@ -94,6 +212,8 @@ func (c collection) Insert(ctx context.Context, ptr are_hub.Archetype) error {
 
 // Update a document matching the hexadecimal-encoded ID.
 func (c collection) UpdateID(ctx context.Context, hex string, ptr are_hub.Archetype) error {
+	defer c.observe("UpdateID")()
+
 	id, e := primitive.ObjectIDFromHex(hex)
 
 	if e != nil {
@@ -108,11 +228,97 @@ func (c collection) UpdateID(ctx context.Context, hex string, ptr are_hub.Archet
 	opts := options.FindOneAndUpdate()
 	opts.SetReturnDocument(options.After)
 
-	return c.get().FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": ptr}, opts).Decode(ptr)
+	e = c.get().FindOneAndUpdate(ctx, live(bson.M{"_id": id}), bson.M{"$set": ptr}, opts).Decode(ptr)
+
+	return c.duplicate(e)
+}
+
+// Update a document matching the hexadecimal-encoded ID if its version is
+// still version, incrementing the version. Documents without a version match
+// version zero.
+func (c collection) updateIDVersion(ctx context.Context, hex string, version int64, ptr are_hub.Versioned) error {
+	defer c.observe("updateIDVersion")()
+
+	ptr.Updated()
+	ptr.UnsetID()
+	ptr.SetVersion(version + 1)
+
+	return c.setIDVersion(ctx, hex, version, ptr, ptr)
+}
+
+// Set only the given fields of a document matching the hexadecimal-encoded ID
+// if its version is still version, incrementing the version and setting the
+// updated timestamp. The updated document is decoded into ptr.
+func (c collection) patchIDVersion(ctx context.Context, hex string, version int64, fields bson.M, ptr interface{}) error {
+	defer c.observe("patchIDVersion")()
+
+	fields["updatedat"] = time.Now().UTC()
+	fields["version"] = version + 1
+
+	return c.setIDVersion(ctx, hex, version, fields, ptr)
+}
+
+// $set update to a document matching the hexadecimal-encoded ID if its version
+// is still version. The updated document is decoded into ptr.
+func (c collection) setIDVersion(ctx context.Context, hex string, version int64, update interface{}, ptr interface{}) error {
+	id, e := primitive.ObjectIDFromHex(hex)
+
+	if e != nil {
+		return e
+	}
+
+	filter := live(bson.M{"_id": id, "version": version})
+
+	if version == 0 {
+		filter["version"] = bson.M{"$exists": false}
+	}
+
+	// ensure the new document is returned rather than the document before updating
+	opts := options.FindOneAndUpdate()
+	opts.SetReturnDocument(options.After)
+
+	coll := c.get()
+	e = coll.FindOneAndUpdate(ctx, filter, bson.M{"$set": update}, opts).Decode(ptr)
+
+	if e != mongo.ErrNoDocuments {
+		return c.duplicate(e)
+	}
+
+	// either the document doesn't exist or its version changed
+	n, e := coll.CountDocuments(ctx, live(bson.M{"_id": id}))
+
+	if e != nil {
+		return e
+	}
+
+	if n == 0 {
+		return are_hub.NewNoObjectsFound("id: "+hex, coll.Name())
+	}
+
+	return are_hub.NewVersionConflict(coll.Name(), hex, version)
+}
+
+// Convert duplicate key errors into are_hub.Duplicate errors. Other errors are
+// returned as they are.
+func (c collection) duplicate(e error) error {
+	if e == nil || !mongo.IsDuplicateKeyError(e) {
+		return e
+	}
+
+	field := "key"
+
+	// eg. "E11000 duplicate key error collection: db.channels index: name_1 dup key: { name: "..." }"
+	if m := dupKey.FindStringSubmatch(e.Error()); m != nil {
+		field = m[1]
+	}
+
+	return are_hub.NewDuplicate(c.name, field)
 }
 
 // Delete a document matching the hexadecimal-encoded ID.
 func (c collection) deleteID(ctx context.Context, hex string, ptr are_hub.Archetype) error {
+	defer c.observe("deleteID")()
+
 	id, e := primitive.ObjectIDFromHex(hex)
 
 	if e != nil {
@@ -121,3 +327,80 @@ func (c collection) deleteID(ctx context.Context, hex string, ptr are_hub.Archet
 
 	return c.get().FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(ptr)
 }
+
+// Move a document matching the hexadecimal-encoded ID to the trash. The
+// deleted document is decoded into ptr.
+func (c collection) trashID(ctx context.Context, hex string, ptr are_hub.Archetype) error {
+	defer c.observe("trashID")()
+
+	return c.setDeleted(ctx, hex, true, ptr)
+}
+
+// Restore a document matching the hexadecimal-encoded ID from the trash. The
+// restored document is decoded into ptr.
+func (c collection) restoreID(ctx context.Context, hex string, ptr are_hub.Archetype) error {
+	defer c.observe("restoreID")()
+
+	return c.setDeleted(ctx, hex, false, ptr)
+}
+
+// Set or unset when a document matching the hexadecimal-encoded ID was moved to
+// the trash.
+func (c collection) setDeleted(ctx context.Context, hex string, deleted bool, ptr are_hub.Archetype) error {
+	id, e := primitive.ObjectIDFromHex(hex)
+
+	if e != nil {
+		return e
+	}
+
+	filter := live(bson.M{"_id": id})
+	update := bson.M{"$set": bson.M{"deletedat": time.Now().UTC()}}
+
+	if !deleted {
+		filter = trashed(bson.M{"_id": id})
+		update = bson.M{"$unset": bson.M{"deletedat": ""}}
+	}
+
+	opts := options.FindOneAndUpdate()
+	opts.SetReturnDocument(options.After)
+
+	coll := c.get()
+	e = coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(ptr)
+
+	if e == mongo.ErrNoDocuments {
+		return are_hub.NewNoObjectsFound("id: "+hex, coll.Name())
+	}
+
+	return e
+}
+
+// Get the documents in the trash, most recently deleted first.
+func (c collection) trash(ctx context.Context, slice interface{}) error {
+	defer c.observe("trash")()
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "deletedat", Value: -1}})
+
+	cursor, e := c.get().Find(ctx, trashed(bson.M{}), opts)
+
+	if e != nil {
+		return e
+	}
+
+	return cursor.All(ctx, slice)
+}
+
+// Add to filter to exclude documents in the trash.
+func live(filter bson.M) bson.M {
+	// matches missing fields as well
+	filter["deletedat"] = nil
+
+	return filter
+}
+
+// Add to filter to only match documents in the trash.
+func trashed(filter bson.M) bson.M {
+	filter["deletedat"] = bson.M{"$ne": nil}
+
+	return filter
+}
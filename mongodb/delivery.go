@@ -0,0 +1,30 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Implements are_hub.DeliveryRepo.
+type Delivery struct {
+	collection
+}
+
+// Create a webhook deliveries collection in db.
+func NewDeliveryCollection(client *mongo.Client, db string) Delivery {
+	return Delivery{collection{client, db, "deliveries"}}
+}
+
+func (d Delivery) FindWebhookID(ctx context.Context, id string) ([]are_hub.Delivery, error) {
+	var deliveries []are_hub.Delivery
+
+	// newest first
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "createdat", Value: -1}})
+
+	return deliveries, d.find(ctx, bson.M{"webhookid": id}, &deliveries, opts)
+}
@@ -0,0 +1,88 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Indexes by collection name, supporting the queries made by the collections.
+var indexes = map[string][]mongo.IndexModel{
+	"channels": {
+		// names are only reserved by channels outside of the trash (see live)
+		{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetName("name_live").SetUnique(true).SetPartialFilterExpression(bson.M{"deletedat": nil})},
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "createdat", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "updatedat", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "visibility", Value: 1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}, {Key: "_id", Value: 1}}},
+	},
+	"messages": {
+		{Keys: bson.D{{Key: "channelid", Value: 1}, {Key: "seq", Value: 1}, {Key: "createdat", Value: 1}}},
+	},
+	"laps": {
+		{Keys: bson.D{{Key: "channelid", Value: 1}, {Key: "createdat", Value: 1}}},
+		{Keys: bson.D{{Key: "channelid", Value: 1}, {Key: "session", Value: 1}, {Key: "createdat", Value: 1}}},
+	},
+	"alertRules": {
+		{Keys: bson.D{{Key: "channelid", Value: 1}}},
+	},
+	"alerts": {
+		{Keys: bson.D{{Key: "channelid", Value: 1}, {Key: "createdat", Value: -1}}},
+	},
+	"webhooks": {
+		{Keys: bson.D{{Key: "channelid", Value: 1}}},
+	},
+	"deliveries": {
+		{Keys: bson.D{{Key: "webhookid", Value: 1}, {Key: "createdat", Value: -1}}},
+	},
+	"invites": {
+		{Keys: bson.D{{Key: "tokenhash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "channelid", Value: 1}}},
+	},
+	"sessions": {
+		{Keys: bson.D{{Key: "tokenhash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "inviteid", Value: 1}}},
+		{Keys: bson.D{{Key: "channelid", Value: 1}}},
+
+		// expired sessions are deleted by the server
+		{Keys: bson.D{{Key: "expiresat", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"auditEvents": {
+		{Keys: bson.D{{Key: "createdat", Value: -1}}},
+		{Keys: bson.D{{Key: "targetid", Value: 1}, {Key: "createdat", Value: -1}}},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "createdat", Value: -1}}},
+	},
+}
+
+// Indexes replaced by those above by collection name.
+var obsolete = map[string][]string{
+	// reserved the names of channels in the trash
+	"channels": {"name_1"},
+}
+
+// Create the indexes of every collection in db, dropping obsolete indexes
+// first. Indexes which already exist are left as they are. Creating a unique
+// index fails if existing documents have duplicate values (eg. channels with
+// the same name).
+func CreateIndexes(ctx context.Context, client *mongo.Client, db string) error {
+	for name, models := range indexes {
+		c := collection{client, db, name}
+		e := c.dropIndexes(ctx, obsolete[name]...)
+
+		if e != nil {
+			return fmt.Errorf("%s: %w", name, e)
+		}
+
+		e = c.createIndexes(ctx, models...)
+
+		if e != nil {
+			return fmt.Errorf("%s: %w", name, e)
+		}
+	}
+
+	return nil
+}
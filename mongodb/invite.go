@@ -0,0 +1,80 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Implements are_hub.InviteRepo.
+type Invite struct {
+	collection
+}
+
+// Create an invitations collection in db.
+func NewInviteCollection(client *mongo.Client, db string) Invite {
+	return Invite{collection{client, db, "invites"}}
+}
+
+// Filter of invitations that can still be redeemed.
+func redeemable() bson.M {
+	return bson.M{
+		"expiresat": bson.M{"$gt": time.Now()},
+		"$expr":     bson.M{"$lt": bson.A{"$uses", "$maxuses"}},
+	}
+}
+
+func (i Invite) FindChannelID(ctx context.Context, id string) ([]are_hub.Invite, error) {
+	var invites []are_hub.Invite
+
+	filter := redeemable()
+	filter["channelid"] = id
+
+	return invites, i.find(ctx, filter, &invites)
+}
+
+func (i Invite) FindID(ctx context.Context, id string) (*are_hub.Invite, error) {
+	invite := &are_hub.Invite{}
+
+	return invite, i.findID(ctx, id, invite)
+}
+
+func (i Invite) DeleteID(ctx context.Context, id string) (*are_hub.Invite, error) {
+	invite := &are_hub.Invite{}
+
+	return invite, i.deleteID(ctx, id, invite)
+}
+
+func (i Invite) Redeem(ctx context.Context, hash string) (*are_hub.Invite, error) {
+	defer i.observe("Redeem")()
+
+	filter := redeemable()
+	filter["tokenhash"] = hash
+
+	// counted atomically so that invitations can't be redeemed more than
+	// maxUses times by concurrent requests
+	update := bson.M{
+		"$inc": bson.M{"uses": 1},
+		"$set": bson.M{"updatedat": time.Now()},
+	}
+
+	opts := options.FindOneAndUpdate()
+	opts.SetReturnDocument(options.After)
+
+	invite := &are_hub.Invite{}
+	e := i.get().FindOneAndUpdate(ctx, filter, update, opts).Decode(invite)
+
+	if e == mongo.ErrNoDocuments {
+		return nil, are_hub.NewNoObjectsFound(i.name, "redeemable token")
+	}
+
+	if e != nil {
+		return nil, e
+	}
+
+	return invite, nil
+}
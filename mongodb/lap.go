@@ -0,0 +1,42 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Implements are_hub.LapRepo.
+type Lap struct {
+	collection
+}
+
+// Create a laps collection in db.
+func NewLapCollection(client *mongo.Client, db string) Lap {
+	return Lap{collection{client, db, "laps"}}
+}
+
+func (l Lap) FindChannelID(ctx context.Context, id, session string) ([]are_hub.Lap, error) {
+	var laps []are_hub.Lap
+
+	filter := bson.M{"channelid": id}
+
+	if len(session) > 0 {
+		filter["session"] = session
+	}
+
+	// in order of completion
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "createdat", Value: 1}})
+
+	return laps, l.find(ctx, filter, &laps, opts)
+}
+
+func (l Lap) FindID(ctx context.Context, id string) (*are_hub.Lap, error) {
+	lap := &are_hub.Lap{}
+
+	return lap, l.findID(ctx, id, lap)
+}
@@ -0,0 +1,30 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Implements are_hub.MessageRepo.
+type Message struct {
+	collection
+}
+
+// Create a messages collection in db.
+func NewMessageCollection(client *mongo.Client, db string) Message {
+	return Message{collection{client, db, "messages"}}
+}
+
+func (m Message) FindChannelID(ctx context.Context, id string) ([]are_hub.Message, error) {
+	var messages []are_hub.Message
+
+	// oldest first so that messages can be replayed alongside telemetry
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "seq", Value: 1}, {Key: "createdat", Value: 1}})
+
+	return messages, m.find(ctx, bson.M{"channelid": id}, &messages, opts)
+}
@@ -0,0 +1,54 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Implements are_hub.SessionRepo.
+type Session struct {
+	collection
+}
+
+// Create a sessions collection in db.
+func NewSessionCollection(client *mongo.Client, db string) Session {
+	return Session{collection{client, db, "sessions"}}
+}
+
+func (s Session) FindToken(ctx context.Context, hash string) (*are_hub.Session, error) {
+	defer s.observe("FindToken")()
+
+	session := &are_hub.Session{}
+	filter := bson.M{"tokenhash": hash, "expiresat": bson.M{"$gt": time.Now()}}
+	e := s.get().FindOne(ctx, filter).Decode(session)
+
+	if e == mongo.ErrNoDocuments {
+		return nil, are_hub.NewNoObjectsFound(s.name, "unexpired token")
+	}
+
+	if e != nil {
+		return nil, e
+	}
+
+	return session, nil
+}
+
+func (s Session) DeleteInviteID(ctx context.Context, id string) error {
+	defer s.observe("DeleteInviteID")()
+
+	_, e := s.get().DeleteMany(ctx, bson.M{"inviteid": id})
+
+	return e
+}
+
+func (s Session) DeleteChannelID(ctx context.Context, id string) error {
+	defer s.observe("DeleteChannelID")()
+
+	_, e := s.get().DeleteMany(ctx, bson.M{"channelid": id})
+
+	return e
+}
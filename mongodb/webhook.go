@@ -0,0 +1,40 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/blacksfk/are_hub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Implements are_hub.WebhookRepo.
+type Webhook struct {
+	collection
+}
+
+// Create a webhooks collection in db.
+func NewWebhookCollection(client *mongo.Client, db string) Webhook {
+	return Webhook{collection{client, db, "webhooks"}}
+}
+
+func (w Webhook) FindChannelID(ctx context.Context, id string) ([]are_hub.Webhook, error) {
+	var webhooks []are_hub.Webhook
+
+	// webhooks without a channel receive the events of every channel
+	filter := bson.M{"channelid": bson.M{"$in": bson.A{id, ""}}}
+
+	return webhooks, w.find(ctx, filter, &webhooks)
+}
+
+func (w Webhook) FindID(ctx context.Context, id string) (*are_hub.Webhook, error) {
+	webhook := &are_hub.Webhook{}
+
+	return webhook, w.findID(ctx, id, webhook)
+}
+
+func (w Webhook) DeleteID(ctx context.Context, id string) (*are_hub.Webhook, error) {
+	webhook := &are_hub.Webhook{}
+
+	return webhook, w.deleteID(ctx, id, webhook)
+}
@@ -0,0 +1,220 @@
+// gRPC interface to the telemetry hub. Frames and messages are exchanged as
+// JSON (the same encoding used by the websocket and HTTP interfaces) so that
+// gRPC, websocket, and HTTP clients interoperate on the same channel.
+//
+// Clients authenticate by sending the channel's ID and password in the
+// "channel-id" and "channel-password" metadata keys.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: telemetry.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Telemetry_Publish_FullMethodName   = "/are_hub.telemetry.Telemetry/Publish"
+	Telemetry_Subscribe_FullMethodName = "/are_hub.telemetry.Telemetry/Subscribe"
+)
+
+// TelemetryClient is the client API for Telemetry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TelemetryClient interface {
+	// Publish a stream of telemetry frames to a channel. The summary is returned
+	// once the client closes the stream.
+	Publish(ctx context.Context, opts ...grpc.CallOption) (Telemetry_PublishClient, error)
+	// Subscribe to the frames and messages broadcast on a channel.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Telemetry_SubscribeClient, error)
+}
+
+type telemetryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTelemetryClient(cc grpc.ClientConnInterface) TelemetryClient {
+	return &telemetryClient{cc}
+}
+
+func (c *telemetryClient) Publish(ctx context.Context, opts ...grpc.CallOption) (Telemetry_PublishClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Telemetry_ServiceDesc.Streams[0], Telemetry_Publish_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &telemetryPublishClient{stream}
+	return x, nil
+}
+
+type Telemetry_PublishClient interface {
+	Send(*Frame) error
+	CloseAndRecv() (*PublishSummary, error)
+	grpc.ClientStream
+}
+
+type telemetryPublishClient struct {
+	grpc.ClientStream
+}
+
+func (x *telemetryPublishClient) Send(m *Frame) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *telemetryPublishClient) CloseAndRecv() (*PublishSummary, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PublishSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *telemetryClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Telemetry_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Telemetry_ServiceDesc.Streams[1], Telemetry_Subscribe_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &telemetrySubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Telemetry_SubscribeClient interface {
+	Recv() (*Message, error)
+	grpc.ClientStream
+}
+
+type telemetrySubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *telemetrySubscribeClient) Recv() (*Message, error) {
+	m := new(Message)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TelemetryServer is the server API for Telemetry service.
+// All implementations must embed UnimplementedTelemetryServer
+// for forward compatibility
+type TelemetryServer interface {
+	// Publish a stream of telemetry frames to a channel. The summary is returned
+	// once the client closes the stream.
+	Publish(Telemetry_PublishServer) error
+	// Subscribe to the frames and messages broadcast on a channel.
+	Subscribe(*SubscribeRequest, Telemetry_SubscribeServer) error
+	mustEmbedUnimplementedTelemetryServer()
+}
+
+// UnimplementedTelemetryServer must be embedded to have forward compatible implementations.
+type UnimplementedTelemetryServer struct {
+}
+
+func (UnimplementedTelemetryServer) Publish(Telemetry_PublishServer) error {
+	return status.Errorf(codes.Unimplemented, "method Publish not implemented")
+}
+func (UnimplementedTelemetryServer) Subscribe(*SubscribeRequest, Telemetry_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedTelemetryServer) mustEmbedUnimplementedTelemetryServer() {}
+
+// UnsafeTelemetryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TelemetryServer will
+// result in compilation errors.
+type UnsafeTelemetryServer interface {
+	mustEmbedUnimplementedTelemetryServer()
+}
+
+func RegisterTelemetryServer(s grpc.ServiceRegistrar, srv TelemetryServer) {
+	s.RegisterService(&Telemetry_ServiceDesc, srv)
+}
+
+func _Telemetry_Publish_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TelemetryServer).Publish(&telemetryPublishServer{stream})
+}
+
+type Telemetry_PublishServer interface {
+	SendAndClose(*PublishSummary) error
+	Recv() (*Frame, error)
+	grpc.ServerStream
+}
+
+type telemetryPublishServer struct {
+	grpc.ServerStream
+}
+
+func (x *telemetryPublishServer) SendAndClose(m *PublishSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *telemetryPublishServer) Recv() (*Frame, error) {
+	m := new(Frame)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Telemetry_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TelemetryServer).Subscribe(m, &telemetrySubscribeServer{stream})
+}
+
+type Telemetry_SubscribeServer interface {
+	Send(*Message) error
+	grpc.ServerStream
+}
+
+type telemetrySubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *telemetrySubscribeServer) Send(m *Message) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Telemetry_ServiceDesc is the grpc.ServiceDesc for Telemetry service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Telemetry_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "are_hub.telemetry.Telemetry",
+	HandlerType: (*TelemetryServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Publish",
+			Handler:       _Telemetry_Publish_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Telemetry_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "telemetry.proto",
+}
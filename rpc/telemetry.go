@@ -0,0 +1,208 @@
+// Package rpc implements the gRPC interface to the telemetry hub described in
+// telemetry.proto.
+package rpc
+
+//go:generate protoc --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative telemetry.proto
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	hub "github.com/blacksfk/are_hub/http"
+	"github.com/blacksfk/are_hub/rpc/pb"
+	uf "github.com/blacksfk/microframework"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Metadata key containing the channel's ID.
+	MD_CHANNEL_ID = "channel-id"
+
+	// Metadata key containing the channel's password.
+	MD_CHANNEL_PASSWORD = "channel-password"
+
+	// Metadata key containing the car published or followed on team channels.
+	MD_CAR = "car"
+
+	// Metadata key containing the driver publishing a car.
+	MD_DRIVER = "driver"
+
+	// Metadata key containing the slow subscriber policy of a subscriber.
+	MD_POLICY = "policy"
+)
+
+// Implements pb.TelemetryServer. Backed by a TelemetryServer so that gRPC,
+// websocket, and HTTP clients share the same telemetry channels.
+type Telemetry struct {
+	pb.UnimplementedTelemetryServer
+	ts *hub.TelemetryServer
+}
+
+// Create a new gRPC telemetry service.
+func NewTelemetry(ts *hub.TelemetryServer) *Telemetry {
+	return &Telemetry{ts: ts}
+}
+
+// Broadcast the frames received on the stream until the client closes it.
+func (t *Telemetry) Publish(stream pb.Telemetry_PublishServer) error {
+	ctx := stream.Context()
+	id, pw, e := credentials(ctx)
+
+	if e != nil {
+		return e
+	}
+
+	slot := hub.Slot{Car: mdValue(ctx, MD_CAR), Driver: mdValue(ctx, MD_DRIVER)}
+	pub, e := t.ts.Publisher(ctx, id, pw, slot)
+
+	if e != nil {
+		return toStatus(e)
+	}
+
+	summary := &pb.PublishSummary{}
+
+	for {
+		frame, e := stream.Recv()
+
+		if e == io.EOF {
+			// client has finished publishing
+			return stream.SendAndClose(summary)
+		}
+
+		if e != nil {
+			return e
+		}
+
+		e = pub.Broadcast(ctx, frame.Json)
+
+		if e != nil {
+			return toStatus(e)
+		}
+
+		summary.Frames++
+	}
+}
+
+// Stream the messages broadcast on a channel until the client disconnects or
+// is dropped for being too slow.
+func (t *Telemetry) Subscribe(_ *pb.SubscribeRequest, stream pb.Telemetry_SubscribeServer) error {
+	ctx := stream.Context()
+	id, pw, e := credentials(ctx)
+
+	if e != nil {
+		return e
+	}
+
+	sub, e := t.ts.Subscription(ctx, id, pw, hub.SubscribeOptions{
+		Car:        mdValue(ctx, MD_CAR),
+		Policy:     mdValue(ctx, MD_POLICY),
+		RemoteAddr: remoteAddr(ctx),
+	})
+
+	if e != nil {
+		return toStatus(e)
+	}
+
+	defer sub.Close()
+
+	// send headers so the client knows it has been subscribed before any
+	// messages are broadcast
+	e = stream.SendHeader(metadata.MD{})
+
+	if e != nil {
+		return e
+	}
+
+	for {
+		ev, e := sub.Next(ctx)
+
+		if e == hub.ErrDropped {
+			return status.Error(codes.ResourceExhausted, e.Error())
+		}
+
+		if e == hub.ErrDisconnected {
+			return status.Error(codes.Aborted, e.Error())
+		}
+
+		if e != nil {
+			// client has gone away
+			return status.FromContextError(e).Err()
+		}
+
+		e = stream.Send(&pb.Message{Status: int32(ev.Status), Seq: ev.Seq, Data: ev.Data})
+
+		if e != nil {
+			return e
+		}
+	}
+}
+
+// Get the channel ID and password from the incoming metadata.
+func credentials(ctx context.Context) (string, string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := md.Get(MD_CHANNEL_ID)
+
+	if len(id) == 0 || len(id[0]) == 0 {
+		return "", "", status.Error(codes.InvalidArgument, "Channel ID required.")
+	}
+
+	pw := md.Get(MD_CHANNEL_PASSWORD)
+
+	if len(pw) == 0 || len(pw[0]) == 0 {
+		return "", "", status.Error(codes.Unauthenticated, "Channel password required.")
+	}
+
+	return id[0], pw[0], nil
+}
+
+// Get the first value of key in the incoming metadata or the empty string.
+func mdValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
+
+// Get the address of the client from ctx or the empty string.
+func remoteAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+
+	return ""
+}
+
+// gRPC status codes by HTTP status code.
+var codesHTTP = map[int]codes.Code{
+	http.StatusBadRequest:         codes.InvalidArgument,
+	http.StatusUnauthorized:       codes.Unauthenticated,
+	http.StatusForbidden:          codes.PermissionDenied,
+	http.StatusNotFound:           codes.NotFound,
+	http.StatusConflict:           codes.FailedPrecondition,
+	http.StatusGone:               codes.Aborted,
+	http.StatusServiceUnavailable: codes.Unavailable,
+}
+
+// Translate errors returned from the TelemetryServer into gRPC status errors.
+func toStatus(e error) error {
+	he, ok := e.(uf.HttpError)
+
+	if !ok {
+		return status.Error(codes.Internal, e.Error())
+	}
+
+	code, ok := codesHTTP[he.Code]
+
+	if !ok {
+		code = codes.Internal
+	}
+
+	return status.Error(code, he.Message)
+}
@@ -0,0 +1,214 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/hash"
+	hub "github.com/blacksfk/are_hub/http"
+	"github.com/blacksfk/are_hub/mock"
+	"github.com/blacksfk/are_hub/rpc/pb"
+	uf "github.com/blacksfk/microframework"
+	"github.com/julienschmidt/httprouter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	// Telemetry channel test data.
+	testChannelID       = "60f6b5f3c2a1e4d9b8a7c6d5"
+	testChannelPassword = "abc123"
+)
+
+// Create a telemetry server with a single channel, serve it over an in-memory
+// connection, and return a connected client.
+func newTestClient(t *testing.T) (pb.TelemetryClient, *hub.TelemetryServer) {
+	pw, e := hash.Password(testChannelPassword)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	channels := &mock.ChannelRepo{
+		FindIDFunc: func(_ context.Context, id string) (*are_hub.Channel, error) {
+			if id != testChannelID {
+				return nil, are_hub.NewNoObjectsFound("channels", "id == "+id)
+			}
+
+			c := are_hub.NewChannel("Audi Sport Team WRT", pw)
+			c.ID = id
+
+			return c, nil
+		},
+	}
+
+	rules := &mock.AlertRuleRepo{
+		FindChannelIDFunc: func(context.Context, string) ([]are_hub.AlertRule, error) {
+			return nil, nil
+		},
+	}
+
+	ts := hub.NewTelemetryServer(nil, hub.TelemetryRepos{
+		Channels: channels,
+		Messages: &mock.MessageRepo{},
+		Laps:     &mock.LapRepo{},
+		Rules:    rules,
+		Alerts:   &mock.AlertRepo{},
+	}, &mock.Emitter{EmitFunc: func(are_hub.Event) {}})
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+
+	pb.RegisterTelemetryServer(s, NewTelemetry(ts))
+
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+
+	conn, e := grpc.Dial("bufnet", grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewTelemetryClient(conn), ts
+}
+
+// Create a context containing the channel credentials.
+func authCtx(t *testing.T, id, pw string) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	t.Cleanup(cancel)
+
+	return metadata.AppendToOutgoingContext(ctx, MD_CHANNEL_ID, id, MD_CHANNEL_PASSWORD, pw)
+}
+
+// Subscribe and wait until the subscriber has been added to the channel.
+func subscribe(t *testing.T, client pb.TelemetryClient) pb.Telemetry_SubscribeClient {
+	stream, e := client.Subscribe(authCtx(t, testChannelID, testChannelPassword), &pb.SubscribeRequest{})
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// headers are sent once the subscriber has been added to the channel
+	_, e = stream.Header()
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return stream
+}
+
+// Are frames published over gRPC received by gRPC subscribers?
+func TestPublishSubscribe(t *testing.T) {
+	client, _ := newTestClient(t)
+	sub := subscribe(t, client)
+
+	pub, e := client.Publish(authCtx(t, testChannelID, testChannelPassword))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	frames := []string{`{"lap":1}`, `{"lap":2}`}
+
+	for _, f := range frames {
+		e = pub.Send(&pb.Frame{Json: []byte(f)})
+
+		if e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	summary, e := pub.CloseAndRecv()
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if summary.Frames != uint64(len(frames)) {
+		t.Fatalf("Expected: %d frames. Actual: %d.", len(frames), summary.Frames)
+	}
+
+	for i, f := range frames {
+		msg, e := sub.Recv()
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if msg.Status != hub.WS_OK || msg.Seq != uint64(i+1) || string(msg.Data) != f {
+			t.Fatalf("Expected: status %d, seq %d, %s. Actual: %+v.", hub.WS_OK, i+1, f, msg)
+		}
+	}
+}
+
+// Do gRPC subscribers receive frames published over HTTP?
+func TestSubscribeHTTPInterop(t *testing.T) {
+	client, ts := newTestClient(t)
+	sub := subscribe(t, client)
+
+	frame := `{"speedKmh":212.5}`
+	req := httptest.NewRequest(http.MethodPost, "/publish/"+testChannelID, bytes.NewReader([]byte(frame)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Channel-Password", testChannelPassword)
+	uf.EmbedParams(req, httprouter.Param{Key: "id", Value: testChannelID})
+
+	e := ts.Publish(httptest.NewRecorder(), req)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	msg, e := sub.Recv()
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if string(msg.Data) != frame {
+		t.Fatalf("Expected: %s. Actual: %s.", frame, msg.Data)
+	}
+}
+
+// Are clients without valid credentials rejected with the appropriate codes?
+func TestCredentials(t *testing.T) {
+	client, _ := newTestClient(t)
+	tests := []struct {
+		id, pw string
+		code   codes.Code
+	}{
+		{"", testChannelPassword, codes.InvalidArgument},
+		{testChannelID, "", codes.Unauthenticated},
+		{testChannelID, "lol123", codes.Unauthenticated},
+		{"60f6b5f3c2a1e4d9b8a7c6d6", testChannelPassword, codes.NotFound},
+	}
+
+	for _, test := range tests {
+		stream, e := client.Subscribe(authCtx(t, test.id, test.pw), &pb.SubscribeRequest{})
+
+		if e == nil {
+			_, e = stream.Recv()
+		}
+
+		if code := status.Code(e); code != test.code {
+			t.Fatalf("Expected: %s. Actual: %s (%v).", test.code, code, e)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Package strategy continuously estimates fuel and tyre strategy from the laps
+// completed and frames broadcast on a channel.
+package strategy
+
+import (
+	"math"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/frame"
+)
+
+const (
+	// How many of the most recent laps rolling averages are calculated over.
+	WINDOW = 5
+)
+
+// Paths (dot separated, eg. "physics.fuel") of the fields in telemetry frames
+// used to estimate strategy. Estimates depending on a field are left as zero
+// values if the field is empty or missing from frames.
+type Fields struct {
+	// Fuel remaining.
+	Fuel string
+
+	// Fuel tank capacity.
+	MaxFuel string
+
+	// Time remaining in the session in milliseconds.
+	SessionTimeLeft string
+
+	// Tyre wear per corner (front left, front right, rear left, rear right).
+	TyreWear string
+}
+
+// The range of laps in which the next pit stop should be made.
+type PitWindow struct {
+	// Earliest lap to pit on while still only needing Stops stops.
+	Open int64 `json:"open"`
+
+	// Last lap to pit on before running out of fuel.
+	Close int64 `json:"close"`
+
+	// Number of stops required to finish.
+	Stops int64 `json:"stops"`
+}
+
+// Strategy estimated from the most recent laps and frames.
+type Estimate struct {
+	// Number of laps the estimate is based on (up to WINDOW).
+	Laps int `json:"laps"`
+
+	// Laps completed in the session.
+	CompletedLaps int64 `json:"completedLaps"`
+
+	// Rolling average lap time in milliseconds.
+	LapTime int64 `json:"lapTime"`
+
+	// Rolling average fuel used per lap.
+	FuelPerLap float64 `json:"fuelPerLap"`
+
+	Fuel float64 `json:"fuel"`
+
+	// Laps that can be completed on the fuel remaining.
+	LapsRemaining float64 `json:"lapsRemaining"`
+
+	// Time remaining in the session in milliseconds.
+	SessionTimeLeft int64 `json:"sessionTimeLeft"`
+
+	// Laps to complete before the session ends.
+	LapsToFinish int64 `json:"lapsToFinish"`
+
+	// Total fuel required to finish.
+	FuelToFinish float64 `json:"fuelToFinish"`
+
+	// Fuel required to finish in addition to the fuel remaining.
+	FuelToAdd float64 `json:"fuelToAdd"`
+
+	// Rolling average tyre wear per lap for each corner.
+	TyreWear []float64 `json:"tyreWear"`
+
+	// Nil if no pit stop is required to finish.
+	PitWindow *PitWindow `json:"pitWindow,omitempty"`
+
+	// Car the estimate is for on team channels. Not set by the Calculator.
+	Car string `json:"car,omitempty"`
+}
+
+// Estimates strategy for a channel. A Calculator is not safe for concurrent use.
+type Calculator struct {
+	fields Fields
+
+	// the most recent laps and the tyre wear when each was completed (oldest first)
+	laps []are_hub.Lap
+	wear [][]float64
+
+	// latest values from frames
+	fuel            float64
+	maxFuel         float64
+	sessionTimeLeft float64
+	tyreWear        []float64
+}
+
+// Create a new calculator.
+func NewCalculator(f Fields) *Calculator {
+	return &Calculator{fields: f}
+}
+
+// Update the calculator with the latest values in f.
+func (c *Calculator) Frame(f frame.Frame) {
+	if fuel, ok := f.Number(c.fields.Fuel); ok {
+		c.fuel = fuel
+	}
+
+	if max, ok := f.Number(c.fields.MaxFuel); ok {
+		c.maxFuel = max
+	}
+
+	if left, ok := f.Number(c.fields.SessionTimeLeft); ok {
+		c.sessionTimeLeft = left
+	}
+
+	if wear, ok := f.Numbers(c.fields.TyreWear); ok {
+		c.tyreWear = wear
+	}
+}
+
+// Update the calculator with a completed lap. Laps from a new session reset
+// the rolling averages.
+func (c *Calculator) Lap(lap *are_hub.Lap) {
+	if l := len(c.laps); l > 0 && c.laps[l-1].Session != lap.Session {
+		c.laps = nil
+		c.wear = nil
+	}
+
+	if len(c.laps) == WINDOW {
+		c.laps = c.laps[1:]
+		c.wear = c.wear[1:]
+	}
+
+	c.laps = append(c.laps, *lap)
+	c.wear = append(c.wear, c.tyreWear)
+}
+
+// Estimate strategy from the laps and frames received so far.
+func (c *Calculator) Estimate() Estimate {
+	est := Estimate{
+		Laps:            len(c.laps),
+		Fuel:            c.fuel,
+		SessionTimeLeft: int64(c.sessionTimeLeft),
+		TyreWear:        c.tyreWearTrend(),
+	}
+
+	if est.Laps == 0 {
+		return est
+	}
+
+	est.CompletedLaps = c.laps[est.Laps-1].Number
+
+	// rolling averages
+	var fuel float64
+	var times int64
+
+	for _, l := range c.laps {
+		fuel += l.FuelUsed
+		times += l.Time
+	}
+
+	est.FuelPerLap = fuel / float64(est.Laps)
+	est.LapTime = times / int64(est.Laps)
+
+	if est.FuelPerLap <= 0 {
+		return est
+	}
+
+	est.LapsRemaining = c.fuel / est.FuelPerLap
+
+	if est.LapTime <= 0 || c.sessionTimeLeft <= 0 {
+		return est
+	}
+
+	// the lap in progress when the session ends must also be completed
+	est.LapsToFinish = int64(math.Ceil(c.sessionTimeLeft / float64(est.LapTime)))
+	est.FuelToFinish = float64(est.LapsToFinish) * est.FuelPerLap
+	est.FuelToAdd = math.Max(0, est.FuelToFinish-c.fuel)
+	est.PitWindow = c.pitWindow(est)
+
+	return est
+}
+
+// Calculate the window for the next pit stop assuming the tank is filled.
+func (c *Calculator) pitWindow(est Estimate) *PitWindow {
+	if est.FuelToAdd == 0 || c.maxFuel <= 0 {
+		return nil
+	}
+
+	remaining := int64(math.Floor(est.LapsRemaining))
+	tank := int64(math.Floor(c.maxFuel / est.FuelPerLap))
+
+	if tank <= 0 {
+		return nil
+	}
+
+	// laps which cannot be completed on the fuel remaining
+	short := est.LapsToFinish - remaining
+	stops := (short + tank - 1) / tank
+
+	// pitting any earlier would require another stop
+	open := est.CompletedLaps + est.LapsToFinish - stops*tank
+
+	if open < est.CompletedLaps {
+		open = est.CompletedLaps
+	}
+
+	return &PitWindow{open, est.CompletedLaps + remaining, stops}
+}
+
+// Calculate the average wear per lap of each corner over the window.
+func (c *Calculator) tyreWearTrend() []float64 {
+	// find the oldest and newest recordings of wear
+	first, last := -1, -1
+
+	for i, w := range c.wear {
+		if len(w) == 0 {
+			continue
+		}
+
+		if first < 0 {
+			first = i
+		}
+
+		last = i
+	}
+
+	if first == last || len(c.wear[first]) != len(c.wear[last]) {
+		return nil
+	}
+
+	trend := make([]float64, len(c.wear[last]))
+
+	for i := range trend {
+		trend[i] = (c.wear[last][i] - c.wear[first][i]) / float64(last-first)
+	}
+
+	return trend
+}
+
+// Get an estimated value by its JSON name (eg. "lapsRemaining"). Returns false
+// if name is not a numeric estimate.
+func (est Estimate) Value(name string) (float64, bool) {
+	switch name {
+	case "laps":
+		return float64(est.Laps), true
+	case "completedLaps":
+		return float64(est.CompletedLaps), true
+	case "lapTime":
+		return float64(est.LapTime), true
+	case "fuelPerLap":
+		return est.FuelPerLap, true
+	case "fuel":
+		return est.Fuel, true
+	case "lapsRemaining":
+		return est.LapsRemaining, true
+	case "sessionTimeLeft":
+		return float64(est.SessionTimeLeft), true
+	case "lapsToFinish":
+		return float64(est.LapsToFinish), true
+	case "fuelToFinish":
+		return est.FuelToFinish, true
+	case "fuelToAdd":
+		return est.FuelToAdd, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,116 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/frame"
+)
+
+// Fields used by the test frames.
+var fields = Fields{
+	Fuel:            "fuel",
+	MaxFuel:         "maxFuel",
+	SessionTimeLeft: "timeLeft",
+	TyreWear:        "wear",
+}
+
+// Decode a JSON encoded frame.
+func decode(t *testing.T, str string) frame.Frame {
+	f, e := frame.Decode([]byte(str))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return f
+}
+
+// Are the estimates zero values before any laps are completed?
+func TestEstimateNoLaps(t *testing.T) {
+	c := NewCalculator(fields)
+	c.Frame(decode(t, `{"fuel":50,"timeLeft":3600000}`))
+
+	est := c.Estimate()
+
+	if est.Fuel != 50 || est.SessionTimeLeft != 3600000 {
+		t.Fatalf("Expected: latest frame values. Actual: %+v.", est)
+	}
+
+	if est.FuelPerLap != 0 || est.PitWindow != nil {
+		t.Fatalf("Expected: no estimates. Actual: %+v.", est)
+	}
+}
+
+// Are the rolling averages and fuel estimates correct?
+// Is the pit window calculated for a race that can't be finished on the fuel remaining?
+func TestEstimateRace(t *testing.T) {
+	c := NewCalculator(fields)
+	c.Frame(decode(t, `{"maxFuel":100,"wear":[0,0,0,0]}`))
+
+	// 3 laps: 100s each, 2.5, 3.0, 3.5 fuel used
+	for i, used := range []float64{2.5, 3, 3.5} {
+		c.Frame(decode(t, `{"wear":[0.5,0.5,0.25,0.25]}`))
+
+		if i == 2 {
+			c.Frame(decode(t, `{"wear":[1.5,1.5,0.75,0.75]}`))
+		}
+
+		c.Lap(&are_hub.Lap{Session: "a", Number: int64(i + 1), Time: 100000, FuelUsed: used})
+	}
+
+	// 9 laps of fuel remaining, 45 laps to finish
+	c.Frame(decode(t, `{"fuel":27,"timeLeft":4450000}`))
+
+	est := c.Estimate()
+
+	if est.Laps != 3 || est.CompletedLaps != 3 || est.LapTime != 100000 || est.FuelPerLap != 3 {
+		t.Fatalf("Expected: 3 laps, 100s lap time, 3 fuel per lap. Actual: %+v.", est)
+	}
+
+	if est.LapsRemaining != 9 || est.LapsToFinish != 45 || est.FuelToFinish != 135 || est.FuelToAdd != 108 {
+		t.Fatalf("Expected: 9 laps remaining, 45 to finish, 135 to finish, 108 to add. Actual: %+v.", est)
+	}
+
+	// a full tank lasts 33 laps. 36 laps short so 2 stops are needed. the first stop
+	// can't be made any earlier than lap 3 + 45 - 66 (i.e. right now)
+	expected := PitWindow{Open: 3, Close: 12, Stops: 2}
+
+	if est.PitWindow == nil || *est.PitWindow != expected {
+		t.Fatalf("Expected: %+v. Actual: %+v.", expected, est.PitWindow)
+	}
+
+	// 1 lap of wear per corner between the first and last laps
+	if len(est.TyreWear) != 4 || math.Abs(est.TyreWear[0]-0.5) > 1e-9 || math.Abs(est.TyreWear[3]-0.25) > 1e-9 {
+		t.Fatalf("Expected: [0.5 0.5 0.25 0.25]. Actual: %v.", est.TyreWear)
+	}
+}
+
+// Is no pit window given when the race can be finished on the fuel remaining?
+// Are the averages reset when a new session starts?
+func TestEstimateSession(t *testing.T) {
+	c := NewCalculator(fields)
+	c.Frame(decode(t, `{"fuel":60,"maxFuel":100,"timeLeft":500000}`))
+
+	for i := 1; i <= WINDOW+2; i++ {
+		c.Lap(&are_hub.Lap{Session: "a", Number: int64(i), Time: 100000, FuelUsed: 3})
+	}
+
+	est := c.Estimate()
+
+	if est.Laps != WINDOW {
+		t.Fatalf("Expected: %d laps. Actual: %d.", WINDOW, est.Laps)
+	}
+
+	if est.PitWindow != nil || est.FuelToAdd != 0 {
+		t.Fatalf("Expected: no pit window. Actual: %+v.", est)
+	}
+
+	c.Lap(&are_hub.Lap{Session: "b", Number: 1, Time: 90000, FuelUsed: 2})
+	est = c.Estimate()
+
+	if est.Laps != 1 || est.LapTime != 90000 || est.FuelPerLap != 2 {
+		t.Fatalf("Expected: averages of the new session. Actual: %+v.", est)
+	}
+}
@@ -0,0 +1,112 @@
+package are_hub
+
+import (
+	"context"
+	"fmt"
+)
+
+// Subscription to the events of a channel, delivered by HTTP POST to URL.
+type Webhook struct {
+	// Webhooks without a channel receive the events of every channel.
+	ChannelID string `json:"channelId"`
+
+	URL string `json:"url"`
+
+	// Key deliveries are signed with (HMAC-SHA256). Not sent to clients.
+	Secret password `json:"secret"`
+
+	// Types of events (see EVENT_*) delivered. All events are delivered if empty.
+	Events []string `json:"events"`
+
+	Common `bson:",inline"`
+}
+
+// Create a new webhook.
+func NewWebhook(url, secret string, events []string) *Webhook {
+	return &Webhook{URL: url, Secret: password(secret), Events: events}
+}
+
+// Get a webhook from a context.
+func WebhookFromCtx(ctx context.Context) (*Webhook, error) {
+	v := ctx.Value(keyWebhook)
+	w, ok := v.(*Webhook)
+
+	if !ok {
+		return nil, fmt.Errorf("Could not assert %v as *Webhook\n", v)
+	}
+
+	return w, nil
+}
+
+// Insert a webhook into context.
+func (w *Webhook) ToCtx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyWebhook, w)
+}
+
+// Retrieve the webhook's secret as a string.
+func (w *Webhook) SecretStr() string {
+	return string(w.Secret)
+}
+
+// Check whether events of type t are delivered to the webhook.
+func (w *Webhook) Accepts(t string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	for _, e := range w.Events {
+		if e == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+type WebhookRepo interface {
+	// Get the webhooks of a channel by the channel's ID. Webhooks without a
+	// channel are included.
+	FindChannelID(context.Context, string) ([]Webhook, error)
+
+	// Create a new webhook.
+	Insert(context.Context, Archetype) error
+
+	// Find a webhook by its ID.
+	FindID(context.Context, string) (*Webhook, error)
+
+	// Find and update a webhook by its ID.
+	UpdateID(context.Context, string, Archetype) error
+
+	// Find and delete a webhook by its ID.
+	DeleteID(context.Context, string) (*Webhook, error)
+}
+
+// A single attempt to deliver an event to a webhook.
+type Delivery struct {
+	WebhookID string `json:"webhookId"`
+	ChannelID string `json:"channelId"`
+	EventID   string `json:"eventId"`
+	Event     string `json:"event"`
+
+	// Starting at 1.
+	Attempt int `json:"attempt"`
+
+	// HTTP status code of the response. 0 if no response was received.
+	StatusCode int `json:"statusCode"`
+
+	// Why the attempt failed (if it did).
+	Error string `json:"error"`
+
+	// Whether the receiver responded with a 2xx status code.
+	Success bool `json:"success"`
+
+	Common `bson:",inline"`
+}
+
+type DeliveryRepo interface {
+	// Get the delivery log of a webhook (by the webhook's ID), newest first.
+	FindWebhookID(context.Context, string) ([]Delivery, error)
+
+	// Record a delivery attempt.
+	Insert(context.Context, Archetype) error
+}
@@ -0,0 +1,199 @@
+// Package webhook delivers channel events to webhooks with HMAC-signed payloads,
+// retrying failed deliveries with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+)
+
+const (
+	// Maximum number of attempts to deliver an event to a webhook.
+	MAX_ATTEMPTS = 5
+
+	// Delay before the first retry. Doubled after every failed attempt.
+	BACKOFF = time.Second
+
+	// How long to wait for a webhook to respond.
+	TIMEOUT = time.Second * 10
+
+	// Header containing the type of event delivered.
+	HEADER_EVENT = "Are-Hub-Event"
+
+	// Header containing the hex-encoded HMAC-SHA256 of the body keyed with the
+	// webhook's secret, prefixed with "sha256=".
+	HEADER_SIGNATURE = "Are-Hub-Signature"
+)
+
+// Delivers events to the webhooks subscribed to them. Implements are_hub.Emitter.
+type Dispatcher struct {
+	webhooks   are_hub.WebhookRepo
+	deliveries are_hub.DeliveryRepo
+	client     *http.Client
+
+	attempts int
+	backoff  time.Duration
+
+	// deliveries in progress
+	wg sync.WaitGroup
+}
+
+// Create a new dispatcher delivering to the webhooks in webhooks and logging
+// every delivery attempt in deliveries.
+func NewDispatcher(webhooks are_hub.WebhookRepo, deliveries are_hub.DeliveryRepo) *Dispatcher {
+	return &Dispatcher{
+		webhooks:   webhooks,
+		deliveries: deliveries,
+		client:     &http.Client{Timeout: TIMEOUT},
+		attempts:   MAX_ATTEMPTS,
+		backoff:    BACKOFF,
+	}
+}
+
+// Deliver ev to the webhooks of its channel in the background.
+func (d *Dispatcher) Emit(ev are_hub.Event) {
+	ev.ID = newID()
+
+	d.wg.Add(1)
+
+	go func() {
+		defer d.wg.Done()
+
+		e := d.dispatch(context.Background(), ev)
+
+		if e != nil {
+			log.Printf("Failed to dispatch %s event %s: %v\n", ev.Type, ev.ID, e)
+		}
+	}()
+}
+
+// Block until all deliveries in progress have completed or been abandoned.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// Deliver ev to each webhook accepting it concurrently.
+func (d *Dispatcher) dispatch(ctx context.Context, ev are_hub.Event) error {
+	webhooks, e := d.webhooks.FindChannelID(ctx, ev.ChannelID)
+
+	if e != nil {
+		return e
+	}
+
+	body, e := json.Marshal(ev)
+
+	if e != nil {
+		return e
+	}
+
+	var wg sync.WaitGroup
+
+	for i := range webhooks {
+		if !webhooks[i].Accepts(ev.Type) {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(w *are_hub.Webhook) {
+			defer wg.Done()
+
+			d.deliver(ctx, w, ev, body)
+		}(&webhooks[i])
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// Attempt to deliver body to w until it succeeds or the maximum number of
+// attempts is reached. Every attempt is logged.
+func (d *Dispatcher) deliver(ctx context.Context, w *are_hub.Webhook, ev are_hub.Event, body []byte) {
+	delay := d.backoff
+
+	for attempt := 1; attempt <= d.attempts; attempt++ {
+		delivery := &are_hub.Delivery{
+			WebhookID: w.ID,
+			ChannelID: ev.ChannelID,
+			EventID:   ev.ID,
+			Event:     ev.Type,
+			Attempt:   attempt,
+		}
+
+		d.post(ctx, w, ev.Type, body, delivery)
+		e := d.deliveries.Insert(ctx, delivery)
+
+		if e != nil {
+			log.Printf("Failed to log delivery of event %s to webhook %s: %v\n", ev.ID, w.ID, e)
+		}
+
+		if delivery.Success || attempt == d.attempts {
+			return
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// POST body to w, recording the outcome in delivery.
+func (d *Dispatcher) post(ctx context.Context, w *are_hub.Webhook, event string, body []byte, delivery *are_hub.Delivery) {
+	req, e := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+
+	if e != nil {
+		delivery.Error = e.Error()
+
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HEADER_EVENT, event)
+	req.Header.Set(HEADER_SIGNATURE, Sign(w.SecretStr(), body))
+
+	res, e := d.client.Do(req)
+
+	if e != nil {
+		delivery.Error = e.Error()
+
+		return
+	}
+
+	res.Body.Close()
+
+	delivery.StatusCode = res.StatusCode
+	delivery.Success = res.StatusCode >= 200 && res.StatusCode <= 299
+
+	if !delivery.Success {
+		delivery.Error = res.Status
+	}
+}
+
+// Create the signature of body keyed with secret as sent in the HEADER_SIGNATURE header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Generate a random event ID.
+func newID() string {
+	bytes := make([]byte, 12)
+
+	// reading can only fail if the system's entropy source is unavailable
+	rand.Read(bytes)
+
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blacksfk/are_hub"
+	"github.com/blacksfk/are_hub/mock"
+)
+
+// Test dispatcher delivering to webhooks with a recorded delivery log.
+type testDispatcher struct {
+	*Dispatcher
+
+	mtx        sync.Mutex
+	deliveries []are_hub.Delivery
+}
+
+func newTestDispatcher(webhooks ...are_hub.Webhook) *testDispatcher {
+	td := &testDispatcher{}
+	repo := &mock.WebhookRepo{
+		FindChannelIDFunc: func(context.Context, string) ([]are_hub.Webhook, error) {
+			return webhooks, nil
+		},
+	}
+
+	deliveries := &mock.DeliveryRepo{
+		InsertFunc: func(_ context.Context, a are_hub.Archetype) error {
+			td.mtx.Lock()
+			defer td.mtx.Unlock()
+
+			td.deliveries = append(td.deliveries, *a.(*are_hub.Delivery))
+
+			return nil
+		},
+	}
+
+	td.Dispatcher = NewDispatcher(repo, deliveries)
+	td.backoff = time.Millisecond
+
+	return td
+}
+
+// Are events delivered with a valid signature and event header?
+// Are events not accepted by a webhook skipped?
+func TestDispatchSigned(t *testing.T) {
+	received := make(chan are_hub.Event, 2)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, e := io.ReadAll(r.Body)
+
+		if e != nil {
+			t.Error(e)
+		}
+
+		if sig := r.Header.Get(HEADER_SIGNATURE); sig != Sign("s3cret", body) {
+			t.Errorf("Invalid signature: %s.", sig)
+		}
+
+		if ev := r.Header.Get(HEADER_EVENT); ev != are_hub.EVENT_ALERT_FIRED {
+			t.Errorf("Expected: %s. Actual: %s.", are_hub.EVENT_ALERT_FIRED, ev)
+		}
+
+		ev := are_hub.Event{}
+		e = json.Unmarshal(body, &ev)
+
+		if e != nil {
+			t.Error(e)
+		}
+
+		received <- ev
+	}))
+
+	defer receiver.Close()
+
+	all := are_hub.NewWebhook(receiver.URL, "s3cret", nil)
+	all.ID = "all"
+	sessions := are_hub.NewWebhook(receiver.URL, "s3cret", []string{are_hub.EVENT_SESSION_STARTED})
+	sessions.ID = "sessions"
+
+	td := newTestDispatcher(*all, *sessions)
+	td.Emit(are_hub.NewEvent(are_hub.EVENT_ALERT_FIRED, "1", map[string]float64{"value": 28.2}))
+	td.Wait()
+
+	if l := len(received); l != 1 {
+		t.Fatalf("Expected: 1 delivery. Actual: %d.", l)
+	}
+
+	ev := <-received
+
+	if ev.ChannelID != "1" || len(ev.ID) == 0 {
+		t.Fatalf("Expected: event with an ID on channel 1. Actual: %+v.", ev)
+	}
+
+	if len(td.deliveries) != 1 || !td.deliveries[0].Success || td.deliveries[0].WebhookID != "all" {
+		t.Fatalf("Expected: 1 successful delivery to all. Actual: %+v.", td.deliveries)
+	}
+}
+
+// Are failed deliveries retried with increasing delays and every attempt logged?
+// Are deliveries abandoned after the maximum number of attempts?
+func TestDispatchRetry(t *testing.T) {
+	var mtx sync.Mutex
+	var times []time.Time
+
+	failures := 2
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		times = append(times, time.Now())
+
+		if len(times) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	defer receiver.Close()
+
+	td := newTestDispatcher(*are_hub.NewWebhook(receiver.URL, "s3cret", nil))
+	td.backoff = time.Millisecond * 20
+	td.Emit(are_hub.NewEvent(are_hub.EVENT_PUBLISHER_OFFLINE, "1", nil))
+	td.Wait()
+
+	if l := len(td.deliveries); l != 3 {
+		t.Fatalf("Expected: 3 attempts. Actual: %d.", l)
+	}
+
+	for i, d := range td.deliveries {
+		if d.Attempt != i+1 || d.Success != (i == 2) {
+			t.Fatalf("Expected: attempt %d, success %t. Actual: %+v.", i+1, i == 2, d)
+		}
+	}
+
+	if d := td.deliveries[0]; d.StatusCode != http.StatusServiceUnavailable || len(d.Error) == 0 {
+		t.Fatalf("Expected: 503 with an error. Actual: %+v.", d)
+	}
+
+	// the second retry waits twice as long as the first
+	if d := times[2].Sub(times[1]); d < td.backoff*2 {
+		t.Fatalf("Expected: at least %v between retries. Actual: %v.", td.backoff*2, d)
+	}
+
+	// a receiver that never succeeds
+	failures = MAX_ATTEMPTS * 2
+	times = nil
+	td.deliveries = nil
+	td.backoff = time.Millisecond
+	td.Emit(are_hub.NewEvent(are_hub.EVENT_PUBLISHER_OFFLINE, "1", nil))
+	td.Wait()
+
+	if l := len(td.deliveries); l != MAX_ATTEMPTS {
+		t.Fatalf("Expected: %d attempts. Actual: %d.", MAX_ATTEMPTS, l)
+	}
+}